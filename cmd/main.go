@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -14,10 +16,16 @@ import (
 
 	_ "github.com/yorukot/starker/docs"
 	"github.com/yorukot/starker/internal/config"
+	"github.com/yorukot/starker/internal/core/imageupdates"
+	"github.com/yorukot/starker/internal/core/orphangc"
+	"github.com/yorukot/starker/internal/core/servermetrics"
+	"github.com/yorukot/starker/internal/core/serverstatus"
+	"github.com/yorukot/starker/internal/core/statsampler"
 	"github.com/yorukot/starker/internal/database"
 	"github.com/yorukot/starker/internal/handler"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/router"
+	"github.com/yorukot/starker/internal/service/privatekeysvc"
 	"github.com/yorukot/starker/pkg/logger"
 	"github.com/yorukot/starker/pkg/response"
 )
@@ -62,6 +70,11 @@ func main() {
 	}
 	defer db.Close()
 
+	// Encrypt private keys stored before encryption at rest
+	if err := privatekeysvc.EncryptPlaintextPrivateKeys(context.Background(), db); err != nil {
+		zap.L().Fatal("Failed to encrypt private keys", zap.Error(err))
+	}
+
 	r.Use(middleware.ZapLoggerMiddleware(zap.L()))
 	r.Use(chiMiddleware.StripSlashes)
 
@@ -77,6 +90,27 @@ func main() {
 
 	setupRouter(r, &handler.App{DB: db})
 
+	// Start the background container stats sampler
+	statsampler.NewSampler(db, time.Duration(config.Env().StatsSampleInterval)*time.Second).Start(context.Background())
+
+	// Start the background server metrics collector
+	servermetrics.NewCollector(db, time.Duration(config.Env().ServerMetricsInterval)*time.Second).Start(context.Background())
+
+	// Start the background server status prober
+	serverstatus.NewProber(db, time.Duration(config.Env().ServerStatusInterval)*time.Second).Start(context.Background())
+
+	// Start the background watcher for new digests of the image tags services run
+	imageupdates.NewWatcher(db,
+		time.Duration(config.Env().ImageUpdateInterval)*time.Second,
+		config.Env().ImageRetentionDeployments,
+	).Start(context.Background())
+
+	// Start the background collector of resources left behind by deleted services
+	orphangc.NewCollector(db,
+		time.Duration(config.Env().OrphanGCInterval)*time.Second,
+		time.Duration(config.Env().OrphanGCGracePeriod)*time.Second,
+	).Start(context.Background())
+
 	zap.L().Info("Starting server on http://localhost:" + config.Env().Port)
 	zap.L().Info("Environment: " + string(config.Env().AppEnv))
 
@@ -96,6 +130,7 @@ func setupRouter(r chi.Router, app *handler.App) {
 		router.ServerRouter(r, app)
 		router.ProjectRouter(r, app)
 		router.ServiceRouter(r, app)
+		router.AgentRouter(r, app)
 	})
 
 	if config.Env().AppEnv == config.AppEnvDev {
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/joho/godotenv/autoload"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/config"
+	"github.com/yorukot/starker/internal/database"
+	"github.com/yorukot/starker/internal/service/privatekeysvc"
+	"github.com/yorukot/starker/pkg/encrypt"
+	"github.com/yorukot/starker/pkg/logger"
+)
+
+// Rotates the master key that encrypts the SSH private keys.
+//
+// The current key is read from PRIVATE_KEY_MASTER_KEY and the new key from NEW_PRIVATE_KEY_MASTER_KEY.
+// All data keys are re-encrypted in a single transaction, so either every row uses the new key or none.
+// Stop the API before rotating and start it again with PRIVATE_KEY_MASTER_KEY set to the new key.
+//
+//	NEW_PRIVATE_KEY_MASTER_KEY=$(openssl rand -base64 32) go run ./cmd/rotate-master-key
+func main() {
+	logger.InitLogger()
+
+	_, err := config.InitConfig()
+	if err != nil {
+		zap.L().Fatal("Error initializing config", zap.Error(err))
+	}
+
+	oldMasterKey, err := encrypt.DecodeMasterKey(config.Env().PrivateKeyMasterKey)
+	if err != nil {
+		zap.L().Fatal("Invalid PRIVATE_KEY_MASTER_KEY", zap.Error(err))
+	}
+	newMasterKey, err := encrypt.DecodeMasterKey(os.Getenv("NEW_PRIVATE_KEY_MASTER_KEY"))
+	if err != nil {
+		zap.L().Fatal("Invalid NEW_PRIVATE_KEY_MASTER_KEY", zap.Error(err))
+	}
+
+	db, err := database.InitDatabase()
+	if err != nil {
+		zap.L().Fatal("Failed to initialize database", zap.Error(err))
+	}
+	defer db.Close()
+
+	// Encrypt leftover plaintext keys with the old key first, so they are rotated too
+	if err := privatekeysvc.EncryptPlaintextPrivateKeys(context.Background(), db); err != nil {
+		zap.L().Fatal("Failed to encrypt private keys", zap.Error(err))
+	}
+
+	count, err := privatekeysvc.RotateMasterKey(context.Background(), db, oldMasterKey, newMasterKey)
+	if err != nil {
+		zap.L().Fatal("Failed to rotate master key", zap.Error(err))
+	}
+
+	zap.L().Info("Master key rotated, restart the API with the new PRIVATE_KEY_MASTER_KEY", zap.Int("private_keys", count))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v10"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/yorukot/starker/pkg/agent"
+	"github.com/yorukot/starker/pkg/logger"
+)
+
+const (
+	// minRetryDelay and maxRetryDelay bound the backoff between connection attempts
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+	// stableTunnel is how long a tunnel has to stay open for the backoff to reset
+	stableTunnel = time.Minute
+)
+
+// agentConfig holds the environment variables of the agent
+type agentConfig struct {
+	StarkerURL   string `env:"STARKER_URL,required"`         // Base URL of the Starker API, e.g. https://starker.example.com
+	AgentToken   string `env:"STARKER_AGENT_TOKEN,required"` // Token returned when the agent server was created
+	DockerSocket string `env:"DOCKER_SOCKET" envDefault:"/var/run/docker.sock"`
+}
+
+// starker-agent runs on a server that Starker cannot reach over SSH, for example behind NAT.
+// It dials the Starker API, keeps the tunnel open and proxies the local Docker socket through it.
+func main() {
+	logger.InitLogger()
+
+	cfg := &agentConfig{}
+	if err := env.Parse(cfg); err != nil {
+		zap.L().Fatal("Error initializing config", zap.Error(err))
+	}
+
+	wsConfig, err := newWebSocketConfig(cfg)
+	if err != nil {
+		zap.L().Fatal("Invalid STARKER_URL", zap.Error(err))
+	}
+	if wsConfig.Location.Scheme != "wss" {
+		zap.L().Warn("STARKER_URL does not use https, the agent token is sent unencrypted")
+	}
+
+	retryDelay := minRetryDelay
+	for {
+		connectedAt := time.Now()
+		if err := runTunnel(wsConfig, cfg.DockerSocket); err != nil {
+			zap.L().Warn("Agent tunnel closed", zap.Error(err))
+		}
+
+		if time.Since(connectedAt) > stableTunnel {
+			retryDelay = minRetryDelay
+		}
+		zap.L().Info("Reconnecting to Starker", zap.Duration("delay", retryDelay))
+		time.Sleep(retryDelay)
+		retryDelay = min(retryDelay*2, maxRetryDelay)
+	}
+}
+
+// newWebSocketConfig builds the WebSocket config for the connect endpoint of the API
+func newWebSocketConfig(cfg *agentConfig) (*websocket.Config, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.StarkerURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	origin := baseURL.String()
+	switch baseURL.Scheme {
+	case "https":
+		baseURL.Scheme = "wss"
+	case "http":
+		baseURL.Scheme = "ws"
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, use http or https", baseURL.Scheme)
+	}
+	baseURL.Path += "/api/agent/connect"
+
+	wsConfig, err := websocket.NewConfig(baseURL.String(), origin)
+	if err != nil {
+		return nil, err
+	}
+	wsConfig.Header.Set("Authorization", "Bearer "+cfg.AgentToken)
+	return wsConfig, nil
+}
+
+// runTunnel dials Starker and serves the Docker socket until the tunnel closes
+func runTunnel(wsConfig *websocket.Config, dockerSocket string) error {
+	ws, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Starker: %w", err)
+	}
+	defer ws.Close()
+	ws.PayloadType = websocket.BinaryFrame
+
+	zap.L().Info("Connected to Starker", zap.String("url", wsConfig.Location.String()))
+	return agent.Serve(ws, dockerSocket)
+}
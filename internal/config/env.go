@@ -1,10 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/caarlos0/env/v10"
 	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/pkg/encrypt"
 )
 
 type AppEnv string
@@ -18,6 +21,8 @@ const (
 type EnvConfig struct {
 	JWTSecretKey string `env:"JWT_SECRET_KEY,required"`
 
+	PrivateKeyMasterKey string `env:"PRIVATE_KEY_MASTER_KEY,required"` // base64 encoded 32 byte key that encrypts the SSH private keys
+
 	FrontendDomain string `env:"FRONTEND_DOMAIN,required"`
 
 	GoogleClientID     string `env:"GOOGLE_CLIENT_ID,required"`
@@ -36,6 +41,20 @@ type EnvConfig struct {
 	AccessTokenExpiresAt  int `env:"ACCESS_TOKEN_EXPIRES_AT" envDefault:"900"`       // 15 minutes
 	RefreshTokenExpiresAt int `env:"REFRESH_TOKEN_EXPIRES_AT" envDefault:"31536000"` // 365 days
 
+	StatsSampleInterval   int `env:"STATS_SAMPLE_INTERVAL" envDefault:"60"`     // 1 minute
+	ServerMetricsInterval int `env:"SERVER_METRICS_INTERVAL" envDefault:"60"`   // 1 minute
+	ServerStatusInterval  int `env:"SERVER_STATUS_INTERVAL" envDefault:"30"`    // 30 seconds
+	ImageUpdateInterval   int `env:"IMAGE_UPDATE_INTERVAL" envDefault:"3600"`   // 1 hour
+	OrphanGCInterval      int `env:"ORPHAN_GC_INTERVAL" envDefault:"3600"`      // 1 hour
+	OrphanGCGracePeriod   int `env:"ORPHAN_GC_GRACE_PERIOD" envDefault:"86400"` // 24 hours, orphaned resources are kept this long before removal
+
+	TerminalIdleTimeout  int    `env:"TERMINAL_IDLE_TIMEOUT" envDefault:"900"`                // 15 minutes
+	TerminalRecordingDir string `env:"TERMINAL_RECORDING_DIR" envDefault:"./data/recordings"` // asciicast files of recorded sessions
+
+	ImageRetentionDeployments int `env:"IMAGE_RETENTION_DEPLOYMENTS" envDefault:"3"` // images of the last N deployments of every service are kept, 0 disables the cleanup
+
+	LocalDockerSocket string `env:"LOCAL_DOCKER_SOCKET" envDefault:""` // Docker socket for local servers, local servers are disabled when empty
+
 	Port    string `env:"PORT" envDefault:"8080"`
 	Debug   bool   `env:"DEBUG" envDefault:"false"`
 	AppEnv  AppEnv `env:"APP_ENV" envDefault:"prod"`
@@ -53,6 +72,9 @@ func loadConfig() (*EnvConfig, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, err
 	}
+	if _, err := encrypt.DecodeMasterKey(cfg.PrivateKeyMasterKey); err != nil {
+		return nil, fmt.Errorf("invalid PRIVATE_KEY_MASTER_KEY: %w", err)
+	}
 	return cfg, nil
 }
 
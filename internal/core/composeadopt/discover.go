@@ -0,0 +1,236 @@
+package composeadopt
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+const (
+	// projectLabel is the label docker compose puts on every resource of a project
+	projectLabel = "com.docker.compose.project"
+	// serviceLabel is the label with the compose service of a container
+	serviceLabel = "com.docker.compose.service"
+	// containerNumberLabel is the label with the replica number of a container
+	containerNumberLabel = "com.docker.compose.container-number"
+	// dependsOnLabel is the label with the dependencies of a container, "service:condition:restart" separated by commas
+	dependsOnLabel = "com.docker.compose.depends_on"
+	// networkLabel is the label with the compose key of a network
+	networkLabel = "com.docker.compose.network"
+	// volumeLabel is the label with the compose key of a volume
+	volumeLabel = "com.docker.compose.volume"
+	// workingDirLabel is the label with the directory docker compose was run in
+	workingDirLabel = "com.docker.compose.project.working_dir"
+	// configFilesLabel is the label with the compose files of a project, separated by commas
+	configFilesLabel = "com.docker.compose.project.config_files"
+)
+
+// Project is a compose project running on a server that Starker does not manage yet
+type Project struct {
+	Name        string             `json:"name" example:"blog"`                                                            // Compose project name
+	WorkingDir  string             `json:"working_dir,omitempty" example:"/home/ubuntu/blog"`                              // Directory docker compose was run in
+	ConfigFiles []string           `json:"config_files" example:"/home/ubuntu/blog/docker-compose.yml"`                    // Compose files the project was started from
+	Containers  []ProjectContainer `json:"containers"`                                                                     // Containers of the project
+	Networks    []ProjectNetwork   `json:"networks"`                                                                       // Networks created for the project
+	Volumes     []ProjectVolume    `json:"volumes"`                                                                        // Volumes created for the project
+	ComposeFile string             `json:"compose_file" example:"services:\n  web:\n    image: nginx:1.27"`                // Compose file reconstructed from the containers
+	Warnings    []string           `json:"warnings" example:"service web: anonymous volume at /cache is not carried over"` // Settings of the running project that are not carried over
+}
+
+// ProjectContainer is a container of a compose project
+type ProjectContainer struct {
+	ID      string `json:"id" example:"abc123def456"`  // Docker container ID
+	Name    string `json:"name" example:"blog-web-1"`  // Container name
+	Service string `json:"service" example:"web"`      // Compose service of the container
+	Image   string `json:"image" example:"nginx:1.27"` // Image the container was created from
+	State   string `json:"state" example:"running"`    // Container state
+	Number  int    `json:"number" example:"1"`         // Replica number within the compose service
+	Primary bool   `json:"primary" example:"true"`     // Whether the compose file was reconstructed from this container
+	inspect container.InspectResponse
+}
+
+// ProjectNetwork is a network of a compose project
+type ProjectNetwork struct {
+	ID     string `json:"id" example:"abc123def456"`   // Docker network ID
+	Name   string `json:"name" example:"blog_default"` // Docker network name
+	Key    string `json:"key" example:"default"`       // Network key in the compose file
+	Driver string `json:"driver" example:"bridge"`     // Network driver
+}
+
+// ProjectVolume is a volume of a compose project, its data is copied to the Starker volume on adoption
+type ProjectVolume struct {
+	Name       string            `json:"name" example:"blog_data"` // Docker volume name
+	Key        string            `json:"key" example:"data"`       // Volume key in the compose file
+	Driver     string            `json:"driver" example:"local"`   // Volume driver
+	DriverOpts map[string]string `json:"driver_opts,omitempty"`    // Volume driver options
+}
+
+// ContainerIDs returns the IDs of all containers of the project
+func (p Project) ContainerIDs() []string {
+	ids := make([]string, 0, len(p.Containers))
+	for _, c := range p.Containers {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// NetworkIDs returns the IDs of all networks of the project
+func (p Project) NetworkIDs() []string {
+	ids := make([]string, 0, len(p.Networks))
+	for _, n := range p.Networks {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+// ListProjects lists the compose projects on the server that were not created by Starker
+func ListProjects(ctx context.Context, dockerClient *client.Client) ([]Project, error) {
+	summaries, err := dockerClient.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", projectLabel)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var names []string
+	for _, summary := range summaries {
+		if _, managed := summary.Labels[generator.ServiceIDLabel]; managed {
+			continue
+		}
+		if name := summary.Labels[projectLabel]; !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	projects := make([]Project, 0, len(names))
+	for _, name := range names {
+		project, err := GetProject(ctx, dockerClient, name)
+		if err != nil {
+			return nil, err
+		}
+		if project != nil {
+			projects = append(projects, *project)
+		}
+	}
+
+	return projects, nil
+}
+
+// GetProject inspects a compose project on the server and reconstructs its compose file.
+// It returns nil when no unmanaged container belongs to the project.
+func GetProject(ctx context.Context, dockerClient *client.Client, name string) (*Project, error) {
+	projectFilter := filters.NewArgs(filters.Arg("label", fmt.Sprintf("%s=%s", projectLabel, name)))
+
+	summaries, err := dockerClient.ContainerList(ctx, container.ListOptions{All: true, Filters: projectFilter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers of project %s: %w", name, err)
+	}
+
+	project := &Project{Name: name, Containers: []ProjectContainer{}, Networks: []ProjectNetwork{}, Volumes: []ProjectVolume{}, Warnings: []string{}}
+	for _, summary := range summaries {
+		if _, managed := summary.Labels[generator.ServiceIDLabel]; managed {
+			continue
+		}
+
+		inspect, err := dockerClient.ContainerInspect(ctx, summary.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s: %w", summary.ID, err)
+		}
+
+		number, _ := strconv.Atoi(summary.Labels[containerNumberLabel])
+		project.Containers = append(project.Containers, ProjectContainer{
+			ID:      summary.ID,
+			Name:    strings.TrimPrefix(inspect.Name, "/"),
+			Service: summary.Labels[serviceLabel],
+			Image:   summary.Image,
+			State:   summary.State,
+			Number:  number,
+			inspect: inspect,
+		})
+
+		project.WorkingDir = summary.Labels[workingDirLabel]
+		if files := summary.Labels[configFilesLabel]; files != "" {
+			project.ConfigFiles = strings.Split(files, ",")
+		}
+	}
+	if len(project.Containers) == 0 {
+		return nil, nil
+	}
+
+	// The lowest replica of every compose service describes the service
+	slices.SortFunc(project.Containers, func(a, b ProjectContainer) int {
+		if a.Service != b.Service {
+			return strings.Compare(a.Service, b.Service)
+		}
+		return a.Number - b.Number
+	})
+	for i := range project.Containers {
+		project.Containers[i].Primary = i == 0 || project.Containers[i-1].Service != project.Containers[i].Service
+	}
+
+	networks, err := dockerClient.NetworkList(ctx, network.ListOptions{Filters: projectFilter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks of project %s: %w", name, err)
+	}
+	for _, n := range networks {
+		project.Networks = append(project.Networks, ProjectNetwork{
+			ID:     n.ID,
+			Name:   n.Name,
+			Key:    composeKey(n.Labels[networkLabel], n.Name, name),
+			Driver: n.Driver,
+		})
+	}
+
+	volumes, err := dockerClient.VolumeList(ctx, volume.ListOptions{Filters: projectFilter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes of project %s: %w", name, err)
+	}
+	for _, v := range volumes.Volumes {
+		project.Volumes = append(project.Volumes, ProjectVolume{
+			Name:       v.Name,
+			Key:        composeKey(v.Labels[volumeLabel], v.Name, name),
+			Driver:     v.Driver,
+			DriverOpts: v.Options,
+		})
+	}
+	slices.SortFunc(project.Volumes, func(a, b ProjectVolume) int { return strings.Compare(a.Key, b.Key) })
+
+	images := make(map[string]*imageDefaults)
+	for _, c := range project.Containers {
+		if !c.Primary || images[c.inspect.Image] != nil {
+			continue
+		}
+		defaults, err := inspectImageDefaults(ctx, dockerClient, c.inspect.Image)
+		if err != nil {
+			return nil, err
+		}
+		images[c.inspect.Image] = defaults
+	}
+
+	if err := project.reconstruct(images); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// composeKey returns the key of a network or volume in the compose file.
+// Resources created by docker compose carry it as label, otherwise the project prefix is stripped from the name.
+func composeKey(label, name, projectName string) string {
+	if label != "" {
+		return label
+	}
+	return strings.TrimPrefix(name, projectName+"_")
+}
@@ -0,0 +1,275 @@
+package composeadopt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"gopkg.in/yaml.v3"
+)
+
+// anonymousVolumeName matches the generated names of anonymous volumes
+var anonymousVolumeName = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// composeFile is the subset of the compose specification Starker deploys
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+	Networks map[string]composeNetwork `yaml:"networks,omitempty"`
+	Volumes  map[string]composeVolume  `yaml:"volumes,omitempty"`
+}
+
+type composeService struct {
+	Image       string            `yaml:"image"`
+	Entrypoint  []string          `yaml:"entrypoint,omitempty"`
+	Command     []string          `yaml:"command,omitempty"`
+	WorkingDir  string            `yaml:"working_dir,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	Networks    []string          `yaml:"networks,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty"`
+	Restart     string            `yaml:"restart,omitempty"`
+}
+
+type composeNetwork struct {
+	Driver string `yaml:"driver,omitempty"`
+}
+
+type composeVolume struct {
+	Driver     string            `yaml:"driver,omitempty"`
+	DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+}
+
+// imageDefaults is the configuration a container inherits from its image,
+// settings equal to it were not set in the compose file and are left out
+type imageDefaults struct {
+	Env        []string
+	Entrypoint []string
+	Cmd        []string
+	WorkingDir string
+}
+
+// inspectImageDefaults returns the configuration of an image
+func inspectImageDefaults(ctx context.Context, dockerClient *client.Client, imageID string) (*imageDefaults, error) {
+	inspect, err := dockerClient.ImageInspect(ctx, imageID)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return &imageDefaults{}, nil
+		}
+		return nil, fmt.Errorf("failed to inspect image %s: %w", imageID, err)
+	}
+	if inspect.Config == nil {
+		return &imageDefaults{}, nil
+	}
+
+	return &imageDefaults{
+		Env:        inspect.Config.Env,
+		Entrypoint: inspect.Config.Entrypoint,
+		Cmd:        inspect.Config.Cmd,
+		WorkingDir: inspect.Config.WorkingDir,
+	}, nil
+}
+
+// reconstruct builds an approximate compose file from the primary containers of the project.
+// Settings Starker can not deploy are reported as warnings.
+func (p *Project) reconstruct(images map[string]*imageDefaults) error {
+	file := composeFile{
+		Services: make(map[string]composeService),
+		Networks: make(map[string]composeNetwork),
+		Volumes:  make(map[string]composeVolume),
+	}
+
+	networks := make(map[string]ProjectNetwork)
+	for _, n := range p.Networks {
+		networks[n.Name] = n
+	}
+	volumes := make(map[string]ProjectVolume)
+	for _, v := range p.Volumes {
+		volumes[v.Name] = v
+	}
+
+	replicas := make(map[string]int)
+	for _, c := range p.Containers {
+		replicas[c.Service]++
+		if !c.Primary {
+			continue
+		}
+
+		service := composeService{
+			Image:       c.inspect.Config.Image,
+			Environment: environment(c.inspect.Config.Env, images[c.inspect.Image].Env),
+			Ports:       ports(c.inspect.HostConfig.PortBindings),
+			DependsOn:   dependsOn(c.inspect.Config.Labels[dependsOnLabel]),
+			Restart:     restartPolicy(c.inspect.HostConfig.RestartPolicy),
+		}
+
+		defaults := images[c.inspect.Image]
+		entrypointChanged := !slices.Equal(c.inspect.Config.Entrypoint, defaults.Entrypoint)
+		if entrypointChanged {
+			service.Entrypoint = escape(c.inspect.Config.Entrypoint)
+		}
+		if entrypointChanged || !slices.Equal(c.inspect.Config.Cmd, defaults.Cmd) {
+			service.Command = escape(c.inspect.Config.Cmd)
+		}
+		if c.inspect.Config.WorkingDir != defaults.WorkingDir {
+			service.WorkingDir = c.inspect.Config.WorkingDir
+		}
+
+		// Volumes
+		for _, m := range c.inspect.Mounts {
+			readOnly := ""
+			if !m.RW {
+				readOnly = ":ro"
+			}
+			switch m.Type {
+			case mount.TypeBind:
+				service.Volumes = append(service.Volumes, fmt.Sprintf("%s:%s%s", m.Source, m.Destination, readOnly))
+			case mount.TypeVolume:
+				v, ok := volumes[m.Name]
+				switch {
+				case ok:
+					service.Volumes = append(service.Volumes, fmt.Sprintf("%s:%s%s", v.Key, m.Destination, readOnly))
+					file.Volumes[v.Key] = topLevelVolume(v)
+				case anonymousVolumeName.MatchString(m.Name):
+					p.Warnings = append(p.Warnings, fmt.Sprintf("service %s: anonymous volume at %s is not carried over", c.Service, m.Destination))
+				default:
+					p.Warnings = append(p.Warnings, fmt.Sprintf("service %s: volume %s at %s does not belong to the project and is not carried over", c.Service, m.Name, m.Destination))
+				}
+			default:
+				p.Warnings = append(p.Warnings, fmt.Sprintf("service %s: %s mount at %s is not carried over", c.Service, m.Type, m.Destination))
+			}
+		}
+		slices.Sort(service.Volumes)
+
+		// Networks
+		if mode := c.inspect.HostConfig.NetworkMode; mode.IsHost() || mode.IsNone() || mode.IsContainer() {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("service %s: network mode %s is not supported, the service is attached to the default network", c.Service, mode))
+		} else if c.inspect.NetworkSettings != nil {
+			for name := range c.inspect.NetworkSettings.Networks {
+				n, ok := networks[name]
+				if !ok {
+					p.Warnings = append(p.Warnings, fmt.Sprintf("service %s: network %s does not belong to the project and is not carried over", c.Service, name))
+					continue
+				}
+				service.Networks = append(service.Networks, n.Key)
+				if n.Key != "default" {
+					file.Networks[n.Key] = topLevelNetwork(n)
+				}
+			}
+			slices.Sort(service.Networks)
+			if slices.Equal(service.Networks, []string{"default"}) {
+				service.Networks = nil
+			}
+		}
+
+		file.Services[c.Service] = service
+	}
+
+	for service, count := range replicas {
+		if count > 1 {
+			p.Warnings = append(p.Warnings, fmt.Sprintf("service %s: runs %d containers, Starker runs one container per service", service, count))
+		}
+	}
+	slices.Sort(p.Warnings)
+
+	var content bytes.Buffer
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to marshal compose file of project %s: %w", p.Name, err)
+	}
+	p.ComposeFile = content.String()
+
+	return nil
+}
+
+// environment returns the variables of a container that do not come from its image
+func environment(env, imageEnv []string) map[string]string {
+	result := make(map[string]string)
+	for _, variable := range env {
+		if slices.Contains(imageEnv, variable) {
+			continue
+		}
+		key, value, _ := strings.Cut(variable, "=")
+		result[key] = escape([]string{value})[0]
+	}
+	return result
+}
+
+// ports returns the published ports in compose short syntax
+func ports(bindings nat.PortMap) []string {
+	var result []string
+	for port, portBindings := range bindings {
+		target := port.Port()
+		if port.Proto() != "tcp" {
+			target += "/" + port.Proto()
+		}
+		for _, binding := range portBindings {
+			switch binding.HostIP {
+			case "", "0.0.0.0", "::":
+				result = append(result, fmt.Sprintf("%s:%s", binding.HostPort, target))
+			default:
+				result = append(result, fmt.Sprintf("%s:%s:%s", binding.HostIP, binding.HostPort, target))
+			}
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// dependsOn returns the services of the depends_on label
+func dependsOn(label string) []string {
+	var services []string
+	for _, dependency := range strings.Split(label, ",") {
+		if service, _, _ := strings.Cut(dependency, ":"); service != "" {
+			services = append(services, service)
+		}
+	}
+	slices.Sort(services)
+	return services
+}
+
+// restartPolicy returns the restart policy in compose syntax
+func restartPolicy(policy container.RestartPolicy) string {
+	switch {
+	case policy.Name == "" || policy.Name == container.RestartPolicyDisabled:
+		return ""
+	case policy.IsOnFailure() && policy.MaximumRetryCount > 0:
+		return fmt.Sprintf("%s:%d", policy.Name, policy.MaximumRetryCount)
+	default:
+		return string(policy.Name)
+	}
+}
+
+// topLevelNetwork returns the definition of a project network, the bridge driver is the default
+func topLevelNetwork(n ProjectNetwork) composeNetwork {
+	if n.Driver == "bridge" {
+		return composeNetwork{}
+	}
+	return composeNetwork{Driver: n.Driver}
+}
+
+// topLevelVolume returns the definition of a project volume, the local driver is the default
+func topLevelVolume(v ProjectVolume) composeVolume {
+	volume := composeVolume{DriverOpts: v.DriverOpts}
+	if v.Driver != "local" {
+		volume.Driver = v.Driver
+	}
+	return volume
+}
+
+// escape doubles the dollar signs so values are not interpolated when the compose file is parsed
+func escape(values []string) []string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = strings.ReplaceAll(value, "$", "$$")
+	}
+	return escaped
+}
@@ -17,7 +17,7 @@ import (
 // This function going to sync all the container to database
 func SyncContainersToDB(ctx context.Context, dbTx pgx.Tx, connPool *connection.ConnectionPool, namingGenerator generator.NamingGenerator, composeProject types.Project) error {
 	// Get the service ID from the naming generator
-	serviceID := namingGenerator.GetLabels()["starker.service.id"]
+	serviceID := namingGenerator.GetLabels()[generator.ServiceIDLabel]
 
 	// Get all existing containers from the database for this service
 	existingContainers, err := repository.GetServiceContainers(ctx, dbTx, serviceID)
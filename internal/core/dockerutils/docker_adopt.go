@@ -0,0 +1,146 @@
+package dockerutils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+)
+
+// ComposeAdoption is a compose project started outside of Starker that the service takes over
+type ComposeAdoption struct {
+	ContainerIDs []string          // Containers of the project, they are replaced by the containers of the service
+	NetworkIDs   []string          // Networks of the project, they are removed once the containers are gone
+	Volumes      map[string]string // Docker volume of the project for every volume key of the compose file, the data is copied and the volume is kept
+}
+
+// AdoptComposeProject replaces a compose project started outside of Starker with the service in a goroutine with streaming output.
+// The images of the service are prepared first, then the containers of the project are stopped, the data of its volumes
+// is copied to the service volumes and the service is started. The resources are not relabeled, they are replaced.
+// Until the old containers are removed a failure starts them again, the old volumes are always kept.
+func (dh *DockerHandler) AdoptComposeProject(ctx context.Context, adoption ComposeAdoption) error {
+	go func() {
+		if err := dh.takeOverComposeProject(ctx, adoption); err != nil {
+			zap.L().Error("Failed to adopt compose project", zap.String("service_id", dh.NamingGenerator.ServiceID()), zap.Error(err))
+			dh.StreamChan.ErrChan <- core.LogError(fmt.Sprintf("Failed to adopt compose project: %v", err))
+			dh.StreamChan.FinalError <- fmt.Errorf("failed to adopt compose project: %w", err)
+			dh.StreamChan.DoneChan <- true
+			return
+		}
+
+		// The old project is gone, start the service the usual way
+		if err := dh.StartDockerCompose(ctx); err != nil {
+			dh.StreamChan.FinalError <- err
+			dh.StreamChan.DoneChan <- true
+		}
+	}()
+
+	return nil
+}
+
+// takeOverComposeProject stops the containers of the project, moves the volume data and removes the old resources
+func (dh *DockerHandler) takeOverComposeProject(ctx context.Context, adoption ComposeAdoption) error {
+	// Every image has to be available before the old project is touched, a failed pull later would leave nothing running
+	if err := dh.prepareServiceImages(ctx); err != nil {
+		return err
+	}
+	if len(adoption.Volumes) > 0 {
+		if err := dh.PullDockerImage(ctx, volumeMigrationImage); err != nil {
+			return err
+		}
+	}
+
+	// Stop the containers first so the volume data does not change while it is copied
+	dh.StreamChan.LogStep("Stopping the containers of the compose project")
+	timeout := 30
+	var stopped []string
+	for _, containerID := range adoption.ContainerIDs {
+		inspect, err := dh.Client.ContainerInspect(ctx, containerID)
+		if err != nil {
+			dh.restartContainers(stopped)
+			return fmt.Errorf("failed to inspect container %s: %w", containerID, err)
+		}
+		if inspect.State == nil || !inspect.State.Running {
+			continue
+		}
+		if err := dh.Client.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout}); err != nil {
+			dh.restartContainers(stopped)
+			return fmt.Errorf("failed to stop container %s: %w", containerID, err)
+		}
+		stopped = append(stopped, containerID)
+	}
+
+	// Copy the data of the project volumes to the service volumes
+	for key, fromVolume := range adoption.Volumes {
+		volumeConfig, ok := dh.Project.Volumes[key]
+		if !ok {
+			continue
+		}
+		toVolume, err := dh.StartDockerVolume(ctx, volumeConfig)
+		if err != nil {
+			dh.restartContainers(stopped)
+			return err
+		}
+		if err := dh.copyVolumeData(ctx, fromVolume, toVolume); err != nil {
+			dh.restartContainers(stopped)
+			return err
+		}
+		dh.StreamChan.LogInfo(fmt.Sprintf("Copied volume %s to %s, the old volume is kept", fromVolume, toVolume))
+	}
+
+	// The data is safe, remove the old containers so their names and ports are free
+	dh.StreamChan.LogStep("Removing the containers of the compose project")
+	for _, containerID := range adoption.ContainerIDs {
+		if err := dh.Client.ContainerRemove(ctx, containerID, container.RemoveOptions{}); err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("failed to remove container %s: %w", containerID, err)
+		}
+	}
+
+	// Networks still used by other containers are left alone
+	for _, networkID := range adoption.NetworkIDs {
+		if err := dh.Client.NetworkRemove(ctx, networkID); err != nil && !client.IsErrNotFound(err) {
+			dh.StreamChan.LogInfo(fmt.Sprintf("Network %s of the compose project was not removed: %v", networkID, err))
+		}
+	}
+
+	return nil
+}
+
+// prepareServiceImages pulls the images of the service. An image that can not be pulled is used when it exists on the server,
+// like the images docker compose built for the project, which are in no registry.
+func (dh *DockerHandler) prepareServiceImages(ctx context.Context) error {
+	dh.StreamChan.LogStep("Preparing the images of the service")
+	dh.preparedImages = make(map[string]bool)
+	for _, service := range dh.Project.Services {
+		if service.Image == "" || dh.preparedImages[service.Image] {
+			continue
+		}
+
+		pullErr := dh.PullDockerImage(ctx, service.Image)
+		if pullErr != nil {
+			if _, err := dh.Client.ImageInspect(ctx, service.Image); err != nil {
+				return fmt.Errorf("image %s can not be pulled and does not exist on the server: %w", service.Image, pullErr)
+			}
+			dh.StreamChan.LogInfo(fmt.Sprintf("Image %s can not be pulled, using the image on the server", service.Image))
+		}
+		dh.preparedImages[service.Image] = true
+	}
+	return nil
+}
+
+// restartContainers starts the containers that were running before a failed adoption again
+func (dh *DockerHandler) restartContainers(containerIDs []string) {
+	if len(containerIDs) == 0 {
+		return
+	}
+	dh.StreamChan.LogStep("Starting the containers of the compose project again")
+	for _, containerID := range containerIDs {
+		if err := dh.Client.ContainerStart(context.Background(), containerID, container.StartOptions{}); err != nil {
+			dh.StreamChan.LogError(fmt.Sprintf("Failed to start container %s again: %v", containerID, err))
+		}
+	}
+}
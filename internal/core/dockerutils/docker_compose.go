@@ -0,0 +1,31 @@
+package dockerutils
+
+import (
+	"github.com/compose-spec/compose-go/v2/types"
+
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+)
+
+// ComposeFiles returns the compose files of a service in load order, the main compose file first
+func ComposeFiles(config models.ServiceComposeConfig) []dockeryaml.ComposeFile {
+	files := []dockeryaml.ComposeFile{{Filename: dockeryaml.MainComposeFileName, Content: config.ComposeFile, Override: true}}
+	for _, file := range config.Files {
+		files = append(files, dockeryaml.ComposeFile{Filename: file.Filename, Content: file.Content, Override: file.Override})
+	}
+	return files
+}
+
+// EnvFiles returns the named env files of a service that env_file entries resolve against
+func EnvFiles(envFiles []models.ServiceEnvFile) []dockeryaml.EnvFile {
+	files := make([]dockeryaml.EnvFile, 0, len(envFiles))
+	for _, envFile := range envFiles {
+		files = append(files, dockeryaml.EnvFile{Name: envFile.Name, Content: envFile.Content})
+	}
+	return files
+}
+
+// ParseServiceCompose loads the compose files of a service with its profiles activated and its env files available to env_file
+func ParseServiceCompose(config models.ServiceComposeConfig, envFiles []models.ServiceEnvFile, projectName string) (*types.Project, error) {
+	return dockeryaml.ParseComposeFiles(ComposeFiles(config), EnvFiles(envFiles), config.Profiles, projectName)
+}
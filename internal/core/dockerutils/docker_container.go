@@ -13,6 +13,7 @@ import (
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
 	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/generator"
 )
 
 func (dh *DockerHandler) StartDockerContainers(ctx context.Context, tx pgx.Tx) error {
@@ -73,7 +74,7 @@ func (dh *DockerHandler) StartDockerContainer(ctx context.Context, serviceName s
 		dh.StreamChan.LogInfo(fmt.Sprintf("Found existing container %s in state: %s", containerName, existingContainer.State))
 
 		// Check if the existing container is from our service (has our labels)
-		serviceIDLabel, hasServiceLabel := existingContainer.Labels["starker.service.id"]
+		serviceIDLabel, hasServiceLabel := existingContainer.Labels[generator.ServiceIDLabel]
 		isOurContainer := hasServiceLabel && serviceIDLabel == dh.NamingGenerator.ServiceID()
 
 		if !isOurContainer {
@@ -102,6 +103,14 @@ func (dh *DockerHandler) StartDockerContainer(ctx context.Context, serviceName s
 		return "", fmt.Errorf("failed to convert service configuration: %w", err)
 	}
 
+	// Run the exact image pulled for this deployment, even when the tag moved since
+	if pinnedImage, ok := dh.pinnedImages[serviceName]; ok {
+		containerConfig.Image = pinnedImage
+	}
+
+	// Mount the named volumes, StartDockerVolumes created them under the service scoped name
+	hostConfig.Mounts = append(hostConfig.Mounts, dh.namedVolumeMounts(serviceConfig)...)
+
 	// Create the Docker container
 	resp, err := dh.Client.ContainerCreate(ctx, containerConfig, hostConfig, networkConfig, nil, containerName)
 	if err != nil {
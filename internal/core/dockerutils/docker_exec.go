@@ -0,0 +1,74 @@
+package dockerutils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// ExecOptions configures an interactive exec session in a container
+type ExecOptions struct {
+	Cmd  []string // Command to run, usually a shell
+	Cols uint     // Initial terminal width
+	Rows uint     // Initial terminal height
+}
+
+// ExecSession is a running exec process attached with a TTY
+type ExecSession struct {
+	ID   string                 // Docker exec ID, used to resize the terminal
+	Conn types.HijackedResponse // Raw stream, stdin is written to Conn.Conn and output is read from Conn.Reader
+}
+
+// StartContainerExec creates an exec process with a TTY in the container and attaches to it
+func (dh *DockerHandler) StartContainerExec(ctx context.Context, containerID string, options ExecOptions) (*ExecSession, error) {
+	execOptions := container.ExecOptions{
+		Cmd:          options.Cmd,
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	if options.Cols > 0 && options.Rows > 0 {
+		execOptions.ConsoleSize = &[2]uint{options.Rows, options.Cols}
+	}
+
+	// Create the exec process
+	execResp, err := dh.Client.ContainerExecCreate(ctx, containerID, execOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec in container %s: %w", containerID, err)
+	}
+
+	// Attach to the exec process, this also starts it
+	hijacked, err := dh.Client.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{
+		Tty:         true,
+		ConsoleSize: execOptions.ConsoleSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to exec %s: %w", execResp.ID, err)
+	}
+
+	return &ExecSession{ID: execResp.ID, Conn: hijacked}, nil
+}
+
+// ResizeContainerExec resizes the TTY of a running exec process
+func (dh *DockerHandler) ResizeContainerExec(ctx context.Context, execID string, cols, rows uint) error {
+	err := dh.Client.ContainerExecResize(ctx, execID, container.ResizeOptions{
+		Width:  cols,
+		Height: rows,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resize exec %s: %w", execID, err)
+	}
+	return nil
+}
+
+// GetContainerExecExitCode returns the exit code of a finished exec process
+func (dh *DockerHandler) GetContainerExecExitCode(ctx context.Context, execID string) (int, error) {
+	inspect, err := dh.Client.ContainerExecInspect(ctx, execID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect exec %s: %w", execID, err)
+	}
+	return inspect.ExitCode, nil
+}
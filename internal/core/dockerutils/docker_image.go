@@ -5,30 +5,97 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types/image"
 	"github.com/jackc/pgx/v5"
+	"github.com/segmentio/ksuid"
 	"go.uber.org/zap"
 
 	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
 )
 
-// PullDockerImages pulls all required Docker images from the compose project
+// PullDockerImages pulls all required Docker images from the compose project, records the resolved
+// image of every compose service as a new deployment and pins the containers to it
 func (dh *DockerHandler) PullDockerImages(ctx context.Context, tx pgx.Tx) error {
-	for _, service := range dh.Project.Services {
+	deploymentID := ksuid.New().String()
+	dh.pinnedImages = make(map[string]string)
+
+	for serviceName, service := range dh.Project.Services {
 		if service.Image == "" {
 			continue
 		}
 
-		// Pull the Docker image
-		err := dh.PullDockerImage(ctx, service.Image)
+		// Pull the Docker image, unless it was prepared before the deployment started
+		if !dh.preparedImages[service.Image] {
+			err := dh.PullDockerImage(ctx, service.Image)
+			if err != nil {
+				zap.L().Error("failed to pull docker image", zap.Error(err), zap.String("image", service.Image))
+				dh.StreamChan.LogError(fmt.Sprintf("Failed to pull docker image %s: %v", service.Image, err))
+				return err
+			}
+
+			dh.StreamChan.LogInfo(fmt.Sprintf("Image %s pulled successfully", service.Image))
+		}
+
+		// Record the image the tag resolved to
+		serviceImage, err := dh.resolveServiceImage(ctx, serviceName, service.Image, deploymentID)
 		if err != nil {
-			zap.L().Error("failed to pull docker image", zap.Error(err), zap.String("image", service.Image))
-			dh.StreamChan.LogError(fmt.Sprintf("Failed to pull docker image %s: %v", service.Image, err))
+			dh.StreamChan.LogError(fmt.Sprintf("Failed to resolve docker image %s: %v", service.Image, err))
 			return err
 		}
+		if err := repository.CreateServiceImage(ctx, tx, *serviceImage); err != nil {
+			dh.StreamChan.LogError(fmt.Sprintf("Failed to record docker image %s: %v", service.Image, err))
+			return fmt.Errorf("failed to record docker image %s: %w", service.Image, err)
+		}
 
-		dh.StreamChan.LogInfo(fmt.Sprintf("Image %s pulled successfully", service.Image))
+		dh.pinnedImages[serviceName] = *serviceImage.ImageID
+		if serviceImage.RepoDigest != nil {
+			dh.pinnedImages[serviceName] = *serviceImage.RepoDigest
+		}
+		dh.StreamChan.LogInfo(fmt.Sprintf("Pinned service %s to %s", serviceName, dh.pinnedImages[serviceName]))
+	}
+
+	return nil
+}
+
+// resolveServiceImage inspects a pulled image and returns its record for the deployment
+func (dh *DockerHandler) resolveServiceImage(ctx context.Context, serviceName, imageName, deploymentID string) (*models.ServiceImage, error) {
+	inspect, err := dh.Client.ImageInspect(ctx, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect docker image %s: %w", imageName, err)
+	}
+
+	now := time.Now()
+	return &models.ServiceImage{
+		ID:           ksuid.New().String(),
+		ServiceID:    dh.NamingGenerator.ServiceID(),
+		ImageID:      &inspect.ID,
+		ImageName:    imageName,
+		RepoDigest:   repoDigest(imageName, inspect.RepoDigests),
+		ServiceName:  serviceName,
+		DeploymentID: deploymentID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}, nil
+}
+
+// repoDigest returns the digest reference of the repository the image was pulled from,
+// or nil when the image has none, for example because it only exists locally
+func repoDigest(imageName string, repoDigests []string) *string {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return nil
+	}
+
+	for _, digest := range repoDigests {
+		digested, err := reference.ParseNormalizedNamed(digest)
+		if err == nil && digested.Name() == named.Name() {
+			return &digest
+		}
 	}
 
 	return nil
@@ -0,0 +1,97 @@
+package dockerutils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+)
+
+// cleanupSupersededImages runs RemoveSupersededImages in its own transaction and only logs failures,
+// it runs after the deployment stream is closed
+func (dh *DockerHandler) cleanupSupersededImages(ctx context.Context) {
+	tx, err := repository.StartTransaction(dh.DB, ctx)
+	if err != nil {
+		zap.L().Warn("Failed to begin transaction for image cleanup", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	if err := dh.RemoveSupersededImages(ctx, tx); err != nil {
+		zap.L().Warn("Failed to remove superseded images", zap.String("server_id", dh.NamingGenerator.ServerID()), zap.Error(err))
+		return
+	}
+	repository.CommitTransaction(tx, ctx)
+}
+
+// RemoveSupersededImages removes the images recorded for the services on the server that none of the last
+// ImageRetention deployments of any service uses anymore, and drops the records outside the retention.
+// Images that Docker refuses to remove, because a container still uses them or another tag points to them,
+// keep their records and are tried again after the next deployment.
+func (dh *DockerHandler) RemoveSupersededImages(ctx context.Context, tx pgx.Tx) error {
+	if dh.ImageRetention <= 0 {
+		return nil
+	}
+
+	images, err := repository.GetServerServiceImages(ctx, tx, dh.NamingGenerator.ServerID())
+	if err != nil {
+		return fmt.Errorf("failed to get service images: %w", err)
+	}
+
+	// The records are newest first, so the deployments of every service are collected newest first as well
+	deployments := make(map[string][]string)
+	keptImages := make(map[string]bool)
+	var superseded []models.ServiceImage
+	for _, serviceImage := range images {
+		serviceDeployments := deployments[serviceImage.ServiceID]
+		if !slices.Contains(serviceDeployments, serviceImage.DeploymentID) {
+			serviceDeployments = append(serviceDeployments, serviceImage.DeploymentID)
+			deployments[serviceImage.ServiceID] = serviceDeployments
+		}
+
+		if slices.Index(serviceDeployments, serviceImage.DeploymentID) < dh.ImageRetention {
+			if serviceImage.ImageID != nil {
+				keptImages[*serviceImage.ImageID] = true
+			}
+			continue
+		}
+		superseded = append(superseded, serviceImage)
+	}
+
+	// Records of images a kept deployment still uses can go right away, the others once their image is removed
+	var droppedRecords []string
+	recordsByImage := make(map[string][]string)
+	for _, serviceImage := range superseded {
+		if serviceImage.ImageID == nil || keptImages[*serviceImage.ImageID] {
+			droppedRecords = append(droppedRecords, serviceImage.ID)
+			continue
+		}
+		recordsByImage[*serviceImage.ImageID] = append(recordsByImage[*serviceImage.ImageID], serviceImage.ID)
+	}
+
+	for imageID, recordIDs := range recordsByImage {
+		_, err := dh.Client.ImageRemove(ctx, imageID, image.RemoveOptions{PruneChildren: true})
+		if err != nil && !client.IsErrNotFound(err) {
+			zap.L().Warn("Failed to remove superseded image", zap.String("image", imageID), zap.Error(err))
+			continue
+		}
+		zap.L().Info("Removed superseded image", zap.String("image", imageID))
+		droppedRecords = append(droppedRecords, recordIDs...)
+	}
+
+	if len(droppedRecords) == 0 {
+		return nil
+	}
+	if err := repository.DeleteServiceImagesByIDs(ctx, tx, droppedRecords); err != nil {
+		return fmt.Errorf("failed to delete superseded image records: %w", err)
+	}
+
+	return nil
+}
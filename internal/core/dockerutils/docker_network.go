@@ -109,16 +109,16 @@ func (dh *DockerHandler) RemoveDockerNetworks(ctx context.Context, tx pgx.Tx) er
 			continue
 		}
 		useByOther, err := dh.RemoveDockerNetwork(ctx, *serviceNetwork.NetworkID, serviceNetwork.NetworkName)
-		if err != nil {
-			zap.L().Error("failed to remove docker network", zap.Error(err), zap.String("network", serviceNetwork.NetworkName))
-			dh.StreamChan.LogError(fmt.Sprintf("Failed to remove Docker network %s: %v", serviceNetwork.NetworkName, err))
-			return err
-		}
 		if useByOther {
 			// If the network is still in use, we can't remove it
 			dh.StreamChan.LogInfo(fmt.Sprintf("Docker network %s is still in use by other containers, skipping removal", serviceNetwork.NetworkName))
 			continue
 		}
+		if err != nil {
+			zap.L().Error("failed to remove docker network", zap.Error(err), zap.String("network", serviceNetwork.NetworkName))
+			dh.StreamChan.LogError(fmt.Sprintf("Failed to remove Docker network %s: %v", serviceNetwork.NetworkName, err))
+			return err
+		}
 	}
 
 	// Delete all network records from database
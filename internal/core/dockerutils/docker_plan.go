@@ -0,0 +1,360 @@
+package dockerutils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// PlanAction is what a deployment does with an image, network, volume or container
+type PlanAction string
+
+const (
+	PlanActionPull     PlanAction = "pull"     // The image is not on the server and is pulled
+	PlanActionRefresh  PlanAction = "refresh"  // The image is on the server, the tag is pulled again in case it moved
+	PlanActionCreate   PlanAction = "create"   // The resource does not exist and is created
+	PlanActionReuse    PlanAction = "reuse"    // The existing resource is used as is
+	PlanActionRecreate PlanAction = "recreate" // The existing resource is removed and created again
+	PlanActionMigrate  PlanAction = "migrate"  // The volume is recreated and its data copied over
+	PlanActionAbort    PlanAction = "abort"    // The deployment stops here
+	PlanActionKeep     PlanAction = "keep"     // The container is no longer in the compose file and is left alone
+)
+
+// DeploymentPlan is what starting the service would do on the server, computed without changing anything
+type DeploymentPlan struct {
+	Images        []PlannedImage     `json:"images"`         // Images of the compose services
+	Networks      []PlannedResource  `json:"networks"`       // Networks of the compose file
+	Volumes       []PlannedResource  `json:"volumes"`        // Volumes of the compose file
+	Containers    []PlannedContainer `json:"containers"`     // Containers of the compose services and leftovers of the service
+	PortConflicts []PortConflict     `json:"port_conflicts"` // Published ports that are already taken
+	Warnings      []string           `json:"warnings"`       // Things to know before deploying
+	Errors        []string           `json:"errors"`         // Problems that make the deployment fail
+}
+
+// PlannedImage is an image the deployment pulls
+type PlannedImage struct {
+	Service string     `json:"service" example:"web"`                         // Compose service
+	Image   string     `json:"image" example:"nginx:1.27"`                    // Image reference of the compose file
+	Action  PlanAction `json:"action" example:"pull"`                         // pull or refresh
+	ImageID string     `json:"image_id,omitempty" example:"sha256:abc123..."` // ID of the image on the server
+}
+
+// PlannedResource is a network or volume of the deployment
+type PlannedResource struct {
+	Name         string     `json:"name" example:"data"`                                      // Key in the compose file
+	ResourceName string     `json:"resource_name" example:"data-2gFq1xRzGv0ZsVqB4dJgoYJhP1K"` // Docker name on the server
+	Action       PlanAction `json:"action" example:"create"`                                  // create, reuse, recreate, migrate or abort
+	Differences  []string   `json:"differences,omitempty"`                                    // Why an existing volume does not match the compose file
+}
+
+// PlannedContainer is a container of the deployment
+type PlannedContainer struct {
+	Service       string     `json:"service" example:"web"`                                    // Compose service, empty for leftovers
+	ContainerName string     `json:"container_name" example:"web-2gFq1xRzGv0ZsVqB4dJgoYJhP1K"` // Docker container name
+	Action        PlanAction `json:"action" example:"recreate"`                                // create, recreate, keep or abort
+	State         string     `json:"state,omitempty" example:"running"`                        // State of the existing container
+}
+
+// PortConflict is a published port of the compose file that another container already publishes
+type PortConflict struct {
+	Service   string `json:"service" example:"web"`               // Compose service publishing the port
+	HostIP    string `json:"host_ip,omitempty" example:"0.0.0.0"` // Host address of the port
+	HostPort  int    `json:"host_port" example:"8080"`            // Published port
+	Protocol  string `json:"protocol" example:"tcp"`              // tcp, udp or sctp
+	Container string `json:"container" example:"nginx-proxy"`     // Container holding the port, or the other compose service
+}
+
+// PlanDeployment inspects the server and returns what StartDockerCompose would do, nothing is changed
+func (dh *DockerHandler) PlanDeployment(ctx context.Context) (*DeploymentPlan, error) {
+	plan := &DeploymentPlan{
+		Images:        []PlannedImage{},
+		Networks:      []PlannedResource{},
+		Volumes:       []PlannedResource{},
+		Containers:    []PlannedContainer{},
+		PortConflicts: []PortConflict{},
+		Warnings:      []string{},
+		Errors:        []string{},
+	}
+
+	serviceNames := make([]string, 0, len(dh.Project.Services))
+	for name := range dh.Project.Services {
+		serviceNames = append(serviceNames, name)
+	}
+	slices.Sort(serviceNames)
+
+	if err := dh.planImages(ctx, plan, serviceNames); err != nil {
+		return nil, err
+	}
+	if err := dh.planNetworks(ctx, plan); err != nil {
+		return nil, err
+	}
+	if err := dh.planVolumes(ctx, plan); err != nil {
+		return nil, err
+	}
+	if err := dh.planContainers(ctx, plan, serviceNames); err != nil {
+		return nil, err
+	}
+	if err := dh.planPorts(ctx, plan, serviceNames); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// planImages checks which images are already on the server
+func (dh *DockerHandler) planImages(ctx context.Context, plan *DeploymentPlan, serviceNames []string) error {
+	for _, name := range serviceNames {
+		service := dh.Project.Services[name]
+		if service.Image == "" {
+			plan.Errors = append(plan.Errors, fmt.Sprintf("service %s has no image, building images is not supported", name))
+			continue
+		}
+
+		planned := PlannedImage{Service: name, Image: service.Image, Action: PlanActionPull}
+		inspect, err := dh.Client.ImageInspect(ctx, service.Image)
+		switch {
+		case err == nil:
+			planned.Action = PlanActionRefresh
+			planned.ImageID = inspect.ID
+		case !client.IsErrNotFound(err):
+			return fmt.Errorf("failed to inspect image %s: %w", service.Image, err)
+		}
+		plan.Images = append(plan.Images, planned)
+	}
+	return nil
+}
+
+// planNetworks checks which networks already exist, existing networks are used as they are
+func (dh *DockerHandler) planNetworks(ctx context.Context, plan *DeploymentPlan) error {
+	for _, key := range sortedKeys(dh.Project.Networks) {
+		networkName := dh.NamingGenerator.NetworkName(dh.Project.Networks[key].Name)
+		planned := PlannedResource{Name: key, ResourceName: networkName, Action: PlanActionCreate}
+
+		_, err := dh.Client.NetworkInspect(ctx, networkName, network.InspectOptions{})
+		switch {
+		case err == nil:
+			planned.Action = PlanActionReuse
+		case !client.IsErrNotFound(err):
+			return fmt.Errorf("failed to inspect network %s: %w", networkName, err)
+		}
+		plan.Networks = append(plan.Networks, planned)
+	}
+	return nil
+}
+
+// planVolumes compares the existing volumes with the compose file and applies the volume strategy
+func (dh *DockerHandler) planVolumes(ctx context.Context, plan *DeploymentPlan) error {
+	results, err := dh.CheckVolumeCompatibility(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check volume compatibility: %w", err)
+	}
+
+	for _, result := range results {
+		planned := PlannedResource{Name: result.Name, ResourceName: result.VolumeName, Action: PlanActionCreate}
+		switch {
+		case !result.Exists:
+		case result.Compatible:
+			planned.Action = PlanActionReuse
+		default:
+			planned.Differences = result.Differences
+			switch dh.VolumeStrategy {
+			case VolumeStrategyRecreate:
+				planned.Action = PlanActionRecreate
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("volume %s is recreated empty, its data is lost", result.VolumeName))
+			case VolumeStrategyMigrate:
+				planned.Action = PlanActionMigrate
+			default:
+				planned.Action = PlanActionAbort
+				plan.Errors = append(plan.Errors, fmt.Sprintf("volume %s does not match the compose file, use volume_strategy recreate or migrate", result.VolumeName))
+			}
+		}
+		plan.Volumes = append(plan.Volumes, planned)
+	}
+	return nil
+}
+
+// planContainers checks the existing containers of the service and containers blocking the names
+func (dh *DockerHandler) planContainers(ctx context.Context, plan *DeploymentPlan, serviceNames []string) error {
+	filterBuilder := generator.NewFilterBuilder(dh.NamingGenerator)
+	serviceContainers, err := dh.Client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filterBuilder.ProjectFilters(dh.NamingGenerator.ProjectName()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list containers of the service: %w", err)
+	}
+
+	planned := make(map[string]bool)
+	for _, name := range serviceNames {
+		containerName := dh.NamingGenerator.ContainerName(name)
+		planned[containerName] = true
+
+		existing, err := dh.checkExistingContainer(ctx, containerName)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case existing == nil:
+			plan.Containers = append(plan.Containers, PlannedContainer{Service: name, ContainerName: containerName, Action: PlanActionCreate})
+		case existing.Labels[generator.ServiceIDLabel] == dh.NamingGenerator.ServiceID():
+			plan.Containers = append(plan.Containers, PlannedContainer{Service: name, ContainerName: containerName, Action: PlanActionRecreate, State: existing.State})
+		default:
+			plan.Containers = append(plan.Containers, PlannedContainer{Service: name, ContainerName: containerName, Action: PlanActionAbort, State: existing.State})
+			plan.Errors = append(plan.Errors, fmt.Sprintf("container name %s is taken by a container of another service", containerName))
+		}
+	}
+
+	// Containers of services removed from the compose file are not touched by a deployment
+	for _, summary := range serviceContainers {
+		containerName := listedContainerName(summary)
+		if planned[containerName] {
+			continue
+		}
+		plan.Containers = append(plan.Containers, PlannedContainer{ContainerName: containerName, Action: PlanActionKeep, State: summary.State})
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("container %s is no longer in the compose file and keeps its current state", containerName))
+	}
+
+	return nil
+}
+
+// planPorts adds the published ports that are already taken and the invalid published ports to the plan
+func (dh *DockerHandler) planPorts(ctx context.Context, plan *DeploymentPlan, serviceNames []string) error {
+	for _, name := range serviceNames {
+		for _, port := range dh.Project.Services[name].Ports {
+			if _, err := publishedPorts(port); err != nil {
+				plan.Errors = append(plan.Errors, fmt.Sprintf("service %s: %v", name, err))
+			}
+		}
+	}
+
+	conflicts, err := dh.PortConflicts(ctx)
+	if err != nil {
+		return err
+	}
+	plan.PortConflicts = append(plan.PortConflicts, conflicts...)
+	if len(conflicts) > 0 {
+		plan.Errors = append(plan.Errors, fmt.Sprintf("%d published ports are already in use", len(conflicts)))
+	}
+
+	return nil
+}
+
+// PortConflicts finds published ports that running containers of other services or two compose services both use.
+// Invalid published ports are skipped, loading the compose file reports them.
+func (dh *DockerHandler) PortConflicts(ctx context.Context) ([]PortConflict, error) {
+	running, err := dh.Client.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list running containers: %w", err)
+	}
+
+	serviceNames := make([]string, 0, len(dh.Project.Services))
+	for name := range dh.Project.Services {
+		serviceNames = append(serviceNames, name)
+	}
+	slices.Sort(serviceNames)
+
+	type publishedPort struct {
+		service string
+		hostIP  string
+		port    int
+		proto   string
+	}
+	var published []publishedPort
+	for _, name := range serviceNames {
+		for _, port := range dh.Project.Services[name].Ports {
+			ports, _ := publishedPorts(port)
+			for _, p := range ports {
+				published = append(published, publishedPort{service: name, hostIP: port.HostIP, port: p, proto: protocol(port)})
+			}
+		}
+	}
+
+	conflicts := []PortConflict{}
+	serviceID := dh.NamingGenerator.ServiceID()
+	for i, port := range published {
+		// Two compose services publishing the same port
+		for _, other := range published[:i] {
+			if other.port == port.port && other.proto == port.proto && hostIPsOverlap(other.hostIP, port.hostIP) {
+				conflicts = append(conflicts, PortConflict{Service: port.service, HostIP: port.hostIP, HostPort: port.port, Protocol: port.proto, Container: other.service})
+			}
+		}
+
+		// Running containers outside the service, the containers of the service are recreated and free their ports
+		for _, summary := range running {
+			if serviceID != "" && summary.Labels[generator.ServiceIDLabel] == serviceID {
+				continue
+			}
+			for _, holder := range summary.Ports {
+				if int(holder.PublicPort) == port.port && holder.Type == port.proto && hostIPsOverlap(holder.IP, port.hostIP) {
+					conflicts = append(conflicts, PortConflict{
+						Service:   port.service,
+						HostIP:    port.hostIP,
+						HostPort:  port.port,
+						Protocol:  port.proto,
+						Container: listedContainerName(summary),
+					})
+					break
+				}
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// publishedPorts returns the host ports a port definition publishes, ranges are expanded
+func publishedPorts(port types.ServicePortConfig) ([]int, error) {
+	if port.Published == "" {
+		return nil, nil
+	}
+	start, end, err := nat.ParsePortRangeToInt(port.Published)
+	if err != nil {
+		return nil, fmt.Errorf("invalid published port %q: %w", port.Published, err)
+	}
+	ports := make([]int, 0, end-start+1)
+	for p := start; p <= end; p++ {
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// protocol returns the protocol of a port definition, tcp when it is not set
+func protocol(port types.ServicePortConfig) string {
+	if port.Protocol == "" {
+		return "tcp"
+	}
+	return strings.ToLower(port.Protocol)
+}
+
+// hostIPsOverlap reports whether two host addresses of a published port collide, an empty or wildcard address binds all
+func hostIPsOverlap(a, b string) bool {
+	wildcard := func(ip string) bool { return ip == "" || ip == "0.0.0.0" || ip == "::" }
+	return wildcard(a) || wildcard(b) || a == b
+}
+
+// listedContainerName returns the name of a listed container, Docker lists names with a leading slash
+func listedContainerName(summary container.Summary) string {
+	if len(summary.Names) == 0 {
+		return summary.ID
+	}
+	return strings.TrimPrefix(summary.Names[0], "/")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,195 @@
+package dockerutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	dockervolume "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// PurgeResource describes a single Docker resource that belongs to a service
+type PurgeResource struct {
+	ID    string `json:"id,omitempty" example:"abc123def456"`            // Docker resource ID
+	Name  string `json:"name" example:"web-2gFq1xRzGv0ZsVqB4dJgoYJhP1K"` // Docker resource name
+	State string `json:"state,omitempty" example:"running"`              // Container state (containers only)
+}
+
+// PurgePlan lists every Docker resource on the server that belongs to a service
+type PurgePlan struct {
+	Containers   []PurgeResource `json:"containers"`         // Containers that will be removed
+	Networks     []PurgeResource `json:"networks"`           // Networks that will be removed
+	Volumes      []PurgeResource `json:"volumes"`            // Volumes that belong to the service
+	PurgeVolumes bool            `json:"purge_volumes"`      // Whether the volumes will be removed as well
+	Warnings     []string        `json:"warnings,omitempty"` // Resources that were left on the server and why
+}
+
+// NewPurgePlan returns a plan without any resources
+func NewPurgePlan() *PurgePlan {
+	return &PurgePlan{
+		Containers: []PurgeResource{},
+		Networks:   []PurgeResource{},
+		Volumes:    []PurgeResource{},
+	}
+}
+
+// ListServiceResources discovers all containers, networks and volumes of the service on the server.
+// Resources are found by their starker labels and by the records stored in the database,
+// so resources left behind by failed deploys are included as well.
+func (dh *DockerHandler) ListServiceResources(ctx context.Context, tx pgx.Tx) (*PurgePlan, error) {
+	plan := NewPurgePlan()
+
+	filterBuilder := generator.NewFilterBuilder(dh.NamingGenerator)
+	projectName := dh.NamingGenerator.ProjectName()
+
+	// Find all containers carrying the service labels (including stopped ones)
+	containers, err := dh.Client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filterBuilder.ProjectFilters(projectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		plan.Containers = append(plan.Containers, PurgeResource{ID: c.ID, Name: name, State: c.State})
+	}
+
+	// Find all networks carrying the service labels
+	networks, err := dh.Client.NetworkList(ctx, network.ListOptions{
+		Filters: filterBuilder.NetworkFilters(projectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	seenNetworks := make(map[string]bool)
+	for _, n := range networks {
+		seenNetworks[n.Name] = true
+		plan.Networks = append(plan.Networks, PurgeResource{ID: n.ID, Name: n.Name})
+	}
+
+	// Add networks recorded in the database but missing the labels
+	serviceNetworks, err := repository.GetServiceNetworks(ctx, tx, dh.NamingGenerator.ServiceID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service networks from database: %w", err)
+	}
+	for _, serviceNetwork := range serviceNetworks {
+		if seenNetworks[serviceNetwork.NetworkName] {
+			continue
+		}
+		networkResource, err := dh.Client.NetworkInspect(ctx, serviceNetwork.NetworkName, network.InspectOptions{})
+		if err != nil {
+			if client.IsErrNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to inspect network %s: %w", serviceNetwork.NetworkName, err)
+		}
+		seenNetworks[serviceNetwork.NetworkName] = true
+		plan.Networks = append(plan.Networks, PurgeResource{ID: networkResource.ID, Name: networkResource.Name})
+	}
+
+	// Find all volumes carrying the service labels
+	volumes, err := dh.Client.VolumeList(ctx, dockervolume.ListOptions{
+		Filters: filterBuilder.VolumeFilters(projectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	seenVolumes := make(map[string]bool)
+	for _, v := range volumes.Volumes {
+		seenVolumes[v.Name] = true
+		plan.Volumes = append(plan.Volumes, PurgeResource{Name: v.Name})
+	}
+
+	// Add volumes recorded in the database but missing the labels
+	serviceVolumes, err := repository.GetServiceVolumes(ctx, tx, dh.NamingGenerator.ServiceID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service volumes from database: %w", err)
+	}
+	for _, serviceVolume := range serviceVolumes {
+		if seenVolumes[serviceVolume.VolumeName] {
+			continue
+		}
+		if _, err := dh.Client.VolumeInspect(ctx, serviceVolume.VolumeName); err != nil {
+			if client.IsErrNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to inspect volume %s: %w", serviceVolume.VolumeName, err)
+		}
+		seenVolumes[serviceVolume.VolumeName] = true
+		plan.Volumes = append(plan.Volumes, PurgeResource{Name: serviceVolume.VolumeName})
+	}
+
+	return plan, nil
+}
+
+// RemoveServiceResources removes the resources listed in the plan from the server.
+// Volumes are only removed when the plan has PurgeVolumes set. Networks other containers
+// are still connected to are kept like RemoveDockerNetworks does, they are listed in the plan warnings.
+func (dh *DockerHandler) RemoveServiceResources(ctx context.Context, plan *PurgePlan) error {
+	// Containers go first so that networks and volumes are no longer in use
+	for _, c := range plan.Containers {
+		err := dh.Client.ContainerRemove(ctx, c.ID, container.RemoveOptions{
+			Force: true,
+		})
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove container %s: %w", c.Name, err)
+		}
+		zap.L().Info("Removed Docker container", zap.String("container", c.Name))
+	}
+
+	for _, n := range plan.Networks {
+		networkInspect, err := dh.Client.NetworkInspect(ctx, n.ID, network.InspectOptions{})
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to inspect network %s: %w", n.Name, err)
+		}
+		if len(networkInspect.Containers) > 0 {
+			zap.L().Warn("Docker network is still in use by other containers, skipping removal", zap.String("network", n.Name))
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("network %s is still in use by other containers and was kept", n.Name))
+			continue
+		}
+
+		err = dh.Client.NetworkRemove(ctx, n.ID)
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove network %s: %w", n.Name, err)
+		}
+		zap.L().Info("Removed Docker network", zap.String("network", n.Name))
+	}
+
+	if !plan.PurgeVolumes {
+		return nil
+	}
+
+	for _, v := range plan.Volumes {
+		err := dh.Client.VolumeRemove(ctx, v.Name, false)
+		if client.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove volume %s: %w", v.Name, err)
+		}
+		zap.L().Info("Removed Docker volume", zap.String("volume", v.Name))
+	}
+
+	return nil
+}
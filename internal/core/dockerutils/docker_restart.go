@@ -20,7 +20,7 @@ func (dh *DockerHandler) RestartDockerCompose(ctx context.Context) error {
 		// Phase 1: Stop everything
 		dh.StreamChan.LogStep("Stopping and removing existing resources")
 
-		err := dh.StopDockerCompose(ctx)
+		err := dh.StopDockerCompose(ctx, false)
 		if err != nil {
 			zap.L().Error("Failed to initiate stop phase in RestartDockerCompose", zap.Error(err))
 			dh.StreamChan.FinalError <- fmt.Errorf("failed to initiate stop phase: %w", err)
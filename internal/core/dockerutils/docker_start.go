@@ -105,6 +105,13 @@ func (dh *DockerHandler) StartDockerCompose(ctx context.Context) error {
 
 		// Docker orchestration completed successfully
 		dh.StreamChan.LogChan <- core.LogInfo("Docker orchestration completed successfully")
+
+		// Remove the images the last deployments no longer use after the deployment is reported as done,
+		// it succeeded either way and the removal can take a while on a slow host
+		if dh.ImageRetention > 0 {
+			dh.StreamChan.LogChan <- core.LogInfo("Removing superseded images in the background")
+			go dh.cleanupSupersededImages(context.WithoutCancel(ctx))
+		}
 	}()
 
 	return nil
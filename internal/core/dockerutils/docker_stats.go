@@ -0,0 +1,119 @@
+package dockerutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// GetContainerStats takes a single stats sample of a container.
+// Docker waits for a second sample internally so the CPU percent can be computed.
+func (dh *DockerHandler) GetContainerStats(ctx context.Context, containerID, containerName string) (*models.ContainerStats, error) {
+	statsReader, err := dh.Client.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats of container %s: %w", containerName, err)
+	}
+	defer statsReader.Body.Close()
+
+	var statsResponse container.StatsResponse
+	if err := json.NewDecoder(statsReader.Body).Decode(&statsResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode stats of container %s: %w", containerName, err)
+	}
+
+	stats := CalculateContainerStats(statsResponse, containerName)
+	return &stats, nil
+}
+
+// StreamContainerStats sends a stats sample of the container roughly every second until the context is canceled
+func (dh *DockerHandler) StreamContainerStats(ctx context.Context, containerID, containerName string, statsChan chan<- models.ContainerStats) error {
+	statsReader, err := dh.Client.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return fmt.Errorf("failed to stream stats of container %s: %w", containerName, err)
+	}
+	defer statsReader.Body.Close()
+
+	decoder := json.NewDecoder(statsReader.Body)
+	for {
+		var statsResponse container.StatsResponse
+		if err := decoder.Decode(&statsResponse); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to decode stats of container %s: %w", containerName, err)
+		}
+
+		select {
+		case statsChan <- CalculateContainerStats(statsResponse, containerName):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// CalculateContainerStats turns the raw Docker stats into the values shown by `docker stats`
+func CalculateContainerStats(statsResponse container.StatsResponse, containerName string) models.ContainerStats {
+	stats := models.ContainerStats{
+		ContainerName: containerName,
+		Resolution:    models.StatsResolutionLive,
+		CPUPercent:    calculateCPUPercent(statsResponse),
+		SampledAt:     statsResponse.Read,
+	}
+	if stats.SampledAt.IsZero() {
+		stats.SampledAt = time.Now()
+	}
+
+	// Memory usage without the page cache, like the Docker CLI
+	memoryUsage := statsResponse.MemoryStats.Usage
+	if inactiveFile, ok := statsResponse.MemoryStats.Stats["inactive_file"]; ok && inactiveFile < memoryUsage {
+		memoryUsage -= inactiveFile // cgroup v2
+	} else if inactiveFile, ok := statsResponse.MemoryStats.Stats["total_inactive_file"]; ok && inactiveFile < memoryUsage {
+		memoryUsage -= inactiveFile // cgroup v1
+	}
+	stats.MemoryUsage = int64(memoryUsage)
+	stats.MemoryLimit = int64(statsResponse.MemoryStats.Limit)
+	if statsResponse.MemoryStats.Limit > 0 {
+		stats.MemoryPercent = float64(memoryUsage) / float64(statsResponse.MemoryStats.Limit) * 100
+	}
+
+	// Sum the traffic of all networks
+	for _, network := range statsResponse.Networks {
+		stats.NetworkRx += int64(network.RxBytes)
+		stats.NetworkTx += int64(network.TxBytes)
+	}
+
+	// Sum the block IO of all devices
+	for _, entry := range statsResponse.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += int64(entry.Value)
+		case "write":
+			stats.BlockWrite += int64(entry.Value)
+		}
+	}
+
+	return stats
+}
+
+// calculateCPUPercent computes the CPU usage between the current and the previous sample
+func calculateCPUPercent(statsResponse container.StatsResponse) float64 {
+	cpuDelta := float64(statsResponse.CPUStats.CPUUsage.TotalUsage) - float64(statsResponse.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(statsResponse.CPUStats.SystemUsage) - float64(statsResponse.PreCPUStats.SystemUsage)
+
+	onlineCPUs := float64(statsResponse.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(statsResponse.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
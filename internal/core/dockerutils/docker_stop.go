@@ -14,7 +14,8 @@ import (
 )
 
 // StopDockerCompose stops the docker compose orchestration in a goroutine with streaming output
-func (dh *DockerHandler) StopDockerCompose(ctx context.Context) error {
+// When purgeVolumes is set the service volumes are removed as well, deleting their data
+func (dh *DockerHandler) StopDockerCompose(ctx context.Context, purgeVolumes bool) error {
 	// Start Docker stop orchestration in a goroutine for streaming
 	go func() {
 		// Create a new transaction for the goroutine
@@ -70,6 +71,20 @@ func (dh *DockerHandler) StopDockerCompose(ctx context.Context) error {
 			return
 		}
 
+		// +-------------------------------------------+
+		// |Purge Docker Volumes                       |
+		// +-------------------------------------------+
+		if purgeVolumes {
+			dh.StreamChan.LogChan <- core.LogStep("Purging Docker volumes")
+
+			err = dh.RemoveDockerVolumes(ctx, tx)
+			if err != nil {
+				dh.StreamChan.ErrChan <- core.LogError(fmt.Sprintf("Failed to purge Docker volumes: %v", err))
+				dh.StreamChan.FinalError <- fmt.Errorf("failed to purge Docker volumes: %w", err)
+				return
+			}
+		}
+
 		// Commit the transaction on successful completion
 		if err := tx.Commit(ctx); err != nil {
 			zap.L().Error("Failed to commit transaction in StopDockerCompose", zap.Error(err))
@@ -3,10 +3,13 @@ package dockerutils
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/mount"
 	dockervolume "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
 	"github.com/jackc/pgx/v5"
 	"github.com/segmentio/ksuid"
 
@@ -47,21 +50,72 @@ func (dh *DockerHandler) StartDockerVolumes(ctx context.Context, tx pgx.Tx) erro
 	return nil
 }
 
-// StartDockerVolume creates a Docker volume and returns the volume ID
+// namedVolumeMounts returns the mounts of the named volumes a service uses.
+// The converter only handles bind mounts, so containers created before this had no named volumes mounted
+// and wrote that data to their own filesystem. Their next recreate mounts the volume, which starts empty.
+func (dh *DockerHandler) namedVolumeMounts(serviceConfig types.ServiceConfig) []mount.Mount {
+	var mounts []mount.Mount
+	for _, serviceVolume := range serviceConfig.Volumes {
+		volumeConfig, ok := dh.Project.Volumes[serviceVolume.Source]
+		if serviceVolume.Type != types.VolumeTypeVolume || !ok {
+			continue
+		}
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeVolume,
+			Source:   dh.NamingGenerator.VolumeName(volumeConfig.Name),
+			Target:   serviceVolume.Target,
+			ReadOnly: serviceVolume.ReadOnly,
+		})
+	}
+	return mounts
+}
+
+// StartDockerVolume creates a Docker volume and returns the volume ID.
+// An existing volume is only reused when it matches the compose definition, otherwise the VolumeStrategy decides.
 func (dh *DockerHandler) StartDockerVolume(ctx context.Context, volumeConfig types.VolumeConfig) (volumeName string, err error) {
 	// Generate volume name using naming generator
 	volumeName = dh.NamingGenerator.VolumeName(volumeConfig.Name)
 
-	// Check if volume already exists
-	volumeResource, err := dh.Client.VolumeInspect(ctx, volumeName)
-	if err == nil {
-		dh.StreamChan.LogChan <- core.LogInfo(fmt.Sprintf("Docker volume %s already exists, using existing volume", volumeName))
-		return volumeResource.Name, nil
+	// Check if volume already exists and still matches the definition
+	compatibility, err := dh.checkVolumeCompatibility(ctx, volumeConfig)
+	if err != nil {
+		dh.StreamChan.LogError(fmt.Sprintf("Failed to check Docker volume %s: %v", volumeName, err))
+		return "", err
+	}
+	if compatibility.Exists {
+		if compatibility.Compatible {
+			dh.StreamChan.LogInfo(fmt.Sprintf("Docker volume %s already exists, using existing volume", volumeName))
+			return volumeName, nil
+		}
+
+		dh.StreamChan.LogInfo(fmt.Sprintf("Docker volume %s does not match the compose definition: %s", volumeName, strings.Join(compatibility.Differences, "; ")))
+
+		switch dh.VolumeStrategy {
+		case VolumeStrategyRecreate:
+			return dh.recreateDockerVolume(ctx, volumeConfig)
+		case VolumeStrategyMigrate:
+			return dh.migrateDockerVolume(ctx, volumeConfig)
+		default:
+			dh.StreamChan.LogError(fmt.Sprintf("Aborting, choose to recreate or migrate the volume %s", volumeName))
+			return "", fmt.Errorf("%w: %s", ErrIncompatibleVolumes, volumeName)
+		}
 	}
 
-	// Generate project name and labels
+	return dh.createDockerVolume(ctx, volumeConfig)
+}
+
+// createDockerVolume creates a Docker volume from the compose definition
+func (dh *DockerHandler) createDockerVolume(ctx context.Context, volumeConfig types.VolumeConfig) (string, error) {
+	volumeName := dh.NamingGenerator.VolumeName(volumeConfig.Name)
+
+	// Generate project name and labels, keeping the labels from the compose file
 	projectName := dh.NamingGenerator.ProjectName()
 	labels := dh.NamingGenerator.GetVolumeLabels(projectName, volumeConfig.Name)
+	for key, value := range volumeConfig.Labels {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
 
 	// Log volume creation start
 	dh.StreamChan.LogStep(fmt.Sprintf("Creating Docker volume: %s", volumeName))
@@ -86,3 +140,59 @@ func (dh *DockerHandler) StartDockerVolume(ctx context.Context, volumeConfig typ
 
 	return dockerVolume.Name, nil
 }
+
+// RemoveDockerVolumes removes all Docker volumes recorded for the service.
+// This deletes the data stored in the volumes, so it must only be called after the user confirmed the purge.
+func (dh *DockerHandler) RemoveDockerVolumes(ctx context.Context, tx pgx.Tx) error {
+	// Get all service volumes from database
+	serviceVolumes, err := repository.GetServiceVolumes(ctx, tx, dh.NamingGenerator.ServiceID())
+	if err != nil {
+		dh.StreamChan.LogError(fmt.Sprintf("Failed to get service volumes from database: %v", err))
+		return fmt.Errorf("failed to get service volumes from database: %w", err)
+	}
+
+	// Remove each Docker volume once, the same volume can be recorded by several deploys
+	removed := make(map[string]bool)
+	for _, serviceVolume := range serviceVolumes {
+		if removed[serviceVolume.VolumeName] {
+			continue
+		}
+		removed[serviceVolume.VolumeName] = true
+
+		if err := dh.RemoveDockerVolume(ctx, serviceVolume.VolumeName); err != nil {
+			return err
+		}
+	}
+
+	// Delete all volume records from database
+	err = repository.DeleteServiceVolumes(ctx, tx, dh.NamingGenerator.ServiceID())
+	if err != nil {
+		dh.StreamChan.LogError(fmt.Sprintf("Failed to delete service volumes from database: %v", err))
+		return fmt.Errorf("failed to delete service volumes from database: %w", err)
+	}
+
+	dh.StreamChan.LogInfo("All Docker volumes purged successfully")
+
+	return nil
+}
+
+// RemoveDockerVolume removes a Docker volume by name, a missing volume is not treated as an error
+func (dh *DockerHandler) RemoveDockerVolume(ctx context.Context, volumeName string) error {
+	// Log volume removal start
+	dh.StreamChan.LogStep(fmt.Sprintf("Removing Docker volume: %s", volumeName))
+
+	err := dh.Client.VolumeRemove(ctx, volumeName, false)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			dh.StreamChan.LogInfo(fmt.Sprintf("Docker volume %s does not exist, skipping removal", volumeName))
+			return nil
+		}
+		dh.StreamChan.LogError(fmt.Sprintf("Failed to remove Docker volume %s: %v", volumeName, err))
+		return fmt.Errorf("failed to remove Docker volume %s: %w", volumeName, err)
+	}
+
+	// Log successful removal
+	dh.StreamChan.LogInfo(fmt.Sprintf("Successfully removed Docker volume: %s", volumeName))
+
+	return nil
+}
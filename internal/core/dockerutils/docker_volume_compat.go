@@ -0,0 +1,256 @@
+package dockerutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	dockervolume "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/segmentio/ksuid"
+)
+
+// VolumeStrategy decides what happens to an existing volume that does not match the compose definition
+type VolumeStrategy string
+
+const (
+	// VolumeStrategyAbort stops the deploy when an incompatible volume is found (default)
+	VolumeStrategyAbort VolumeStrategy = "abort"
+	// VolumeStrategyRecreate removes the incompatible volume and creates a fresh empty one
+	VolumeStrategyRecreate VolumeStrategy = "recreate"
+	// VolumeStrategyMigrate recreates the volume with the new definition and copies the data over
+	VolumeStrategyMigrate VolumeStrategy = "migrate"
+)
+
+// volumeMigrationImage is the helper image used to copy data between volumes
+const volumeMigrationImage = "alpine:latest"
+
+// ErrIncompatibleVolumes is returned when existing volumes do not match the compose definition
+// and the deploy was not told how to handle them
+var ErrIncompatibleVolumes = errors.New("incompatible volumes found")
+
+// VolumeCompatibility is the result of comparing an existing volume with its compose definition
+type VolumeCompatibility struct {
+	Name        string   `json:"name" example:"data"`                                    // Volume name in the compose file
+	VolumeName  string   `json:"volume_name" example:"data-2gFq1xRzGv0ZsVqB4dJgoYJhP1K"` // Docker volume name on the server
+	Exists      bool     `json:"exists" example:"true"`                                  // Whether the volume already exists on the server
+	Compatible  bool     `json:"compatible" example:"false"`                             // Whether the existing volume can be reused
+	Differences []string `json:"differences"`                                            // Human readable list of mismatches
+}
+
+// CheckVolumeCompatibility compares every volume of the compose project against the volume on the server
+func (dh *DockerHandler) CheckVolumeCompatibility(ctx context.Context) ([]VolumeCompatibility, error) {
+	results := []VolumeCompatibility{}
+
+	// Sort the volume names so the result is stable
+	names := make([]string, 0, len(dh.Project.Volumes))
+	for name := range dh.Project.Volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		volumeConfig := dh.Project.Volumes[name]
+		if volumeConfig.Name == "" {
+			volumeConfig.Name = name
+		}
+
+		result, err := dh.checkVolumeCompatibility(ctx, volumeConfig)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// IncompatibleVolumes returns only the existing volumes that can not be reused
+func IncompatibleVolumes(results []VolumeCompatibility) []VolumeCompatibility {
+	incompatible := []VolumeCompatibility{}
+	for _, result := range results {
+		if result.Exists && !result.Compatible {
+			incompatible = append(incompatible, result)
+		}
+	}
+	return incompatible
+}
+
+// checkVolumeCompatibility inspects a single volume and compares driver, driver_opts and labels
+func (dh *DockerHandler) checkVolumeCompatibility(ctx context.Context, volumeConfig types.VolumeConfig) (*VolumeCompatibility, error) {
+	volumeName := dh.NamingGenerator.VolumeName(volumeConfig.Name)
+	result := &VolumeCompatibility{
+		Name:        volumeConfig.Name,
+		VolumeName:  volumeName,
+		Compatible:  true,
+		Differences: []string{},
+	}
+
+	volumeResource, err := dh.Client.VolumeInspect(ctx, volumeName)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to inspect Docker volume %s: %w", volumeName, err)
+	}
+	result.Exists = true
+	result.Differences = compareVolume(volumeResource, volumeConfig)
+	result.Compatible = len(result.Differences) == 0
+
+	return result, nil
+}
+
+// compareVolume lists the differences between an existing volume and its compose definition
+func compareVolume(existing dockervolume.Volume, volumeConfig types.VolumeConfig) []string {
+	differences := []string{}
+
+	// Docker uses the local driver when none is given
+	wantDriver := volumeConfig.Driver
+	if wantDriver == "" {
+		wantDriver = "local"
+	}
+	if existing.Driver != wantDriver {
+		differences = append(differences, fmt.Sprintf("driver: existing %q, wanted %q", existing.Driver, wantDriver))
+	}
+
+	differences = append(differences, compareStringMaps("driver_opts", existing.Options, volumeConfig.DriverOpts)...)
+	differences = append(differences, compareStringMaps("labels", existing.Labels, volumeConfig.Labels)...)
+
+	return differences
+}
+
+// compareStringMaps checks that every wanted key is present with the same value.
+// For driver options extra keys are a mismatch too, extra labels are ignored because starker adds its own.
+func compareStringMaps(field string, existing, wanted map[string]string) []string {
+	differences := []string{}
+
+	keys := make([]string, 0, len(wanted))
+	for key := range wanted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, ok := existing[key]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s.%s: missing, wanted %q", field, key, wanted[key]))
+			continue
+		}
+		if value != wanted[key] {
+			differences = append(differences, fmt.Sprintf("%s.%s: existing %q, wanted %q", field, key, value, wanted[key]))
+		}
+	}
+
+	if field == "driver_opts" {
+		extra := make([]string, 0)
+		for key := range existing {
+			if _, ok := wanted[key]; !ok {
+				extra = append(extra, key)
+			}
+		}
+		sort.Strings(extra)
+		for _, key := range extra {
+			differences = append(differences, fmt.Sprintf("%s.%s: existing %q, not wanted", field, key, existing[key]))
+		}
+	}
+
+	return differences
+}
+
+// recreateDockerVolume removes the existing volume and creates it again from the compose definition
+func (dh *DockerHandler) recreateDockerVolume(ctx context.Context, volumeConfig types.VolumeConfig) (string, error) {
+	volumeName := dh.NamingGenerator.VolumeName(volumeConfig.Name)
+
+	if err := dh.RemoveDockerVolume(ctx, volumeName); err != nil {
+		return "", err
+	}
+
+	return dh.createDockerVolume(ctx, volumeConfig)
+}
+
+// migrateDockerVolume recreates the volume with the new definition and keeps its data.
+// The data is copied to a temporary volume first, so nothing is lost if the new volume can not be created.
+func (dh *DockerHandler) migrateDockerVolume(ctx context.Context, volumeConfig types.VolumeConfig) (string, error) {
+	volumeName := dh.NamingGenerator.VolumeName(volumeConfig.Name)
+	tempVolumeName := fmt.Sprintf("%s-migrate-%s", volumeName, ksuid.New().String())
+
+	dh.StreamChan.LogStep(fmt.Sprintf("Migrating Docker volume %s to the new definition", volumeName))
+
+	// Make sure the helper image is available
+	if err := dh.PullDockerImage(ctx, volumeMigrationImage); err != nil {
+		return "", err
+	}
+
+	// Copy the data to a temporary volume
+	_, err := dh.Client.VolumeCreate(ctx, dockervolume.CreateOptions{
+		Name:   tempVolumeName,
+		Labels: dh.NamingGenerator.GetVolumeLabels(dh.NamingGenerator.ProjectName(), volumeConfig.Name),
+	})
+	if err != nil {
+		dh.StreamChan.LogError(fmt.Sprintf("Failed to create temporary volume %s: %v", tempVolumeName, err))
+		return "", fmt.Errorf("failed to create temporary volume %s: %w", tempVolumeName, err)
+	}
+	if err := dh.copyVolumeData(ctx, volumeName, tempVolumeName); err != nil {
+		return "", err
+	}
+
+	// Recreate the volume and copy the data back
+	newVolumeName, err := dh.recreateDockerVolume(ctx, volumeConfig)
+	if err != nil {
+		dh.StreamChan.LogError(fmt.Sprintf("Failed to recreate volume %s, data is kept in %s", volumeName, tempVolumeName))
+		return "", err
+	}
+	if err := dh.copyVolumeData(ctx, tempVolumeName, newVolumeName); err != nil {
+		dh.StreamChan.LogError(fmt.Sprintf("Failed to copy data back to %s, data is kept in %s", newVolumeName, tempVolumeName))
+		return "", err
+	}
+
+	// The temporary volume is no longer needed
+	if err := dh.RemoveDockerVolume(ctx, tempVolumeName); err != nil {
+		return "", err
+	}
+
+	dh.StreamChan.LogInfo(fmt.Sprintf("Successfully migrated Docker volume: %s", newVolumeName))
+
+	return newVolumeName, nil
+}
+
+// copyVolumeData copies all files from one volume to another using a short lived helper container
+func (dh *DockerHandler) copyVolumeData(ctx context.Context, fromVolume, toVolume string) error {
+	dh.StreamChan.LogInfo(fmt.Sprintf("Copying data from %s to %s", fromVolume, toVolume))
+
+	resp, err := dh.Client.ContainerCreate(ctx, &container.Config{
+		Image:  volumeMigrationImage,
+		Cmd:    []string{"sh", "-c", "cp -a /from/. /to/"},
+		Labels: dh.NamingGenerator.GetLabels(),
+	}, &container.HostConfig{
+		Mounts: []mount.Mount{
+			{Type: mount.TypeVolume, Source: fromVolume, Target: "/from", ReadOnly: true},
+			{Type: mount.TypeVolume, Source: toVolume, Target: "/to"},
+		},
+	}, nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to create volume copy container: %w", err)
+	}
+	defer dh.Client.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+
+	if err := dh.Client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start volume copy container: %w", err)
+	}
+
+	statusCh, errCh := dh.Client.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to wait for volume copy container: %w", err)
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			return fmt.Errorf("volume copy from %s to %s exited with code %d", fromVolume, toVolume, status.StatusCode)
+		}
+	}
+
+	return nil
+}
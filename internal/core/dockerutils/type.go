@@ -17,4 +17,11 @@ type DockerHandler struct {
 	DB              *pgxpool.Pool
 	ConnectionPool  *connection.ConnectionPool
 	StreamChan      core.StreamChan
+	VolumeStrategy  VolumeStrategy
+	ImageRetention  int // Deployments per service whose images are kept on the server, 0 keeps all images
+
+	// pinnedImages maps the compose services to the image digests pulled for this deployment
+	pinnedImages map[string]string
+	// preparedImages are images already pulled, or only built on the server, that PullDockerImages does not pull again
+	preparedImages map[string]bool
 }
@@ -0,0 +1,72 @@
+package hostkeys
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// Store pins the SSH host keys of pooled server connections in the servers table
+type Store struct {
+	DB *pgxpool.Pool
+}
+
+// NewStore creates a host key store backed by the database
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{DB: db}
+}
+
+// GetHostKey returns the pinned host key of the server behind the connection ID
+func (s *Store) GetHostKey(connectionID string) (string, error) {
+	teamID, serverID, ok := generator.ParseServerConnectionID(connectionID)
+	if !ok {
+		return "", fmt.Errorf("invalid connection ID %s", connectionID)
+	}
+
+	ctx := context.Background()
+	tx, err := repository.StartTransaction(s.DB, ctx)
+	if err != nil {
+		return "", err
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	server, err := repository.GetServerByID(ctx, tx, serverID, teamID)
+	if err != nil {
+		return "", err
+	}
+	if server == nil {
+		return "", fmt.Errorf("server %s not found", serverID)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	if server.HostKey == nil {
+		return "", nil
+	}
+	return *server.HostKey, nil
+}
+
+// SaveHostKey pins the host key of the server unless one was pinned in the meantime
+func (s *Store) SaveHostKey(connectionID, hostKey string) error {
+	teamID, serverID, ok := generator.ParseServerConnectionID(connectionID)
+	if !ok {
+		return fmt.Errorf("invalid connection ID %s", connectionID)
+	}
+
+	ctx := context.Background()
+	tx, err := repository.StartTransaction(s.DB, ctx)
+	if err != nil {
+		return err
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	if err := repository.PinServerHostKey(ctx, tx, serverID, teamID, hostKey); err != nil {
+		return err
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	return nil
+}
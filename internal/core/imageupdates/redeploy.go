@@ -0,0 +1,181 @@
+package imageupdates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// errRedeploySkipped is returned by deploy when the service changed since it was checked, the updates stay pending
+var errRedeploySkipped = errors.New("service is busy, stopped or no longer updated automatically")
+
+// redeploy deploys the service again so it pulls the new digests, and records the outcome as an event
+func (w *Watcher) redeploy(ctx context.Context, service models.Service, dockerClient *client.Client, updates []models.ServiceImageUpdate) {
+	images := make([]string, 0, len(updates))
+	for _, update := range updates {
+		images = append(images, update.ImageName)
+	}
+	metadata := map[string]string{"images": strings.Join(images, ", ")}
+
+	zap.L().Info("Deploying image updates", zap.String("service_id", service.ID), zap.Strings("images", images))
+	deployErr := w.deploy(ctx, &service, dockerClient)
+	if errors.Is(deployErr, errRedeploySkipped) {
+		zap.L().Info("Skipped deploying image updates", zap.String("service_id", service.ID), zap.Error(deployErr))
+		return
+	}
+
+	// Record the outcome, the state is set the same way as for a deploy started by a user
+	tx, err := repository.StartTransaction(w.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for storing the image update", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	var event models.ServiceEvent
+	if deployErr != nil {
+		zap.L().Warn("Failed to deploy image updates", zap.String("service_id", service.ID), zap.Error(deployErr))
+		metadata["error"] = deployErr.Error()
+		event = newEvent(service, models.ServiceEventImageUpdateFailed, fmt.Sprintf("Deploying the image updates of %s failed", service.Name), metadata)
+	} else {
+		event = newEvent(service, models.ServiceEventImageUpdateDeployed, fmt.Sprintf("%s was redeployed with the image updates", service.Name), metadata)
+		if err := repository.DeleteServiceImageUpdates(ctx, tx, service.ID); err != nil {
+			zap.L().Error("Failed to delete deployed image updates", zap.String("service_id", service.ID), zap.Error(err))
+			return
+		}
+	}
+
+	// A deploy that failed before the containers were touched left the service as it was.
+	// Only the state is written, the service may have been edited during the deploy.
+	if deployErr == nil || service.State != models.ServiceStateRunning {
+		if err := repository.UpdateServiceState(ctx, tx, service.ID, service.State, service.LastDeployedAt); err != nil {
+			zap.L().Error("Failed to update service state", zap.String("service_id", service.ID), zap.Error(err))
+			return
+		}
+	}
+	if err := repository.CreateServiceEvent(ctx, tx, event); err != nil {
+		zap.L().Error("Failed to create service event", zap.String("service_id", service.ID), zap.Error(err))
+		return
+	}
+
+	repository.CommitTransaction(tx, ctx)
+}
+
+// deploy starts the compose project of the service again and waits for it to finish.
+// The service is loaded again first, it is skipped unless it still runs with automatic updates and no one else deploys it.
+// On return the service has the state it should be stored with.
+func (w *Watcher) deploy(ctx context.Context, service *models.Service, dockerClient *client.Client) error {
+	tx, err := repository.StartTransaction(w.DB, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	current, err := repository.GetServiceForUpdate(ctx, tx, service.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get service: %w", err)
+	}
+	if current == nil || current.State != models.ServiceStateRunning || !current.AutoUpdate {
+		return errRedeploySkipped
+	}
+	*service = *current
+
+	composeConfig, err := repository.GetServiceComposeConfig(ctx, tx, service.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get service compose config: %w", err)
+	}
+	if composeConfig == nil {
+		return fmt.Errorf("no compose configuration found for service")
+	}
+
+	envFiles, err := repository.GetServiceEnvFiles(ctx, tx, service.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get service env files: %w", err)
+	}
+
+	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
+	project, err := dockerutils.ParseServiceCompose(*composeConfig, envFiles, namingGenerator.ProjectName())
+	if err != nil {
+		return fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if err := dockeryaml.Validate(project); err != nil {
+		return fmt.Errorf("invalid compose configuration: %w", err)
+	}
+
+	dockerHandler := &dockerutils.DockerHandler{
+		Client:          dockerClient,
+		Project:         project,
+		NamingGenerator: namingGenerator,
+		DB:              w.DB,
+		ConnectionPool:  w.ConnectionPool,
+		StreamChan:      core.NewStreamChan(),
+		VolumeStrategy:  dockerutils.VolumeStrategyAbort,
+		ImageRetention:  w.ImageRetention,
+	}
+
+	// Never touch volume data without a user deciding about it
+	results, err := dockerHandler.CheckVolumeCompatibility(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check volume compatibility: %w", err)
+	}
+	if incompatible := dockerutils.IncompatibleVolumes(results); len(incompatible) > 0 {
+		return fmt.Errorf("%w, deploy the service manually", dockerutils.ErrIncompatibleVolumes)
+	}
+
+	// Deploys started by users are refused while the service is restarting
+	service.State = models.ServiceStateRestarting
+	if err := repository.UpdateServiceState(ctx, tx, service.ID, service.State, nil); err != nil {
+		return fmt.Errorf("failed to update service state: %w", err)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	if err := dockerHandler.StartDockerCompose(ctx); err != nil {
+		service.State = models.ServiceStateStopped
+		return fmt.Errorf("failed to start Docker compose: %w", err)
+	}
+	if err := waitForCompletion(ctx, dockerHandler.StreamChan); err != nil {
+		service.State = models.ServiceStateStopped
+		return err
+	}
+
+	service.State = models.ServiceStateRunning
+	service.LastDeployedAt = &[]time.Time{time.Now()}[0]
+	return nil
+}
+
+// waitForCompletion drains the output of a Docker operation until it finished
+func waitForCompletion(ctx context.Context, streamChan core.StreamChan) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case logMsg := <-streamChan.LogChan:
+			zap.L().Debug("Image update deployment", zap.String("message", logMsg.Message))
+		case errMsg := <-streamChan.ErrChan:
+			zap.L().Debug("Image update deployment error", zap.String("message", errMsg.Message))
+		case <-streamChan.ProgressChan:
+		case finalErr := <-streamChan.FinalError:
+			return finalErr
+		case <-streamChan.DoneChan:
+			// A failed operation sends its error before it is done
+			select {
+			case finalErr := <-streamChan.FinalError:
+				return finalErr
+			default:
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package imageupdates
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/hostkeys"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// registryTimeout limits a single registry lookup
+const registryTimeout = 30 * time.Second
+
+// Watcher periodically checks whether the image tags of the running services point to a new digest in their
+// registry, records the available updates and redeploys the services that opted in to automatic updates.
+// The registry is asked through the Docker daemon of the server, so the daemon's registry settings apply.
+type Watcher struct {
+	DB             *pgxpool.Pool
+	ConnectionPool *connection.ConnectionPool
+	Interval       time.Duration
+	ImageRetention int
+}
+
+// NewWatcher creates a watcher with its own connection pool
+func NewWatcher(db *pgxpool.Pool, interval time.Duration, imageRetention int) *Watcher {
+	connectionPool := connection.NewConnectionPool(20*time.Minute, 1*time.Hour)
+	connectionPool.SetHostKeyStore(hostkeys.NewStore(db))
+
+	return &Watcher{
+		DB:             db,
+		ConnectionPool: connectionPool,
+		Interval:       interval,
+		ImageRetention: imageRetention,
+	}
+}
+
+// Start runs the watcher in a goroutine until the context is canceled
+func (w *Watcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				w.ConnectionPool.Close()
+				return
+			case <-ticker.C:
+				w.checkAll(ctx)
+			}
+		}
+	}()
+
+	zap.L().Info("Image update watcher started", zap.Duration("interval", w.Interval))
+}
+
+// checkAll checks the images of every running service
+func (w *Watcher) checkAll(ctx context.Context) {
+	// Get the services in a short transaction, the registry lookups can be slow
+	tx, err := repository.StartTransaction(w.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for image update check", zap.Error(err))
+		return
+	}
+	services, err := repository.GetServicesByState(ctx, tx, models.ServiceStateRunning)
+	repository.CommitTransaction(tx, ctx)
+	if err != nil {
+		zap.L().Error("Failed to get services for image update check", zap.Error(err))
+		return
+	}
+
+	for _, service := range services {
+		if err := w.checkService(ctx, service); err != nil {
+			zap.L().Warn("Failed to check service for image updates", zap.String("service_id", service.ID), zap.Error(err))
+		}
+	}
+}
+
+// checkService compares the digests of the last deployment of a service with its registries
+func (w *Watcher) checkService(ctx context.Context, service models.Service) error {
+	tx, err := repository.StartTransaction(w.DB, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	server, err := repository.GetServerByID(ctx, tx, service.ServerID, service.TeamID)
+	if err != nil {
+		return fmt.Errorf("failed to get server: %w", err)
+	}
+	if server == nil {
+		return fmt.Errorf("server %s not found", service.ServerID)
+	}
+	credentials, err := generator.ServerCredentials(ctx, tx, *server)
+	if err != nil {
+		return fmt.Errorf("failed to resolve server credentials: %w", err)
+	}
+	images, err := repository.GetServiceImages(ctx, tx, service.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get service images: %w", err)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	deployed := lastDeployment(images)
+	if len(deployed) == 0 {
+		return nil
+	}
+
+	connectionID := generator.ServerConnectionID(server.TeamID, server.ID)
+	dockerClient, err := w.ConnectionPool.GetDockerConnection(connectionID, generator.ServerHost(*server), credentials)
+	if err != nil {
+		return fmt.Errorf("failed to get Docker connection: %w", err)
+	}
+
+	// Ask the registries for the digests the tags point to now
+	checkedAt := time.Now()
+	var updates []models.ServiceImageUpdate
+	var upToDate []string
+	for _, serviceImage := range deployed {
+		if serviceImage.RepoDigest == nil {
+			// Without a repository digest the image did not come from a registry
+			continue
+		}
+		_, currentDigest, _ := strings.Cut(*serviceImage.RepoDigest, "@")
+
+		registryCtx, cancel := context.WithTimeout(ctx, registryTimeout)
+		distribution, err := dockerClient.DistributionInspect(registryCtx, serviceImage.ImageName, "")
+		cancel()
+		if err != nil {
+			zap.L().Warn("Failed to look up image in registry", zap.String("service_id", service.ID), zap.String("image", serviceImage.ImageName), zap.Error(err))
+			continue
+		}
+
+		availableDigest := distribution.Descriptor.Digest.String()
+		if availableDigest == currentDigest {
+			upToDate = append(upToDate, serviceImage.ServiceName)
+			continue
+		}
+		updates = append(updates, models.ServiceImageUpdate{
+			ID:              ksuid.New().String(),
+			ServiceID:       service.ID,
+			ServiceName:     serviceImage.ServiceName,
+			ImageName:       serviceImage.ImageName,
+			CurrentDigest:   currentDigest,
+			AvailableDigest: availableDigest,
+			DetectedAt:      checkedAt,
+			CheckedAt:       checkedAt,
+		})
+	}
+
+	if err := w.storeUpdates(ctx, service, updates, upToDate); err != nil {
+		return err
+	}
+
+	if len(updates) > 0 && service.AutoUpdate && InUpdateWindow(service.AutoUpdateWindow, checkedAt) {
+		w.redeploy(ctx, service, dockerClient, updates)
+	}
+
+	return nil
+}
+
+// storeUpdates records the available updates of a service and creates an event for every new digest
+func (w *Watcher) storeUpdates(ctx context.Context, service models.Service, updates []models.ServiceImageUpdate, upToDate []string) error {
+	tx, err := repository.StartTransaction(w.DB, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	for _, serviceName := range upToDate {
+		if err := repository.DeleteServiceImageUpdate(ctx, tx, service.ID, serviceName); err != nil {
+			return fmt.Errorf("failed to delete image update: %w", err)
+		}
+	}
+
+	for i := range updates {
+		update := &updates[i]
+
+		existing, err := repository.GetServiceImageUpdate(ctx, tx, service.ID, update.ServiceName)
+		if err != nil {
+			return fmt.Errorf("failed to get image update: %w", err)
+		}
+		isNew := existing == nil || existing.AvailableDigest != update.AvailableDigest
+		if !isNew {
+			update.ID = existing.ID
+			update.DetectedAt = existing.DetectedAt
+		}
+
+		if err := repository.UpsertServiceImageUpdate(ctx, tx, *update); err != nil {
+			return fmt.Errorf("failed to store image update: %w", err)
+		}
+		if !isNew {
+			continue
+		}
+
+		event := newEvent(service, models.ServiceEventImageUpdateAvailable,
+			fmt.Sprintf("%s of %s has a new digest", update.ImageName, update.ServiceName),
+			map[string]string{
+				"service_name":     update.ServiceName,
+				"image":            update.ImageName,
+				"current_digest":   update.CurrentDigest,
+				"available_digest": update.AvailableDigest,
+			},
+		)
+		if err := repository.CreateServiceEvent(ctx, tx, event); err != nil {
+			return fmt.Errorf("failed to create service event: %w", err)
+		}
+	}
+
+	repository.CommitTransaction(tx, ctx)
+	return nil
+}
+
+// lastDeployment returns the image records of the newest deployment, the records are newest first
+func lastDeployment(images []models.ServiceImage) []models.ServiceImage {
+	if len(images) == 0 {
+		return nil
+	}
+
+	var deployed []models.ServiceImage
+	for _, serviceImage := range images {
+		if serviceImage.DeploymentID == images[0].DeploymentID {
+			deployed = append(deployed, serviceImage)
+		}
+	}
+	return deployed
+}
+
+// newEvent creates a service event that happened now
+func newEvent(service models.Service, eventType models.ServiceEventType, message string, metadata map[string]string) models.ServiceEvent {
+	return models.ServiceEvent{
+		ID:        ksuid.New().String(),
+		TeamID:    service.TeamID,
+		ServiceID: service.ID,
+		Type:      eventType,
+		Message:   message,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,55 @@
+package imageupdates
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidUpdateWindow is returned for update windows that are not in the HH:MM-HH:MM format
+var ErrInvalidUpdateWindow = errors.New("update window must be in the HH:MM-HH:MM format")
+
+// ParseUpdateWindow parses an update window like "02:00-05:00" into its start and end minute of the day.
+// The end may be before the start for windows that span midnight.
+func ParseUpdateWindow(window string) (start, end int, err error) {
+	startStr, endStr, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, ErrInvalidUpdateWindow
+	}
+
+	startTime, err := time.Parse("15:04", strings.TrimSpace(startStr))
+	if err != nil {
+		return 0, 0, ErrInvalidUpdateWindow
+	}
+	endTime, err := time.Parse("15:04", strings.TrimSpace(endStr))
+	if err != nil {
+		return 0, 0, ErrInvalidUpdateWindow
+	}
+
+	start = startTime.Hour()*60 + startTime.Minute()
+	end = endTime.Hour()*60 + endTime.Minute()
+	if start == end {
+		return 0, 0, ErrInvalidUpdateWindow
+	}
+
+	return start, end, nil
+}
+
+// InUpdateWindow reports whether the time falls into the UTC update window, a missing window allows any time
+func InUpdateWindow(window *string, now time.Time) bool {
+	if window == nil || *window == "" {
+		return true
+	}
+
+	start, end, err := ParseUpdateWindow(*window)
+	if err != nil {
+		return false
+	}
+
+	now = now.UTC()
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
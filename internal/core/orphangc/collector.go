@@ -0,0 +1,207 @@
+package orphangc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/hostkeys"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// Collector periodically finds Docker resources of deleted services on every server,
+// records them and removes them once they stayed orphaned for the grace period.
+// Volumes hold data, they are only removed after their removal was approved.
+type Collector struct {
+	DB             *pgxpool.Pool
+	ConnectionPool *connection.ConnectionPool
+	Interval       time.Duration
+	GracePeriod    time.Duration
+}
+
+// NewCollector creates a collector with its own connection pool
+func NewCollector(db *pgxpool.Pool, interval, gracePeriod time.Duration) *Collector {
+	connectionPool := connection.NewConnectionPool(20*time.Minute, 1*time.Hour)
+	connectionPool.SetHostKeyStore(hostkeys.NewStore(db))
+
+	return &Collector{
+		DB:             db,
+		ConnectionPool: connectionPool,
+		Interval:       interval,
+		GracePeriod:    gracePeriod,
+	}
+}
+
+// Start runs the collector in a goroutine until the context is canceled
+func (c *Collector) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.ConnectionPool.Close()
+				return
+			case <-ticker.C:
+				c.collect(ctx)
+			}
+		}
+	}()
+
+	zap.L().Info("Orphaned resource collector started", zap.Duration("interval", c.Interval), zap.Duration("grace_period", c.GracePeriod))
+}
+
+// collect runs the garbage collection on every server
+func (c *Collector) collect(ctx context.Context) {
+	// Get the servers in a short transaction, the Docker calls can be slow
+	tx, err := repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for orphan collection", zap.Error(err))
+		return
+	}
+	servers, err := repository.GetAllServers(ctx, tx)
+	repository.CommitTransaction(tx, ctx)
+	if err != nil {
+		zap.L().Error("Failed to get servers for orphan collection", zap.Error(err))
+		return
+	}
+
+	for _, server := range servers {
+		if err := c.collectServer(ctx, server); err != nil {
+			zap.L().Warn("Failed to collect orphaned resources", zap.String("server_id", server.ID), zap.Error(err))
+		}
+	}
+}
+
+// collectServer records the orphaned resources of a single server and removes the ones that are due
+func (c *Collector) collectServer(ctx context.Context, server models.Server) error {
+	// Get the credentials and jump hosts for the connection
+	tx, err := repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	credentials, err := generator.ServerCredentials(ctx, tx, server)
+	if err != nil {
+		return fmt.Errorf("failed to resolve server credentials: %w", err)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	connectionID := generator.ServerConnectionID(server.TeamID, server.ID)
+	dockerClient, err := c.ConnectionPool.GetDockerConnection(connectionID, generator.ServerHost(server), credentials)
+	if err != nil {
+		return fmt.Errorf("failed to get Docker connection: %w", err)
+	}
+
+	seenAt := time.Now()
+	orphans, err := c.findOrphans(ctx, dockerClient, server)
+	if err != nil {
+		return err
+	}
+
+	// Record the orphans, the records of resources that are no longer orphaned are dropped
+	tx, err = repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	for _, orphan := range orphans {
+		orphan.ID = ksuid.New().String()
+		orphan.FirstSeenAt = seenAt
+		orphan.LastSeenAt = seenAt
+		if err := repository.UpsertOrphanedResource(ctx, tx, orphan); err != nil {
+			return fmt.Errorf("failed to record orphaned resource %s: %w", orphan.Name, err)
+		}
+	}
+	if err := repository.DeleteOrphanedResourcesSeenBefore(ctx, tx, server.ID, seenAt); err != nil {
+		return fmt.Errorf("failed to delete stale orphaned resources: %w", err)
+	}
+	recorded, err := repository.GetOrphanedResources(ctx, tx, server.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get orphaned resources: %w", err)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	return c.removeDue(ctx, dockerClient, recorded)
+}
+
+// removeDue removes the orphaned resources whose grace period is over and stores the outcome
+func (c *Collector) removeDue(ctx context.Context, dockerClient *client.Client, orphans []models.OrphanedResource) error {
+	// Containers go first so that networks and volumes are no longer in use
+	slices.SortStableFunc(orphans, func(a, b models.OrphanedResource) int {
+		return removalOrder(a.ResourceType) - removalOrder(b.ResourceType)
+	})
+
+	dueBefore := time.Now().Add(-c.GracePeriod)
+	removed := []string{}
+	failed := map[string]string{}
+	for _, orphan := range orphans {
+		if orphan.FirstSeenAt.After(dueBefore) {
+			continue
+		}
+		if orphan.ResourceType == models.OrphanedResourceVolume && orphan.ApprovedAt == nil {
+			continue
+		}
+
+		if err := removeResource(ctx, dockerClient, orphan); err != nil {
+			zap.L().Warn("Failed to remove orphaned resource", zap.String("server_id", orphan.ServerID), zap.String("name", orphan.Name), zap.Error(err))
+			failed[orphan.ID] = err.Error()
+			continue
+		}
+		zap.L().Info("Removed orphaned resource",
+			zap.String("server_id", orphan.ServerID),
+			zap.String("service_id", orphan.ServiceID),
+			zap.String("type", string(orphan.ResourceType)),
+			zap.String("name", orphan.Name),
+		)
+		removed = append(removed, orphan.ID)
+	}
+	if len(removed) == 0 && len(failed) == 0 {
+		return nil
+	}
+
+	// Store the outcome of the removals
+	tx, err := repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	for _, orphanID := range removed {
+		if err := repository.DeleteOrphanedResource(ctx, tx, orphanID); err != nil {
+			return fmt.Errorf("failed to delete orphaned resource record: %w", err)
+		}
+	}
+	for orphanID, removalError := range failed {
+		if err := repository.UpdateOrphanedResourceRemovalError(ctx, tx, orphanID, &removalError); err != nil {
+			return fmt.Errorf("failed to store removal error: %w", err)
+		}
+	}
+
+	repository.CommitTransaction(tx, ctx)
+	return nil
+}
+
+// removalOrder sorts containers before networks before volumes
+func removalOrder(resourceType models.OrphanedResourceType) int {
+	switch resourceType {
+	case models.OrphanedResourceContainer:
+		return 0
+	case models.OrphanedResourceNetwork:
+		return 1
+	default:
+		return 2
+	}
+}
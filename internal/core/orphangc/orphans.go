@@ -0,0 +1,172 @@
+package orphangc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// findOrphans returns the containers, networks and volumes on the server whose service no longer exists
+func (c *Collector) findOrphans(ctx context.Context, dockerClient *client.Client, server models.Server) ([]models.OrphanedResource, error) {
+	serviceIDs, err := labelledServiceIDs(ctx, dockerClient, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(serviceIDs) == 0 {
+		return nil, nil
+	}
+
+	tx, err := repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	existing, err := repository.GetExistingServiceIDs(ctx, tx, serviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing services: %w", err)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	var orphans []models.OrphanedResource
+	for _, serviceID := range serviceIDs {
+		if existing[serviceID] {
+			continue
+		}
+		serviceOrphans, err := serviceResources(ctx, dockerClient, server, serviceID)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, serviceOrphans...)
+	}
+
+	return orphans, nil
+}
+
+// labelledServiceIDs returns the IDs of all services with resources created on the server
+func labelledServiceIDs(ctx context.Context, dockerClient *client.Client, serverID string) ([]string, error) {
+	serverFilters := filters.NewArgs(
+		filters.Arg("label", generator.ServiceIDLabel),
+		filters.Arg("label", fmt.Sprintf("%s=%s", generator.ServerIDLabel, serverID)),
+	)
+
+	var serviceIDs []string
+	seen := make(map[string]bool)
+	add := func(labels map[string]string) {
+		if serviceID := labels[generator.ServiceIDLabel]; serviceID != "" && !seen[serviceID] {
+			seen[serviceID] = true
+			serviceIDs = append(serviceIDs, serviceID)
+		}
+	}
+
+	containers, err := dockerClient.ContainerList(ctx, container.ListOptions{All: true, Filters: serverFilters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, c := range containers {
+		add(c.Labels)
+	}
+
+	networks, err := dockerClient.NetworkList(ctx, network.ListOptions{Filters: serverFilters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, n := range networks {
+		add(n.Labels)
+	}
+
+	volumes, err := dockerClient.VolumeList(ctx, volume.ListOptions{Filters: serverFilters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		add(v.Labels)
+	}
+
+	return serviceIDs, nil
+}
+
+// serviceResources lists the resources of a deleted service with the same filters the deploy and purge use
+func serviceResources(ctx context.Context, dockerClient *client.Client, server models.Server, serviceID string) ([]models.OrphanedResource, error) {
+	namingGenerator := generator.NewNamingGenerator(serviceID, server.TeamID, server.ID)
+	filterBuilder := generator.NewFilterBuilder(namingGenerator)
+	projectName := namingGenerator.ProjectName()
+
+	orphan := func(resourceType models.OrphanedResourceType, resourceID, name string) models.OrphanedResource {
+		return models.OrphanedResource{
+			ServerID:     server.ID,
+			ServiceID:    serviceID,
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			Name:         name,
+		}
+	}
+
+	var orphans []models.OrphanedResource
+
+	containers, err := dockerClient.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filterBuilder.ProjectFilters(projectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers of service %s: %w", serviceID, err)
+	}
+	for _, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		orphans = append(orphans, orphan(models.OrphanedResourceContainer, c.ID, name))
+	}
+
+	networks, err := dockerClient.NetworkList(ctx, network.ListOptions{
+		Filters: filterBuilder.NetworkFilters(projectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks of service %s: %w", serviceID, err)
+	}
+	for _, n := range networks {
+		orphans = append(orphans, orphan(models.OrphanedResourceNetwork, n.ID, n.Name))
+	}
+
+	volumes, err := dockerClient.VolumeList(ctx, volume.ListOptions{
+		Filters: filterBuilder.VolumeFilters(projectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes of service %s: %w", serviceID, err)
+	}
+	for _, v := range volumes.Volumes {
+		orphans = append(orphans, orphan(models.OrphanedResourceVolume, v.Name, v.Name))
+	}
+
+	return orphans, nil
+}
+
+// removeResource removes an orphaned resource from the server, a resource that is already gone counts as removed
+func removeResource(ctx context.Context, dockerClient *client.Client, orphan models.OrphanedResource) error {
+	var err error
+	switch orphan.ResourceType {
+	case models.OrphanedResourceContainer:
+		err = dockerClient.ContainerRemove(ctx, orphan.ResourceID, container.RemoveOptions{Force: true})
+	case models.OrphanedResourceNetwork:
+		err = dockerClient.NetworkRemove(ctx, orphan.ResourceID)
+	case models.OrphanedResourceVolume:
+		err = dockerClient.VolumeRemove(ctx, orphan.ResourceID, false)
+	default:
+		return fmt.Errorf("unknown resource type %s", orphan.ResourceType)
+	}
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package serverbootstrap
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/pkg/connection"
+)
+
+const (
+	// commandTimeout limits the quick detection and configuration commands
+	commandTimeout = 1 * time.Minute
+	// installTimeout limits the Docker installation, package downloads can be slow
+	installTimeout = 15 * time.Minute
+
+	// daemonConfig enables log rotation for the default json-file log driver
+	daemonConfig = `{"log-driver":"json-file","log-opts":{"max-size":"10m","max-file":"3"}}`
+)
+
+// supportedDistros are the distributions supported by the Docker install script
+var supportedDistros = []string{"ubuntu", "debian", "raspbian", "centos", "rhel", "fedora"}
+
+// supportedArchs are the architectures Docker Engine is published for
+var supportedArchs = []string{"x86_64", "aarch64", "armv7l", "s390x", "ppc64le"}
+
+// Bootstrapper installs and configures Docker Engine on a server over SSH
+type Bootstrapper struct {
+	ConnectionPool *connection.ConnectionPool
+	ConnectionID   string
+	Host           string // SSH host in user@ip:port format
+	User           string // SSH user that is added to the docker group
+	Credentials    connection.Credentials
+	StreamChan     core.StreamChan
+}
+
+// Distro describes the operating system of the server
+type Distro struct {
+	ID        string
+	VersionID string
+	IDLike    string
+	Arch      string
+}
+
+// Bootstrap runs the bootstrap in a goroutine with streaming output.
+// Cancelling the context stops the running command and the remaining steps.
+func (b *Bootstrapper) Bootstrap(ctx context.Context) {
+	go func() {
+		dockerVersion, err := b.run(ctx)
+		if err != nil {
+			zap.L().Error("Server bootstrap failed", zap.String("connection_id", b.ConnectionID), zap.Error(err))
+			select {
+			case b.StreamChan.FinalError <- err:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		b.logInfo(ctx, fmt.Sprintf("Docker %s is installed and ready", dockerVersion))
+		select {
+		case b.StreamChan.DoneChan <- true:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// run executes all bootstrap steps and returns the installed Docker version
+func (b *Bootstrapper) run(ctx context.Context) (string, error) {
+	// +-------------------------------------------+
+	// |Detect Distro                              |
+	// +-------------------------------------------+
+	b.logStep(ctx, "Detecting operating system")
+	distro, err := b.detectDistro(ctx)
+	if err != nil {
+		return "", err
+	}
+	b.logInfo(ctx, fmt.Sprintf("Detected %s %s (%s)", distro.ID, distro.VersionID, distro.Arch))
+
+	if !slices.Contains(supportedArchs, distro.Arch) {
+		return "", fmt.Errorf("unsupported architecture %s", distro.Arch)
+	}
+	if !slices.Contains(supportedDistros, distro.ID) {
+		return "", fmt.Errorf("unsupported distribution %s, supported are %s", distro.ID, strings.Join(supportedDistros, ", "))
+	}
+
+	// Everything below needs root, non-root users need passwordless sudo
+	sudo := ""
+	if b.User != "root" {
+		sudo = "sudo -n "
+		if _, err := b.runCommand(ctx, "sudo -n true", commandTimeout); err != nil {
+			return "", fmt.Errorf("user %s needs passwordless sudo to bootstrap the server: %w", b.User, err)
+		}
+	}
+
+	// +-------------------------------------------+
+	// |Install Docker                             |
+	// +-------------------------------------------+
+	if _, err := b.runCommand(ctx, "command -v docker", commandTimeout); err == nil {
+		b.logInfo(ctx, "Docker is already installed, skipping installation")
+	} else {
+		b.logStep(ctx, "Installing Docker Engine")
+		// The script is run as root, so it is downloaded to a directory only this user can write to
+		install := `dir=$(mktemp -d) && { (curl -fsSL https://get.docker.com -o "$dir/get-docker.sh" || wget -qO "$dir/get-docker.sh" https://get.docker.com) && ` +
+			sudo + `sh "$dir/get-docker.sh"; status=$?; rm -rf "$dir"; [ $status -eq 0 ]; }`
+		if _, err := b.streamCommand(ctx, install, installTimeout); err != nil {
+			return "", fmt.Errorf("failed to install Docker: %w", err)
+		}
+	}
+
+	// socat bridges the Docker socket over SSH
+	if _, err := b.runCommand(ctx, "command -v socat", commandTimeout); err != nil {
+		b.logStep(ctx, "Installing socat")
+		if _, err := b.streamCommand(ctx, sudo+installPackageCommand(distro, "socat"), installTimeout); err != nil {
+			return "", fmt.Errorf("failed to install socat: %w", err)
+		}
+	}
+
+	// +-------------------------------------------+
+	// |Configure Docker                           |
+	// +-------------------------------------------+
+	b.logStep(ctx, "Configuring Docker log rotation")
+	output, err := b.runCommand(ctx, fmt.Sprintf(
+		"if [ -f /etc/docker/daemon.json ]; then echo exists; else %smkdir -p /etc/docker && echo %s | %stee /etc/docker/daemon.json > /dev/null && echo created; fi",
+		sudo, shellQuote(daemonConfig), sudo,
+	), commandTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure log rotation: %w", err)
+	}
+	if slices.Contains(output, "exists") {
+		b.logInfo(ctx, "/etc/docker/daemon.json already exists, leaving it untouched")
+	}
+
+	// Only restart Docker when the config changed, a restart stops the running containers
+	systemctl := sudo + "systemctl enable --now docker"
+	if slices.Contains(output, "created") {
+		systemctl += " && " + sudo + "systemctl restart docker"
+	}
+
+	b.logStep(ctx, "Enabling and starting the Docker service")
+	if _, err := b.runCommand(ctx, systemctl, commandTimeout); err != nil {
+		b.logInfo(ctx, fmt.Sprintf("Could not manage Docker with systemctl, make sure it starts on boot: %v", err))
+	}
+
+	if b.User != "root" {
+		b.logStep(ctx, fmt.Sprintf("Adding %s to the docker group", b.User))
+		if _, err := b.runCommand(ctx, sudo+"usermod -aG docker "+shellQuote(b.User), commandTimeout); err != nil {
+			return "", fmt.Errorf("failed to add %s to the docker group: %w", b.User, err)
+		}
+	}
+
+	// +-------------------------------------------+
+	// |Verify Docker                              |
+	// +-------------------------------------------+
+	// Group membership only applies to new SSH sessions, so drop the pooled connection
+	b.ConnectionPool.RemoveConnection(b.ConnectionID)
+
+	b.logStep(ctx, "Verifying the Docker installation")
+	dockerClient, err := b.ConnectionPool.GetDockerConnection(b.ConnectionID, b.Host, b.Credentials)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to Docker: %w", err)
+	}
+	version, err := dockerClient.ServerVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Docker version: %w", err)
+	}
+
+	return version.Version, nil
+}
+
+// detectDistro reads /etc/os-release and the machine architecture
+func (b *Bootstrapper) detectDistro(ctx context.Context) (*Distro, error) {
+	output, err := b.runCommand(ctx, `. /etc/os-release && echo "$ID|$VERSION_ID|$ID_LIKE" && uname -m`, commandTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect operating system: %w", err)
+	}
+	if len(output) < 2 {
+		return nil, fmt.Errorf("unexpected operating system detection output: %q", strings.Join(output, "\n"))
+	}
+
+	fields := strings.SplitN(output[0], "|", 3)
+	for len(fields) < 3 {
+		fields = append(fields, "")
+	}
+
+	return &Distro{
+		ID:        strings.ToLower(fields[0]),
+		VersionID: fields[1],
+		IDLike:    fields[2],
+		Arch:      strings.TrimSpace(output[1]),
+	}, nil
+}
+
+// runCommand runs a command and returns its stdout lines without streaming them
+func (b *Bootstrapper) runCommand(ctx context.Context, command string, timeout time.Duration) ([]string, error) {
+	return b.execute(ctx, command, timeout, false)
+}
+
+// streamCommand runs a command and streams its output as log messages
+func (b *Bootstrapper) streamCommand(ctx context.Context, command string, timeout time.Duration) ([]string, error) {
+	return b.execute(ctx, command, timeout, true)
+}
+
+// execute runs a command over the pooled SSH connection and waits for it to finish
+func (b *Bootstrapper) execute(ctx context.Context, command string, timeout time.Duration, stream bool) ([]string, error) {
+	result, err := b.ConnectionPool.ExecuteSSHCommand(ctx, b.ConnectionID, b.Host, b.Credentials, command, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []string
+	stdoutChan, stderrChan, errorChan := result.StdoutChan, result.StderrChan, result.ErrorChan
+	for stdoutChan != nil || stderrChan != nil || errorChan != nil {
+		select {
+		case line, ok := <-stdoutChan:
+			if !ok {
+				stdoutChan = nil
+				continue
+			}
+			output = append(output, line)
+			if stream {
+				b.logInfo(ctx, line)
+			}
+		case line, ok := <-stderrChan:
+			if !ok {
+				stderrChan = nil
+				continue
+			}
+			if stream {
+				b.logInfo(ctx, line)
+			}
+		case _, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+			}
+		}
+	}
+	<-result.DoneChan
+
+	if err := result.GetFinalError(); err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+// installPackageCommand returns the package manager command to install a package on the distro
+func installPackageCommand(distro *Distro, pkg string) string {
+	switch distro.ID {
+	case "ubuntu", "debian", "raspbian":
+		return "sh -c 'apt-get update -q && DEBIAN_FRONTEND=noninteractive apt-get install -y -q " + pkg + "'"
+	case "fedora":
+		return "dnf install -y " + pkg
+	default:
+		return "sh -c 'command -v dnf > /dev/null && dnf install -y " + pkg + " || yum install -y " + pkg + "'"
+	}
+}
+
+// shellQuote quotes a string for use in a POSIX shell command
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// logInfo sends an info message unless the client is gone
+func (b *Bootstrapper) logInfo(ctx context.Context, message string) {
+	b.send(ctx, core.LogInfo(message))
+}
+
+// logStep sends a step message unless the client is gone
+func (b *Bootstrapper) logStep(ctx context.Context, message string) {
+	b.send(ctx, core.LogStep(message))
+}
+
+// send forwards a log message without blocking after the client disconnected
+func (b *Bootstrapper) send(ctx context.Context, message core.LogMessage) {
+	select {
+	case b.StreamChan.LogChan <- message:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,195 @@
+package serverdocker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// Container is a container on the server
+type Container struct {
+	ID        string    `json:"id" example:"abc123def456"`                            // Docker container ID
+	Name      string    `json:"name" example:"web-2gFq1xRzGv0ZsVqB4dJgoYJhP1K"`       // Container name
+	Image     string    `json:"image" example:"nginx:latest"`                         // Image the container was created from
+	ImageID   string    `json:"image_id" example:"sha256:abc123..."`                  // ID of the image
+	State     string    `json:"state" example:"running"`                              // Container state
+	Status    string    `json:"status" example:"Up 2 hours"`                          // Human readable status
+	ServiceID *string   `json:"service_id,omitempty" example:"2gFq1xRzGv0ZsVqB4dJgo"` // Starker service the container belongs to
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`            // Creation time
+}
+
+// Image is an image on the server
+type Image struct {
+	ID         string    `json:"id" example:"sha256:abc123..."`                     // Docker image ID
+	RepoTags   []string  `json:"repo_tags" example:"nginx:latest"`                  // Tags of the image
+	Size       int64     `json:"size" example:"187654321"`                          // Size in bytes
+	Dangling   bool      `json:"dangling" example:"false"`                          // Whether the image has no tags
+	Containers int       `json:"containers" example:"1"`                            // Number of containers using the image
+	ServiceIDs []string  `json:"service_ids" example:"2gFq1xRzGv0ZsVqB4dJgoYJhP1K"` // Starker services with containers using the image
+	CreatedAt  time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`         // Creation time
+}
+
+// Volume is a volume on the server
+type Volume struct {
+	Name       string  `json:"name" example:"data-2gFq1xRzGv0ZsVqB4dJgoYJhP1K"`            // Volume name
+	Driver     string  `json:"driver" example:"local"`                                     // Volume driver
+	Mountpoint string  `json:"mountpoint" example:"/var/lib/docker/volumes/data/_data"`    // Location on the host
+	ServiceID  *string `json:"service_id,omitempty" example:"2gFq1xRzGv0ZsVqB4dJgoYJhP1K"` // Starker service the volume belongs to
+	CreatedAt  string  `json:"created_at,omitempty" example:"2023-01-01T12:00:00Z"`        // Creation time as reported by Docker
+}
+
+// Network is a network on the server
+type Network struct {
+	ID        string    `json:"id" example:"abc123def456"`                                  // Docker network ID
+	Name      string    `json:"name" example:"default-2gFq1xRzGv0ZsVqB4dJgoYJhP1K"`         // Network name
+	Driver    string    `json:"driver" example:"bridge"`                                    // Network driver
+	Scope     string    `json:"scope" example:"local"`                                      // Network scope
+	Internal  bool      `json:"internal" example:"false"`                                   // Whether the network has no external access
+	ServiceID *string   `json:"service_id,omitempty" example:"2gFq1xRzGv0ZsVqB4dJgoYJhP1K"` // Starker service the network belongs to
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`                  // Creation time
+}
+
+// ListContainers lists all containers on the server, including stopped ones
+func ListContainers(ctx context.Context, dockerClient *client.Client) ([]Container, error) {
+	summaries, err := dockerClient.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	containers := make([]Container, 0, len(summaries))
+	for _, summary := range summaries {
+		containers = append(containers, Container{
+			ID:        summary.ID,
+			Name:      containerName(summary),
+			Image:     summary.Image,
+			ImageID:   summary.ImageID,
+			State:     summary.State,
+			Status:    summary.Status,
+			ServiceID: serviceID(summary.Labels),
+			CreatedAt: time.Unix(summary.Created, 0),
+		})
+	}
+
+	return containers, nil
+}
+
+// ListImages lists all images on the server with the Starker services whose containers use them
+func ListImages(ctx context.Context, dockerClient *client.Client) ([]Image, error) {
+	summaries, err := dockerClient.ImageList(ctx, image.ListOptions{All: false})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	// Images carry no Starker labels, their services are found through the containers
+	containers, err := ListContainers(ctx, dockerClient)
+	if err != nil {
+		return nil, err
+	}
+	containerCounts := make(map[string]int)
+	imageServices := make(map[string][]string)
+	for _, c := range containers {
+		containerCounts[c.ImageID]++
+		if c.ServiceID != nil && !slices.Contains(imageServices[c.ImageID], *c.ServiceID) {
+			imageServices[c.ImageID] = append(imageServices[c.ImageID], *c.ServiceID)
+		}
+	}
+
+	images := make([]Image, 0, len(summaries))
+	for _, summary := range summaries {
+		serviceIDs := imageServices[summary.ID]
+		if serviceIDs == nil {
+			serviceIDs = []string{}
+		}
+		images = append(images, Image{
+			ID:         summary.ID,
+			RepoTags:   summary.RepoTags,
+			Size:       summary.Size,
+			Dangling:   isDangling(summary),
+			Containers: containerCounts[summary.ID],
+			ServiceIDs: serviceIDs,
+			CreatedAt:  time.Unix(summary.Created, 0),
+		})
+	}
+
+	return images, nil
+}
+
+// ListVolumes lists all volumes on the server
+func ListVolumes(ctx context.Context, dockerClient *client.Client) ([]Volume, error) {
+	response, err := dockerClient.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	volumes := make([]Volume, 0, len(response.Volumes))
+	for _, v := range response.Volumes {
+		volumes = append(volumes, Volume{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			Mountpoint: v.Mountpoint,
+			ServiceID:  serviceID(v.Labels),
+			CreatedAt:  v.CreatedAt,
+		})
+	}
+
+	return volumes, nil
+}
+
+// ListNetworks lists all networks on the server
+func ListNetworks(ctx context.Context, dockerClient *client.Client) ([]Network, error) {
+	summaries, err := dockerClient.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	networks := make([]Network, 0, len(summaries))
+	for _, summary := range summaries {
+		networks = append(networks, Network{
+			ID:        summary.ID,
+			Name:      summary.Name,
+			Driver:    summary.Driver,
+			Scope:     summary.Scope,
+			Internal:  summary.Internal,
+			ServiceID: serviceID(summary.Labels),
+			CreatedAt: summary.Created,
+		})
+	}
+
+	return networks, nil
+}
+
+// serviceID returns the Starker service of a resource from its labels
+func serviceID(labels map[string]string) *string {
+	if id, ok := labels[generator.ServiceIDLabel]; ok && id != "" {
+		return &id
+	}
+	return nil
+}
+
+// containerName returns the name of a container without the leading slash
+func containerName(summary container.Summary) string {
+	if len(summary.Names) == 0 {
+		return summary.ID
+	}
+	return strings.TrimPrefix(summary.Names[0], "/")
+}
+
+// isDangling reports whether an image has no tags left
+func isDangling(summary image.Summary) bool {
+	for _, tag := range summary.RepoTags {
+		if tag != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package serverdocker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// predefinedNetworks are created by Docker itself and can never be removed
+var predefinedNetworks = map[string]bool{
+	"bridge":          true,
+	"host":            true,
+	"none":            true,
+	"docker_gwbridge": true,
+	"ingress":         true,
+}
+
+// PruneResource is a Docker resource that is removed by a prune
+type PruneResource struct {
+	ID    string  `json:"id" example:"abc123def456"`                     // Docker resource ID
+	Name  string  `json:"name" example:"old-worker"`                     // Resource name, the ID for untagged images
+	Size  int64   `json:"size,omitempty" example:"187654321"`            // Size in bytes (images only)
+	Error *string `json:"error,omitempty" example:"image is being used"` // Why the resource could not be removed
+}
+
+// PruneResult lists the resources a prune removed, or would remove in a dry run
+type PruneResult struct {
+	DryRun         bool            `json:"dry_run" example:"true"`              // Whether nothing was removed
+	Containers     []PruneResource `json:"containers"`                          // Stopped containers that do not belong to Starker
+	Images         []PruneResource `json:"images"`                              // Dangling images
+	Networks       []PruneResource `json:"networks"`                            // Unused networks that do not belong to Starker
+	SpaceReclaimed int64           `json:"space_reclaimed" example:"187654321"` // Bytes freed by the removed images
+}
+
+// Prune removes the stopped containers and unused networks that Starker did not create and all dangling images.
+// Resources of Starker services are never touched, stopped services keep their containers and networks.
+// With dryRun the resources are only listed. Failures of single resources are reported on the resource.
+func Prune(ctx context.Context, dockerClient *client.Client, dryRun bool) (*PruneResult, error) {
+	result := &PruneResult{
+		DryRun:     dryRun,
+		Containers: []PruneResource{},
+		Images:     []PruneResource{},
+		Networks:   []PruneResource{},
+	}
+
+	// Containers go first so the images and networks they used become unused
+	containerFilters := filters.NewArgs(
+		filters.Arg("status", "created"),
+		filters.Arg("status", "exited"),
+		filters.Arg("status", "dead"),
+	)
+	containers, err := dockerClient.ContainerList(ctx, container.ListOptions{All: true, Filters: containerFilters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stopped containers: %w", err)
+	}
+	for _, summary := range containers {
+		if serviceID(summary.Labels) != nil {
+			continue
+		}
+		resource := PruneResource{ID: summary.ID, Name: containerName(summary)}
+		if !dryRun {
+			if err := dockerClient.ContainerRemove(ctx, summary.ID, container.RemoveOptions{}); err != nil {
+				resource.Error = &[]string{err.Error()}[0]
+			}
+		}
+		result.Containers = append(result.Containers, resource)
+	}
+
+	images, err := dockerClient.ImageList(ctx, image.ListOptions{Filters: filters.NewArgs(filters.Arg("dangling", "true"))})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dangling images: %w", err)
+	}
+	for _, summary := range images {
+		resource := PruneResource{ID: summary.ID, Name: summary.ID, Size: summary.Size}
+		if !dryRun {
+			if _, err := dockerClient.ImageRemove(ctx, summary.ID, image.RemoveOptions{PruneChildren: true}); err != nil {
+				resource.Error = &[]string{err.Error()}[0]
+			}
+		}
+		if resource.Error == nil {
+			result.SpaceReclaimed += summary.Size
+		}
+		result.Images = append(result.Images, resource)
+	}
+
+	networks, err := dockerClient.NetworkList(ctx, network.ListOptions{Filters: filters.NewArgs(filters.Arg("dangling", "true"))})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unused networks: %w", err)
+	}
+	for _, summary := range networks {
+		if predefinedNetworks[summary.Name] || serviceID(summary.Labels) != nil {
+			continue
+		}
+		resource := PruneResource{ID: summary.ID, Name: summary.Name}
+		if !dryRun {
+			if err := dockerClient.NetworkRemove(ctx, summary.ID); err != nil {
+				resource.Error = &[]string{err.Error()}[0]
+			}
+		}
+		result.Networks = append(result.Networks, resource)
+	}
+
+	return result, nil
+}
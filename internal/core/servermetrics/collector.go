@@ -0,0 +1,245 @@
+package servermetrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/hostkeys"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+const (
+	// retention is how long host metrics samples are kept
+	retention = 7 * 24 * time.Hour
+	// commandTimeout limits a single metrics command on a slow host
+	commandTimeout = 15 * time.Second
+)
+
+// Collector periodically gathers host metrics of all servers over SSH
+type Collector struct {
+	DB             *pgxpool.Pool
+	ConnectionPool *connection.ConnectionPool
+	Interval       time.Duration
+}
+
+// NewCollector creates a collector with its own connection pool
+func NewCollector(db *pgxpool.Pool, interval time.Duration) *Collector {
+	connectionPool := connection.NewConnectionPool(20*time.Minute, 1*time.Hour)
+	connectionPool.SetHostKeyStore(hostkeys.NewStore(db))
+
+	return &Collector{
+		DB:             db,
+		ConnectionPool: connectionPool,
+		Interval:       interval,
+	}
+}
+
+// Start runs the collector in a goroutine until the context is canceled
+func (c *Collector) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.ConnectionPool.Close()
+				return
+			case <-ticker.C:
+				c.collect(ctx)
+			}
+		}
+	}()
+
+	zap.L().Info("Server metrics collector started", zap.Duration("interval", c.Interval))
+}
+
+// collect stores one sample of every server and removes expired samples
+func (c *Collector) collect(ctx context.Context) {
+	// Get the servers in a short transaction, the SSH calls can be slow
+	tx, err := repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for metrics collection", zap.Error(err))
+		return
+	}
+	servers, err := repository.GetAllServers(ctx, tx)
+	repository.CommitTransaction(tx, ctx)
+	if err != nil {
+		zap.L().Error("Failed to get servers for metrics collection", zap.Error(err))
+		return
+	}
+
+	sampledAt := time.Now().Truncate(time.Second)
+
+	var samples []models.ServerMetrics
+	for _, server := range servers {
+		metrics, err := c.collectServer(ctx, server)
+		if err != nil {
+			zap.L().Warn("Failed to collect server metrics", zap.String("server_id", server.ID), zap.Error(err))
+			continue
+		}
+		metrics.ID = ksuid.New().String()
+		metrics.ServerID = server.ID
+		metrics.SampledAt = sampledAt
+		samples = append(samples, *metrics)
+	}
+
+	// Store the samples and drop the expired ones
+	tx, err = repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for storing metrics", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	for _, metrics := range samples {
+		if err := repository.CreateServerMetrics(ctx, tx, metrics); err != nil {
+			zap.L().Error("Failed to store server metrics", zap.String("server_id", metrics.ServerID), zap.Error(err))
+			return
+		}
+	}
+
+	if err := repository.DeleteServerMetricsBefore(ctx, tx, time.Now().Add(-retention)); err != nil {
+		zap.L().Error("Failed to delete expired server metrics", zap.Error(err))
+		return
+	}
+
+	repository.CommitTransaction(tx, ctx)
+}
+
+// collectServer gathers the host and Docker disk metrics of a single server
+func (c *Collector) collectServer(ctx context.Context, server models.Server) (*models.ServerMetrics, error) {
+	// Get the credentials and jump hosts for the connection
+	tx, err := repository.StartTransaction(c.DB, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	credentials, err := generator.ServerCredentials(ctx, tx, server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve server credentials: %w", err)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	sshHost := generator.ServerHost(server)
+	connectionID := generator.ServerConnectionID(server.TeamID, server.ID)
+
+	// Collect the host metrics over SSH first, they don't depend on the Docker daemon
+	metrics := &models.ServerMetrics{}
+	if server.ConnectionType == models.ServerConnectionSSH {
+		if err := c.collectHostMetrics(ctx, connectionID, sshHost, credentials, metrics); err != nil {
+			return nil, err
+		}
+	}
+
+	dockerClient, err := c.ConnectionPool.GetDockerConnection(connectionID, sshHost, credentials)
+	if err != nil {
+		// Without SSH only Docker can tell about the host
+		if server.ConnectionType != models.ServerConnectionSSH {
+			return nil, fmt.Errorf("failed to get Docker connection: %w", err)
+		}
+		zap.L().Warn("Failed to get Docker connection, storing host metrics only", zap.String("server_id", server.ID), zap.Error(err))
+		return metrics, nil
+	}
+
+	if server.ConnectionType != models.ServerConnectionSSH {
+		if err := collectDockerHostInfo(ctx, dockerClient, metrics); err != nil {
+			return nil, err
+		}
+	}
+
+	// The Docker disk usage is optional, a busy or broken daemon should not drop the host sample
+	if err := collectDockerDiskUsage(ctx, dockerClient, metrics); err != nil {
+		zap.L().Warn("Failed to collect Docker disk usage", zap.String("server_id", server.ID), zap.Error(err))
+	}
+
+	return metrics, nil
+}
+
+// collectDockerHostInfo fills the CPU count and total memory reported by Docker, for servers reached without SSH
+func collectDockerHostInfo(ctx context.Context, dockerClient *client.Client, metrics *models.ServerMetrics) error {
+	info, err := dockerClient.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get Docker info: %w", err)
+	}
+
+	metrics.CPUCount = info.NCPU
+	metrics.MemoryTotal = info.MemTotal
+	return nil
+}
+
+// collectHostMetrics runs hostMetricsCommand over SSH and parses its output
+func (c *Collector) collectHostMetrics(ctx context.Context, connectionID, sshHost string, credentials connection.Credentials, metrics *models.ServerMetrics) error {
+	result, err := c.ConnectionPool.ExecuteSSHCommand(ctx, connectionID, sshHost, credentials, hostMetricsCommand, commandTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to run metrics command: %w", err)
+	}
+
+	// Drain all channels until the command finished, stderr is only logged
+	var lines []string
+	stdoutChan, stderrChan, errorChan := result.StdoutChan, result.StderrChan, result.ErrorChan
+	for stdoutChan != nil || stderrChan != nil || errorChan != nil {
+		select {
+		case line, ok := <-stdoutChan:
+			if !ok {
+				stdoutChan = nil
+				continue
+			}
+			lines = append(lines, line)
+		case line, ok := <-stderrChan:
+			if !ok {
+				stderrChan = nil
+				continue
+			}
+			zap.L().Debug("Metrics command stderr", zap.String("line", line))
+		case _, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+			}
+		}
+	}
+	<-result.DoneChan
+
+	if err := parseHostMetrics(lines, metrics); err != nil {
+		if finalErr := result.GetFinalError(); finalErr != nil {
+			return fmt.Errorf("metrics command failed: %w", finalErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// collectDockerDiskUsage adds the disk space used by Docker images, containers, volumes and build cache
+func collectDockerDiskUsage(ctx context.Context, dockerClient *client.Client, metrics *models.ServerMetrics) error {
+	diskUsage, err := dockerClient.DiskUsage(ctx, types.DiskUsageOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get Docker disk usage: %w", err)
+	}
+
+	metrics.DockerImagesSize = diskUsage.LayersSize
+	for _, container := range diskUsage.Containers {
+		metrics.DockerContainersSize += container.SizeRw
+	}
+	for _, volume := range diskUsage.Volumes {
+		if volume.UsageData != nil && volume.UsageData.Size > 0 {
+			metrics.DockerVolumesSize += volume.UsageData.Size
+		}
+	}
+	for _, cache := range diskUsage.BuildCache {
+		metrics.DockerBuildCacheSize += cache.Size
+	}
+
+	return nil
+}
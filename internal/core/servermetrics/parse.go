@@ -0,0 +1,162 @@
+package servermetrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// sectionSeparator splits the output of the different commands in hostMetricsCommand
+const sectionSeparator = "---starker---"
+
+// hostMetricsCommand reads the load, CPU count, memory and disk usage in a single SSH round trip.
+// Only /proc and POSIX df output are used so it works on minimal hosts as well.
+var hostMetricsCommand = strings.Join([]string{
+	"cat /proc/loadavg",
+	"echo " + sectionSeparator,
+	"nproc 2>/dev/null || grep -c ^processor /proc/cpuinfo",
+	"echo " + sectionSeparator,
+	"cat /proc/meminfo",
+	"echo " + sectionSeparator,
+	"df -P -k -x tmpfs -x devtmpfs -x overlay -x squashfs 2>/dev/null",
+}, "; ")
+
+// parseHostMetrics fills the host metrics from the output lines of hostMetricsCommand
+func parseHostMetrics(lines []string, metrics *models.ServerMetrics) error {
+	sections := splitSections(lines)
+	if len(sections) < 4 {
+		return fmt.Errorf("unexpected metrics output: got %d sections, want 4", len(sections))
+	}
+
+	if err := parseLoadAvg(sections[0], metrics); err != nil {
+		return err
+	}
+	if err := parseCPUCount(sections[1], metrics); err != nil {
+		return err
+	}
+	if err := parseMemInfo(sections[2], metrics); err != nil {
+		return err
+	}
+	metrics.Disks = parseDf(sections[3])
+
+	return nil
+}
+
+// splitSections splits the output lines at the section separator
+func splitSections(lines []string) [][]string {
+	sections := [][]string{{}}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == sectionSeparator {
+			sections = append(sections, []string{})
+			continue
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], line)
+	}
+	return sections
+}
+
+// parseLoadAvg parses /proc/loadavg, e.g. "0.42 0.35 0.30 1/123 4567"
+func parseLoadAvg(lines []string, metrics *models.ServerMetrics) error {
+	if len(lines) == 0 {
+		return fmt.Errorf("empty /proc/loadavg output")
+	}
+
+	fields := strings.Fields(lines[0])
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected /proc/loadavg output: %q", lines[0])
+	}
+
+	loads := make([]float64, 3)
+	for i := range loads {
+		load, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse load average %q: %w", fields[i], err)
+		}
+		loads[i] = load
+	}
+	metrics.Load1, metrics.Load5, metrics.Load15 = loads[0], loads[1], loads[2]
+
+	return nil
+}
+
+// parseCPUCount parses the output of nproc
+func parseCPUCount(lines []string, metrics *models.ServerMetrics) error {
+	if len(lines) == 0 {
+		return fmt.Errorf("empty nproc output")
+	}
+
+	cpuCount, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return fmt.Errorf("failed to parse CPU count %q: %w", lines[0], err)
+	}
+	metrics.CPUCount = cpuCount
+
+	return nil
+}
+
+// parseMemInfo parses /proc/meminfo, values are given in kB
+func parseMemInfo(lines []string, metrics *models.ServerMetrics) error {
+	values := make(map[string]int64)
+	for _, line := range lines {
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[key] = value * 1024
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return fmt.Errorf("MemTotal missing in /proc/meminfo")
+	}
+	metrics.MemoryTotal = total
+
+	// Old kernels have no MemAvailable, estimate it from free memory and caches
+	if available, ok := values["MemAvailable"]; ok {
+		metrics.MemoryAvailable = available
+	} else {
+		metrics.MemoryAvailable = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	metrics.SwapTotal = values["SwapTotal"]
+	metrics.SwapFree = values["SwapFree"]
+
+	return nil
+}
+
+// parseDf parses POSIX df output in 1K blocks, the header and malformed lines are skipped
+func parseDf(lines []string) []models.ServerDiskUsage {
+	disks := []models.ServerDiskUsage{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+
+		total, errTotal := strconv.ParseInt(fields[1], 10, 64)
+		used, errUsed := strconv.ParseInt(fields[2], 10, 64)
+		available, errAvailable := strconv.ParseInt(fields[3], 10, 64)
+		if errTotal != nil || errUsed != nil || errAvailable != nil {
+			continue
+		}
+
+		disks = append(disks, models.ServerDiskUsage{
+			Filesystem: fields[0],
+			// Mount points may contain spaces, they are the rest of the line
+			Mount:     strings.Join(fields[5:], " "),
+			Total:     total * 1024,
+			Used:      used * 1024,
+			Available: available * 1024,
+		})
+	}
+	return disks
+}
@@ -0,0 +1,181 @@
+package serverstatus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/hostkeys"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+const (
+	// probeTimeout limits the ping and version calls of a single probe
+	probeTimeout = 15 * time.Second
+	// maxConcurrentProbes limits how many servers are probed at the same time,
+	// so a few unreachable servers do not delay the others by their dial timeouts
+	maxConcurrentProbes = 8
+)
+
+// Prober periodically checks that the Docker daemon of every server is reachable
+// and records the status, latency and daemon details on the server
+type Prober struct {
+	DB             *pgxpool.Pool
+	ConnectionPool *connection.ConnectionPool
+	Interval       time.Duration
+}
+
+// NewProber creates a prober with its own connection pool
+func NewProber(db *pgxpool.Pool, interval time.Duration) *Prober {
+	connectionPool := connection.NewConnectionPool(20*time.Minute, 1*time.Hour)
+	connectionPool.SetHostKeyStore(hostkeys.NewStore(db))
+
+	return &Prober{
+		DB:             db,
+		ConnectionPool: connectionPool,
+		Interval:       interval,
+	}
+}
+
+// Start runs the prober in a goroutine until the context is canceled
+func (p *Prober) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+
+		// Probe right away so the status is known shortly after startup
+		p.probeAll(ctx)
+
+		for {
+			select {
+			case <-ctx.Done():
+				p.ConnectionPool.Close()
+				return
+			case <-ticker.C:
+				p.probeAll(ctx)
+			}
+		}
+	}()
+
+	zap.L().Info("Server status prober started", zap.Duration("interval", p.Interval))
+}
+
+// probeAll probes every server and stores the results
+func (p *Prober) probeAll(ctx context.Context) {
+	// Get the servers in a short transaction, the probes can be slow
+	tx, err := repository.StartTransaction(p.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for status probe", zap.Error(err))
+		return
+	}
+	servers, err := repository.GetAllServers(ctx, tx)
+	repository.CommitTransaction(tx, ctx)
+	if err != nil {
+		zap.L().Error("Failed to get servers for status probe", zap.Error(err))
+		return
+	}
+
+	results := make([]models.Server, len(servers))
+	semaphore := make(chan struct{}, maxConcurrentProbes)
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = p.probe(ctx, server)
+		}()
+	}
+	wg.Wait()
+
+	// Store the results
+	tx, err = repository.StartTransaction(p.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for storing server status", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	for _, server := range results {
+		if err := repository.UpdateServerStatus(ctx, tx, server); err != nil {
+			zap.L().Error("Failed to store server status", zap.String("server_id", server.ID), zap.Error(err))
+			return
+		}
+	}
+
+	repository.CommitTransaction(tx, ctx)
+}
+
+// probe checks a single server and returns it with the status fields set
+func (p *Prober) probe(ctx context.Context, server models.Server) models.Server {
+	checkedAt := time.Now()
+	server.StatusCheckedAt = &checkedAt
+	server.StatusError = nil
+	server.LastSeenAt = nil
+	server.LatencyMs = nil
+	server.DockerVersion = nil
+	server.OS = nil
+	server.Arch = nil
+
+	if err := p.probeDocker(ctx, &server); err != nil {
+		zap.L().Debug("Server status probe failed", zap.String("server_id", server.ID), zap.Error(err))
+		server.Status = models.ServerStatusOffline
+		server.StatusError = &[]string{err.Error()}[0]
+		return server
+	}
+
+	server.Status = models.ServerStatusOnline
+	server.LastSeenAt = &checkedAt
+	return server
+}
+
+// probeDocker pings the Docker daemon of the server and reads its version
+func (p *Prober) probeDocker(ctx context.Context, server *models.Server) error {
+	tx, err := repository.StartTransaction(p.DB, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	credentials, err := generator.ServerCredentials(ctx, tx, *server)
+	if err != nil {
+		return fmt.Errorf("failed to resolve server credentials: %w", err)
+	}
+	repository.CommitTransaction(tx, ctx)
+
+	connectionID := generator.ServerConnectionID(server.TeamID, server.ID)
+	dockerClient, err := p.ConnectionPool.GetDockerConnection(connectionID, generator.ServerHost(*server), credentials)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := dockerClient.Ping(probeCtx); err != nil {
+		// Drop the broken connection so the next probe dials again
+		p.ConnectionPool.RemoveConnection(connectionID)
+		return fmt.Errorf("failed to ping Docker: %w", err)
+	}
+	latency := int(time.Since(start).Milliseconds())
+
+	version, err := dockerClient.ServerVersion(probeCtx)
+	if err != nil {
+		return fmt.Errorf("failed to get Docker version: %w", err)
+	}
+
+	server.LatencyMs = &latency
+	server.DockerVersion = &version.Version
+	server.OS = &version.Os
+	server.Arch = &version.Arch
+	return nil
+}
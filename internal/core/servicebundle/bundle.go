@@ -0,0 +1,242 @@
+package servicebundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/dotenv"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+const (
+	// ComposeFileName is the name of the compose file in the bundle, docker compose picks it up without flags
+	ComposeFileName = "docker-compose.yml"
+	// EnvFileName is the name of the environment file in the bundle, docker compose reads it for interpolation
+	EnvFileName = ".env"
+	// ReadmeFileName is the name of the deployment notes in the bundle
+	ReadmeFileName = "README.md"
+)
+
+// Bundle is everything needed to run a service with plain docker compose
+type Bundle struct {
+	Service         models.Service
+	Server          models.Server
+	ComposeConfig   models.ServiceComposeConfig // Compose files and profiles as stored for the service
+	Project         *types.Project              // Parsed compose file
+	NamingGenerator *generator.NamingGenerator  // Names the service is deployed with
+	Environments    []models.ServiceEnvironment // Environment variables of the service
+	EnvFiles        []models.ServiceEnvFile     // Env files env_file entries of the compose files reference
+	Images          []models.ServiceImage       // Images of the last deployment
+	IncludeSecrets  bool                        // Whether the environment and env file values are written, otherwise they are left empty
+	ExportedAt      time.Time                   // Time the bundle was created
+}
+
+// bundleFile is a file of the zip archive
+type bundleFile struct {
+	name    string
+	content string
+}
+
+// Build returns the bundle as zip archive
+func Build(bundle Bundle) ([]byte, error) {
+	composeFile, err := dockeryaml.ResolveNames(bundle.ComposeConfig.ComposeFile, bundle.Project, bundle.NamingGenerator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the names in the compose file: %w", err)
+	}
+
+	envFile, err := bundle.envFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	files := []bundleFile{
+		{ComposeFileName, composeFile},
+		{EnvFileName, envFile},
+		{ReadmeFileName, bundle.readme()},
+	}
+	// The additional compose files are referenced by their filenames, they keep them unchanged
+	for _, file := range bundle.ComposeConfig.Files {
+		files = append(files, bundleFile{file.Filename, file.Content})
+	}
+	// An env file named like the .env of the bundle is part of it already
+	for _, file := range bundle.EnvFiles {
+		if file.Name == EnvFileName {
+			continue
+		}
+		content, err := bundle.envFileContent(file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, bundleFile{file.Name, content})
+	}
+	for _, file := range files {
+		fileWriter, err := writer.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: bundle.ExportedAt})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to the bundle: %w", file.name, err)
+		}
+		if _, err := fileWriter.Write([]byte(file.content)); err != nil {
+			return nil, fmt.Errorf("failed to write %s to the bundle: %w", file.name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish the bundle: %w", err)
+	}
+
+	return archive.Bytes(), nil
+}
+
+// envFile returns the environment variables of the service in dotenv format
+func (b Bundle) envFile() (string, error) {
+	var env strings.Builder
+	fmt.Fprintf(&env, "# Environment of %s, exported from Starker\n", b.Service.Name)
+	if !b.IncludeSecrets {
+		env.WriteString("# The values were not exported, fill them in before starting the service\n")
+	}
+
+	// docker compose reads the files and profiles to load from the .env file of the project
+	if overrides := b.overrideFiles(); len(overrides) > 0 {
+		env.WriteString(dotenv.Format("COMPOSE_FILE", strings.Join(append([]string{ComposeFileName}, overrides...), ":")) + "\n")
+	}
+	if len(b.ComposeConfig.Profiles) > 0 {
+		env.WriteString(dotenv.Format("COMPOSE_PROFILES", strings.Join(b.ComposeConfig.Profiles, ",")) + "\n")
+	}
+
+	environments := slices.Clone(b.Environments)
+	slices.SortFunc(environments, func(a, b models.ServiceEnvironment) int { return strings.Compare(a.Key, b.Key) })
+	entries := make([]dotenv.Entry, 0, len(environments))
+	for _, environment := range environments {
+		entry := dotenv.Entry{Key: environment.Key, Value: environment.Value}
+		if environment.Comment != nil {
+			entry.Comment = *environment.Comment
+		}
+		if !b.IncludeSecrets {
+			entry.Value = ""
+		}
+		entries = append(entries, entry)
+	}
+	env.WriteString(dotenv.Marshal(entries))
+
+	// The env file of the service named .env is read by env_file from the same file
+	for _, file := range b.EnvFiles {
+		if file.Name != EnvFileName {
+			continue
+		}
+		content, err := b.envFileContent(file)
+		if err != nil {
+			return "", err
+		}
+		env.WriteString("\n# Env file .env of the service\n" + content)
+	}
+
+	return env.String(), nil
+}
+
+// envFileContent returns an env file of the service, its values are left empty unless the secrets are included
+func (b Bundle) envFileContent(file models.ServiceEnvFile) (string, error) {
+	if b.IncludeSecrets {
+		return file.Content, nil
+	}
+
+	entries, err := dotenv.Parse(file.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read env file %s: %w", file.Name, err)
+	}
+	for i := range entries {
+		entries[i].Value = ""
+	}
+	return dotenv.Marshal(entries), nil
+}
+
+// readme returns the notes on how the service was deployed and how to run the bundle
+func (b Bundle) readme() string {
+	var readme strings.Builder
+	fmt.Fprintf(&readme, "# %s\n\n", b.Service.Name)
+	if b.Service.Description != nil && *b.Service.Description != "" {
+		fmt.Fprintf(&readme, "%s\n\n", *b.Service.Description)
+	}
+	fmt.Fprintf(&readme, "Exported from Starker on %s.\n\n", b.ExportedAt.UTC().Format(time.RFC3339))
+
+	readme.WriteString("## Deployment\n\n")
+	fmt.Fprintf(&readme, "- Service ID: `%s`\n", b.Service.ID)
+	fmt.Fprintf(&readme, "- Server: %s (`%s`)\n", b.Server.Name, generator.ServerHost(b.Server))
+	fmt.Fprintf(&readme, "- State: %s\n", b.Service.State)
+	if b.Service.LastDeployedAt != nil {
+		fmt.Fprintf(&readme, "- Last deployed: %s\n", b.Service.LastDeployedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&readme, "- Compose project: `%s`\n\n", b.NamingGenerator.ProjectName())
+
+	if len(b.Images) > 0 {
+		readme.WriteString("The last deployment ran these images:\n\n")
+		readme.WriteString("| Service | Image | Digest |\n| --- | --- | --- |\n")
+		for _, image := range b.Images {
+			digest := "-"
+			if image.RepoDigest != nil {
+				digest = "`" + *image.RepoDigest + "`"
+			}
+			fmt.Fprintf(&readme, "| %s | `%s` | %s |\n", image.ServiceName, image.ImageName, digest)
+		}
+		readme.WriteString("\n")
+	}
+
+	readme.WriteString("## Resources\n\n")
+	readme.WriteString("The compose file was rewritten with the names Starker uses, so docker compose manages the same resources:\n\n")
+	for _, name := range sortedKeys(b.Project.Services) {
+		fmt.Fprintf(&readme, "- Container `%s` for service %s\n", b.NamingGenerator.ContainerName(name), name)
+	}
+	for _, key := range sortedKeys(b.Project.Networks) {
+		if !b.Project.Networks[key].External {
+			fmt.Fprintf(&readme, "- Network `%s` for %s\n", dockeryaml.ExportNetworkName(b.Project, key, b.NamingGenerator), key)
+		}
+	}
+	for _, key := range sortedKeys(b.Project.Volumes) {
+		if !b.Project.Volumes[key].External {
+			fmt.Fprintf(&readme, "- Volume `%s` for %s\n", b.NamingGenerator.VolumeName(b.Project.Volumes[key].Name), key)
+		}
+	}
+
+	readme.WriteString("\n## Running it\n\n")
+	readme.WriteString("```sh\ndocker compose up -d\n```\n\n")
+	fmt.Fprintf(&readme, "Run it in the directory of this file. docker compose reads `%s` for the variables the compose file references.\n", EnvFileName)
+	if overrides := b.overrideFiles(); len(overrides) > 0 {
+		fmt.Fprintf(&readme, "It also sets `COMPOSE_FILE`, so the overrides %s are loaded on top of `%s` in this order.\n", "`"+strings.Join(overrides, "`, `")+"`", ComposeFileName)
+	}
+	if len(b.ComposeConfig.Profiles) > 0 {
+		fmt.Fprintf(&readme, "It also sets `COMPOSE_PROFILES`, so the profiles %s are active.\n", "`"+strings.Join(b.ComposeConfig.Profiles, "`, `")+"`")
+	}
+	if !b.IncludeSecrets {
+		fmt.Fprintf(&readme, "The values in `%s` were not exported, fill them in first.\n", EnvFileName)
+	}
+	readme.WriteString("\nOn the same server the volumes and their data are reused. Stop the service in Starker first, the container names would conflict.\n")
+
+	return readme.String()
+}
+
+// overrideFiles returns the filenames of the additional compose files loaded on top of the main one
+func (b Bundle) overrideFiles() []string {
+	var overrides []string
+	for _, file := range b.ComposeConfig.Files {
+		if file.Override {
+			overrides = append(overrides, file.Filename)
+		}
+	}
+	return overrides
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,215 @@
+package statsampler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/core/hostkeys"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+const (
+	// minuteRetention is how long raw samples are kept
+	minuteRetention = 24 * time.Hour
+	// hourRetention is how long hourly rollups are kept
+	hourRetention = 7 * 24 * time.Hour
+)
+
+// Sampler periodically stores the resource usage of all running containers
+// and downsamples the history for the charts
+type Sampler struct {
+	DB             *pgxpool.Pool
+	ConnectionPool *connection.ConnectionPool
+	Interval       time.Duration
+}
+
+// NewSampler creates a sampler with its own connection pool
+func NewSampler(db *pgxpool.Pool, interval time.Duration) *Sampler {
+	connectionPool := connection.NewConnectionPool(20*time.Minute, 1*time.Hour)
+	connectionPool.SetHostKeyStore(hostkeys.NewStore(db))
+
+	return &Sampler{
+		DB:             db,
+		ConnectionPool: connectionPool,
+		Interval:       interval,
+	}
+}
+
+// Start runs the sampler in a goroutine until the context is canceled
+func (s *Sampler) Start(ctx context.Context) {
+	go func() {
+		sampleTicker := time.NewTicker(s.Interval)
+		defer sampleTicker.Stop()
+		rollupTicker := time.NewTicker(time.Hour)
+		defer rollupTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				s.ConnectionPool.Close()
+				return
+			case <-sampleTicker.C:
+				s.sample(ctx)
+			case <-rollupTicker.C:
+				s.rollup(ctx)
+			}
+		}
+	}()
+
+	zap.L().Info("Container stats sampler started", zap.Duration("interval", s.Interval))
+}
+
+// sample stores one sample of every container of every running service
+func (s *Sampler) sample(ctx context.Context) {
+	// Get the running services in a short transaction, the stats calls can be slow
+	tx, err := repository.StartTransaction(s.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for stats sampling", zap.Error(err))
+		return
+	}
+	services, err := repository.GetServicesByState(ctx, tx, models.ServiceStateRunning)
+	repository.CommitTransaction(tx, ctx)
+	if err != nil {
+		zap.L().Error("Failed to get running services for stats sampling", zap.Error(err))
+		return
+	}
+
+	// All samples of one round share the same timestamp so the charts line up
+	sampledAt := time.Now().Truncate(time.Minute)
+
+	var samples []models.ContainerStats
+	for _, service := range services {
+		serviceSamples, err := s.sampleService(ctx, service)
+		if err != nil {
+			zap.L().Warn("Failed to sample service stats", zap.String("service_id", service.ID), zap.Error(err))
+			continue
+		}
+
+		for _, stats := range serviceSamples {
+			stats.ID = ksuid.New().String()
+			stats.ServiceID = service.ID
+			stats.Resolution = models.StatsResolutionMinute
+			stats.SampledAt = sampledAt
+			samples = append(samples, stats)
+		}
+	}
+
+	if len(samples) == 0 {
+		return
+	}
+
+	// Store the samples
+	tx, err = repository.StartTransaction(s.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for storing stats", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	for _, stats := range samples {
+		if err := repository.CreateContainerStats(ctx, tx, stats); err != nil {
+			zap.L().Error("Failed to store container stats", zap.String("container", stats.ContainerName), zap.Error(err))
+			return
+		}
+	}
+
+	repository.CommitTransaction(tx, ctx)
+}
+
+// sampleService takes a stats sample of every running container of the service
+func (s *Sampler) sampleService(ctx context.Context, service models.Service) ([]models.ContainerStats, error) {
+	// Read the connection details in a short transaction, the stats calls can be slow
+	tx, err := repository.StartTransaction(s.DB, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	containers, err := repository.GetServiceContainers(ctx, tx, service.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service containers: %w", err)
+	}
+
+	server, err := repository.GetServerByID(ctx, tx, service.ServerID, service.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("server not found")
+	}
+
+	credentials, err := generator.ServerCredentials(ctx, tx, *server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve server credentials: %w", err)
+	}
+
+	repository.CommitTransaction(tx, ctx)
+
+	// Get Docker client from connection pool
+	sshHost := generator.ServerHost(*server)
+	connectionID := generator.ServerConnectionID(service.TeamID, service.ServerID)
+	dockerClient, err := s.ConnectionPool.GetDockerConnection(connectionID, sshHost, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Docker connection: %w", err)
+	}
+
+	dockerHandler := &dockerutils.DockerHandler{
+		Client:     dockerClient,
+		StreamChan: core.NewStreamChan(),
+	}
+
+	var samples []models.ContainerStats
+	for _, container := range containers {
+		if container.ContainerID == nil || *container.ContainerID == "" || container.State != models.ContainerStateRunning {
+			continue
+		}
+
+		stats, err := dockerHandler.GetContainerStats(ctx, *container.ContainerID, container.ContainerName)
+		if err != nil {
+			zap.L().Warn("Failed to sample container stats", zap.String("container", container.ContainerName), zap.Error(err))
+			continue
+		}
+		samples = append(samples, *stats)
+	}
+
+	return samples, nil
+}
+
+// rollup aggregates the minute samples into hourly samples and removes expired history
+func (s *Sampler) rollup(ctx context.Context) {
+	tx, err := repository.StartTransaction(s.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for stats rollup", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	// Roll up every complete hour still covered by the minute samples
+	now := time.Now()
+	currentHour := now.Truncate(time.Hour)
+	if err := repository.RollupContainerStats(ctx, tx, currentHour.Add(-minuteRetention), currentHour); err != nil {
+		zap.L().Error("Failed to roll up container stats", zap.Error(err))
+		return
+	}
+
+	if err := repository.DeleteContainerStatsBefore(ctx, tx, models.StatsResolutionMinute, now.Add(-minuteRetention)); err != nil {
+		zap.L().Error("Failed to delete expired minute stats", zap.Error(err))
+		return
+	}
+	if err := repository.DeleteContainerStatsBefore(ctx, tx, models.StatsResolutionHour, now.Add(-hourRetention)); err != nil {
+		zap.L().Error("Failed to delete expired hour stats", zap.Error(err))
+		return
+	}
+
+	repository.CommitTransaction(tx, ctx)
+}
@@ -27,6 +27,7 @@ const (
 	LogTypeInfo     LogType = "info"
 	LogTypeProgress LogType = "progress"
 	LogTypeStep     LogType = "step"
+	LogTypeStats    LogType = "stats"
 )
 
 type LogMessage struct {
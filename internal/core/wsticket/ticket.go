@@ -0,0 +1,90 @@
+package wsticket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/segmentio/ksuid"
+
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/encrypt"
+)
+
+// Resource types a ticket can be issued for
+const (
+	ResourceServerTerminal = "server_terminal" // SSH terminal of a server, the resource ID is the server ID
+	ResourceContainerExec  = "container_exec"  // Shell in a service container, the resource ID is the service container ID
+)
+
+const (
+	// TTL is how long a ticket can be used after it was issued, it only has to outlive the handshake
+	TTL = 30 * time.Second
+	// Protocol is the WebSocket subprotocol a client offers next to the ticket, the server selects it
+	Protocol = "starker.ticket"
+	// QueryParam is the query parameter a client can pass the ticket in instead
+	QueryParam = "ticket"
+	// ticketPrefix starts every ticket, it tells the ticket apart from the other offered subprotocols
+	ticketPrefix = "starker_ws_"
+)
+
+// Ticket is an issued ticket as returned to the client
+type Ticket struct {
+	Ticket    string    `json:"ticket" example:"starker_ws_3q2-7wEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"` // Pass as ticket query parameter or offer as subprotocol next to starker.ticket
+	ExpiresAt time.Time `json:"expires_at" example:"2023-01-01T12:00:30Z"`                               // The ticket has to be used before this time
+}
+
+// Issue creates a ticket that opens one WebSocket to the resource as the user
+func Issue(ctx context.Context, tx pgx.Tx, userID, resourceType, resourceID string) (*Ticket, error) {
+	ticket, err := encrypt.GenerateWebSocketTicket()
+	if err != nil {
+		return nil, err
+	}
+
+	// Tickets that were never used are cleaned up whenever a new one is issued
+	now := time.Now()
+	if err := repository.DeleteExpiredWebSocketTickets(ctx, tx, now); err != nil {
+		return nil, fmt.Errorf("failed to delete expired tickets: %w", err)
+	}
+
+	webSocketTicket := models.WebSocketTicket{
+		ID:           ksuid.New().String(),
+		TicketHash:   encrypt.HashWebSocketTicket(ticket),
+		UserID:       userID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ExpiresAt:    now.Add(TTL),
+		CreatedAt:    now,
+	}
+	if err := repository.CreateWebSocketTicket(ctx, tx, webSocketTicket); err != nil {
+		return nil, fmt.Errorf("failed to create ticket: %w", err)
+	}
+
+	return &Ticket{Ticket: ticket, ExpiresAt: webSocketTicket.ExpiresAt}, nil
+}
+
+// Consume uses up a ticket and returns the user it authenticates, the user is empty when the ticket is not valid for the resource
+func Consume(ctx context.Context, tx pgx.Tx, ticket, resourceType, resourceID string) (string, error) {
+	webSocketTicket, err := repository.ConsumeWebSocketTicket(ctx, tx, encrypt.HashWebSocketTicket(ticket), resourceType, resourceID, time.Now())
+	if err != nil || webSocketTicket == nil {
+		return "", err
+	}
+	return webSocketTicket.UserID, nil
+}
+
+// FromRequest returns the ticket of a handshake request, passed in the query or offered as subprotocol next to Protocol
+func FromRequest(r *http.Request) string {
+	if ticket := r.URL.Query().Get(QueryParam); ticket != "" {
+		return ticket
+	}
+	for _, protocol := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if protocol = strings.TrimSpace(protocol); strings.HasPrefix(protocol, ticketPrefix) {
+			return protocol
+		}
+	}
+	return ""
+}
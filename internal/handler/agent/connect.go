@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/agent"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/encrypt"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Connect Agent                                |
+// +----------------------------------------------+
+
+// ConnectAgent godoc
+// @Summary Connect an agent
+// @Description Endpoint starker-agent dials from servers behind NAT. The agent authenticates with the token of its server
+// @Description and the connection is upgraded to a WebSocket that carries the tunnel to the Docker socket of the server.
+// @Description The tunnel stays open until the agent disconnects, its token is rotated or the server is deleted.
+// @Tags agent
+// @Param Authorization header string true "Bearer followed by the agent token"
+// @Success 101 "Switching protocols to the agent tunnel"
+// @Failure 401 {object} response.ErrorResponse "Missing or invalid agent token"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /agent/connect [get]
+func (h *AgentHandler) ConnectAgent(w http.ResponseWriter, r *http.Request) {
+	// Get the agent token from the authorization header
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		response.RespondWithError(w, http.StatusUnauthorized, "Missing agent token", "MISSING_AGENT_TOKEN")
+		return
+	}
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Find the server of the token
+	server, err := repository.GetServerByAgentTokenHash(r.Context(), tx, encrypt.HashAgentToken(token))
+	if err != nil {
+		zap.L().Error("Failed to get agent server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get agent server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusUnauthorized, "Invalid agent token", "INVALID_AGENT_TOKEN")
+		return
+	}
+
+	// Commit the transaction since the tunnel is long lived
+	repository.CommitTransaction(tx, r.Context())
+
+	// Upgrade to WebSocket and start the tunnel over it
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		ws.PayloadType = websocket.BinaryFrame
+
+		sshClient, err := agent.NewClient(ws, server.ID)
+		if err != nil {
+			zap.L().Warn("Failed to start agent tunnel", zap.String("server_id", server.ID), zap.Error(err))
+			return
+		}
+		defer sshClient.Close()
+
+		unregister := connection.RegisterAgentTunnel(server.ID, sshClient)
+		defer unregister()
+
+		zap.L().Info("Agent connected", zap.String("server_id", server.ID), zap.String("remote_addr", r.RemoteAddr))
+		err = sshClient.Wait()
+		zap.L().Info("Agent disconnected", zap.String("server_id", server.ID), zap.Error(err))
+	}}.ServeHTTP(w, r)
+}
@@ -0,0 +1,7 @@
+package agent
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+type AgentHandler struct {
+	DB *pgxpool.Pool
+}
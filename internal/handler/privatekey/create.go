@@ -17,18 +17,18 @@ import (
 // | Create Private Key                          |
 // +----------------------------------------------+
 
-// TODO: Need to encrypt the private key before storing it in the database
-
 // CreatePrivateKey godoc
 // @Summary Create a new private key
-// @Description Creates a new private key for SSH authentication within a team
+// @Description Creates a new private key for SSH authentication within a team. The key material is stored encrypted and never returned.
+// @Description Encrypted keys need their passphrase, which is stored encrypted as well. An optional OpenSSH user certificate
+// @Description signed for the key is presented instead of the bare public key when connecting to servers.
 // @Tags privatekey
 // @Accept json
 // @Produce json
 // @Param teamID path string true "Team ID"
 // @Param request body privatekeysvc.CreatePrivateKeyRequest true "Private key creation request"
 // @Success 201 {object} response.SuccessResponse{data=models.PrivateKey} "Private key created successfully"
-// @Failure 400 {object} response.ErrorResponse "Invalid request body or team access denied"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, invalid private key or team access denied"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
 // @Router /teams/{teamID}/private-keys [post]
@@ -77,6 +77,12 @@ func (h *PrivateKeyHandler) CreatePrivateKey(w http.ResponseWriter, r *http.Requ
 	// Generate the private key
 	privateKey := privatekeysvc.GeneratePrivateKey(createPrivateKeyRequest, teamID)
 
+	// Make sure the key can be used to authenticate
+	if err := privatekeysvc.ValidateCredentials(privateKey); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_PRIVATE_KEY")
+		return
+	}
+
 	// Create the private key
 	if err = repository.CreatePrivateKey(r.Context(), tx, privateKey); err != nil {
 		zap.L().Error("Failed to create private key", zap.Error(err))
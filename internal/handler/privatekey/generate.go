@@ -0,0 +1,96 @@
+package privatekey
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/internal/service/privatekeysvc"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Generate Private Key                        |
+// +----------------------------------------------+
+
+// GeneratePrivateKey godoc
+// @Summary Generate a new SSH key pair
+// @Description Generates an ed25519 or RSA key pair on the server and stores the private key encrypted.
+// @Description Only the public key is returned, add it to ~/.ssh/authorized_keys on the servers.
+// @Tags privatekey
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param request body privatekeysvc.GenerateKeyPairRequest true "Key pair generation request"
+// @Success 201 {object} response.SuccessResponse{data=models.PrivateKey} "Key pair generated successfully"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body or team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/private-keys/generate [post]
+// @Security BearerAuth
+func (h *PrivateKeyHandler) GeneratePrivateKey(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID from the URL
+	teamID := chi.URLParam(r, "teamID")
+
+	// Get the key pair settings from the request body
+	var generateKeyPairRequest privatekeysvc.GenerateKeyPairRequest
+	if err := json.NewDecoder(r.Body).Decode(&generateKeyPairRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate the key pair settings
+	if err := privatekeysvc.GenerateKeyPairValidate(generateKeyPairRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Generate the key pair
+	privateKey, err := privatekeysvc.GenerateKeyPairPrivateKey(generateKeyPairRequest, teamID)
+	if err != nil {
+		zap.L().Error("Failed to generate key pair", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to generate key pair", "FAILED_TO_GENERATE_KEY_PAIR")
+		return
+	}
+
+	// Store the private key
+	if err = repository.CreatePrivateKey(r.Context(), tx, privateKey); err != nil {
+		zap.L().Error("Failed to create private key", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create private key", "FAILED_TO_CREATE_PRIVATE_KEY")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	// Response, the private key is never serialized
+	response.RespondWithJSON(w, http.StatusCreated, privateKey)
+}
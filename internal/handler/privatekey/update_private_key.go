@@ -19,7 +19,7 @@ import (
 
 // UpdatePrivateKey godoc
 // @Summary Update a private key
-// @Description Updates a specific private key by ID within a team
+// @Description Updates a specific private key by ID within a team. An empty passphrase or certificate removes it.
 // @Tags privatekey
 // @Accept json
 // @Produce json
@@ -27,7 +27,7 @@ import (
 // @Param privateKeyID path string true "Private Key ID"
 // @Param request body privatekeysvc.UpdatePrivateKeyRequest true "Private key update request"
 // @Success 200 {object} response.SuccessResponse{data=models.PrivateKey} "Private key updated successfully"
-// @Failure 400 {object} response.ErrorResponse "Invalid request body or team access denied"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, invalid private key or team access denied"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
 // @Failure 404 {object} response.ErrorResponse "Private key not found"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
@@ -91,6 +91,14 @@ func (h *PrivateKeyHandler) UpdatePrivateKey(w http.ResponseWriter, r *http.Requ
 	// Update the private key fields
 	privatekeysvc.UpdatePrivateKeyFields(privateKey, updatePrivateKeyRequest)
 
+	// Make sure the changed key material can still be used to authenticate
+	if updatePrivateKeyRequest.UpdatesCredentials() {
+		if err := privatekeysvc.ValidateCredentials(*privateKey); err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_PRIVATE_KEY")
+			return
+		}
+	}
+
 	// Update the private key in the database
 	if err = repository.UpdatePrivateKeyByID(r.Context(), tx, *privateKey); err != nil {
 		zap.L().Error("Failed to update private key", zap.Error(err))
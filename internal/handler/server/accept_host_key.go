@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Accept Host Key                              |
+// +----------------------------------------------+
+
+type acceptHostKeyRequest struct {
+	Fingerprint string `json:"fingerprint" validate:"required,startswith=SHA256:" example:"SHA256:abc123..."` // Fingerprint of the presented host key the user confirmed
+}
+
+// AcceptHostKey godoc
+// @Summary Accept a rotated server host key
+// @Description Pins the SSH host key the server presents now, replacing the previously pinned key.
+// @Description The request has to contain the fingerprint of the presented key, as returned by the get host key endpoint, as explicit confirmation.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Param request body acceptHostKeyRequest true "Host key confirmation"
+// @Success 200 {object} response.SuccessResponse{data=models.Server} "Host key accepted successfully"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, team access denied or server not connected over SSH"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "The server presents a different host key than the confirmed one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/host-key [put]
+// @Security BearerAuth
+func (h *ServerHandler) AcceptHostKey(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Parse and validate the request body
+	var acceptHostKeyRequest acceptHostKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&acceptHostKeyRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+	if err := validator.New().Struct(acceptHostKeyRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Get the server from the database
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+	if server.ConnectionType != models.ServerConnectionSSH {
+		response.RespondWithError(w, http.StatusBadRequest, "Host keys are only used by servers connected over SSH", "SSH_NOT_AVAILABLE")
+		return
+	}
+
+	// Servers behind a bastion are scanned through their jump hosts
+	jumpHost, err := generator.ServerJumpHost(r.Context(), tx, *server)
+	if err != nil {
+		zap.L().Error("Failed to resolve jump host", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve jump host", "FAILED_TO_RESOLVE_JUMP_HOST")
+		return
+	}
+
+	// Read the host key the server presents now and make sure it is the one the user confirmed
+	presentedHostKey, err := h.DockerPool.ScanHostKey(generator.ServerHost(*server), jumpHost)
+	if err != nil {
+		zap.L().Error("Failed to scan host key", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to read the server host key", "FAILED_TO_SCAN_HOST_KEY")
+		return
+	}
+	presentedFingerprint, err := connection.HostKeyFingerprint(presentedHostKey)
+	if err != nil {
+		zap.L().Error("Failed to fingerprint host key", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to read the server host key", "FAILED_TO_SCAN_HOST_KEY")
+		return
+	}
+	if presentedFingerprint != acceptHostKeyRequest.Fingerprint {
+		response.RespondWithError(w, http.StatusConflict, fmt.Sprintf("The server presents host key %s, not the confirmed one", presentedFingerprint), "HOST_KEY_MISMATCH")
+		return
+	}
+
+	// Pin the new host key
+	if err = repository.UpdateServerHostKey(r.Context(), tx, serverID, teamID, presentedHostKey); err != nil {
+		zap.L().Error("Failed to update host key", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to update host key", "FAILED_TO_UPDATE_HOST_KEY")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	// Drop the pooled connection so the next one is verified against the new key
+	h.DockerPool.RemoveConnection(generator.ServerConnectionID(teamID, serverID))
+
+	server.HostKey = &presentedHostKey
+	response.RespondWithJSON(w, http.StatusOK, server)
+}
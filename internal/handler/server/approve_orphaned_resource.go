@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Approve Orphaned Resource                    |
+// +----------------------------------------------+
+
+// ApproveOrphanedResource godoc
+// @Summary Approve the removal of an orphaned volume
+// @Description Allows the garbage collection to remove an orphaned volume and the data in it once the grace period is over.
+// @Description Orphaned containers and networks are removed without approval.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Param orphanID path string true "Orphaned resource ID"
+// @Success 200 {object} response.SuccessResponse{data=models.OrphanedResource} "Removal approved"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or the resource is not a volume"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server or orphaned resource not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/orphans/{orphanID}/approve [post]
+// @Security BearerAuth
+func (h *ServerHandler) ApproveOrphanedResource(w http.ResponseWriter, r *http.Request) {
+	// Get the URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+	orphanID := chi.URLParam(r, "orphanID")
+
+	// Get the user ID from the context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Check that the server exists
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+
+	// Get the orphaned resource
+	orphan, err := repository.GetOrphanedResourceByID(r.Context(), tx, orphanID, serverID)
+	if err != nil {
+		zap.L().Error("Failed to get orphaned resource", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get orphaned resource", "FAILED_TO_GET_ORPHANED_RESOURCE")
+		return
+	}
+	if orphan == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Orphaned resource not found", "ORPHANED_RESOURCE_NOT_FOUND")
+		return
+	}
+	if orphan.ResourceType != models.OrphanedResourceVolume {
+		response.RespondWithError(w, http.StatusBadRequest, "Only the removal of volumes needs approval", "ORPHANED_RESOURCE_NOT_VOLUME")
+		return
+	}
+
+	// Approve the removal, an earlier approval is kept
+	if orphan.ApprovedAt == nil {
+		approvedAt := time.Now()
+		if err := repository.ApproveOrphanedResource(r.Context(), tx, orphan.ID, approvedAt); err != nil {
+			zap.L().Error("Failed to approve orphaned resource", zap.Error(err))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to approve orphaned resource", "FAILED_TO_APPROVE_ORPHANED_RESOURCE")
+			return
+		}
+		orphan.ApprovedAt = &approvedAt
+
+		// Record the approval in the audit log
+		auditLog := models.AuditLog{
+			ID:           ksuid.New().String(),
+			TeamID:       teamID,
+			UserID:       userID,
+			Action:       models.AuditActionServerOrphanApprove,
+			ResourceType: "server",
+			ResourceID:   server.ID,
+			Metadata: map[string]string{
+				"service_id": orphan.ServiceID,
+				"volume":     orphan.Name,
+			},
+			IP:        &r.RemoteAddr,
+			CreatedAt: approvedAt,
+		}
+		if err := repository.CreateAuditLog(r.Context(), tx, auditLog); err != nil {
+			zap.L().Error("Failed to create audit log", zap.Error(err))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to create audit log", "FAILED_TO_CREATE_AUDIT_LOG")
+			return
+		}
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	// Return the orphaned resource
+	response.RespondWithJSON(w, http.StatusOK, orphan)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/serverbootstrap"
+	"github.com/yorukot/starker/internal/handler/server/utils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Bootstrap Server                             |
+// +----------------------------------------------+
+
+// BootstrapServer godoc
+// @Summary Bootstrap a server
+// @Description Installs and configures Docker Engine on the server over SSH and streams the progress using Server-Sent Events.
+// @Description Detects the distribution, installs Docker and socat when missing, enables the Docker service, adds the SSH user to the docker group,
+// @Description enables log rotation when no daemon.json exists and verifies the Docker connection. Non-root users need passwordless sudo.
+// @Tags server
+// @Accept json
+// @Produce text/event-stream
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {string} string "Server-Sent Events stream of the bootstrap progress"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or server not connected over SSH"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/bootstrap [post]
+// @Security BearerAuth
+func (h *ServerHandler) BootstrapServer(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get user ID from context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Get the server from the database
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+	if server.ConnectionType != models.ServerConnectionSSH {
+		response.RespondWithError(w, http.StatusBadRequest, "Only servers connected over SSH can be bootstrapped", "SSH_NOT_AVAILABLE")
+		return
+	}
+
+	// Resolve the credentials, including the jump hosts in front of the server
+	credentials, err := generator.ServerCredentials(r.Context(), tx, *server)
+	if errors.Is(err, generator.ErrPrivateKeyNotFound) {
+		response.RespondWithError(w, http.StatusBadRequest, "Private key not found", "PRIVATE_KEY_NOT_FOUND")
+		return
+	}
+	if err != nil {
+		zap.L().Error("Failed to resolve server credentials", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve server credentials", "FAILED_TO_RESOLVE_CREDENTIALS")
+		return
+	}
+
+	// Nothing is written during the bootstrap, so release the transaction before streaming
+	repository.CommitTransaction(tx, r.Context())
+
+	// Run the bootstrap in the background, it is cancelled when the client disconnects
+	streamChan := core.NewStreamChan()
+	bootstrapper := &serverbootstrap.Bootstrapper{
+		ConnectionPool: h.DockerPool,
+		ConnectionID:   generator.ServerConnectionID(teamID, serverID),
+		Host:           generator.ServerHost(*server),
+		User:           server.User,
+		Credentials:    credentials,
+		StreamChan:     streamChan,
+	}
+	bootstrapper.Bootstrap(r.Context())
+
+	utils.StreamBootstrapOutput(r.Context(), w, &streamChan)
+}
@@ -10,10 +10,10 @@ import (
 	"github.com/segmentio/ksuid"
 	"go.uber.org/zap"
 
-	"github.com/yorukot/starker/internal/handler/server/utils"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
 	"github.com/yorukot/starker/pkg/response"
 )
 
@@ -22,25 +22,40 @@ import (
 // +----------------------------------------------+
 
 type createServerRequest struct {
-	Name         string  `json:"name" validate:"required,min=3,max=255"`
-	Description  *string `json:"description,omitempty" validate:"omitempty,max=500"`
-	IP           string  `json:"ip" validate:"required,ip"`
-	Port         string  `json:"port" validate:"required,min=1,max=5"`
-	User         string  `json:"user" validate:"required,min=1,max=255"`
-	PrivateKeyID string  `json:"private_key_id" validate:"required"`
+	Name             string                      `json:"name" validate:"required,min=3,max=255"`
+	Description      *string                     `json:"description,omitempty" validate:"omitempty,max=500"`
+	ConnectionType   models.ServerConnectionType `json:"connection_type,omitempty" validate:"omitempty,oneof=ssh local tcp agent"` // Defaults to ssh
+	IP               string                      `json:"ip,omitempty" validate:"omitempty,ip"`
+	Port             string                      `json:"port,omitempty" validate:"omitempty,min=1,max=5"`
+	User             string                      `json:"user,omitempty" validate:"omitempty,min=1,max=255"`
+	PrivateKeyID     string                      `json:"private_key_id,omitempty"`
+	TLSCACertificate *string                     `json:"tls_ca_certificate,omitempty" validate:"omitempty,max=16384"` // CA of the TCP Docker daemon
+	TLSCertificate   *string                     `json:"tls_certificate,omitempty" validate:"omitempty,max=16384"`    // Client certificate, its key is the private key
+	HostKey          *string                     `json:"host_key,omitempty" validate:"omitempty,max=16384"`           // Expected SSH host key, the first presented key is trusted when empty
+	JumpHostID       *string                     `json:"jump_host_id,omitempty" validate:"omitempty"`                 // Server of the team used as bastion to reach this server
+	Bootstrap        bool                        `json:"bootstrap,omitempty"`                                         // Only require SSH access, Docker is installed with the bootstrap endpoint afterwards
 }
 
 // CreateServer godoc
 // @Summary Create a new server
 // @Description Creates a new server configuration for SSH connections within a team. Tests the connection before saving.
+// @Description When bootstrap is set only the SSH connection is tested, so servers without Docker can be added and bootstrapped afterwards.
+// @Description The SSH host key is pinned on creation. When host_key is given the server has to present that key, otherwise the first presented key is trusted.
+// @Description With jump_host_id the server is reached through that server of the team, the IP has to be reachable from the bastion.
+// @Description connection_type selects how the Docker daemon is reached: ssh (default) needs ip, port, user and private_key_id,
+// @Description tcp needs ip, port, private_key_id as key of tls_certificate and tls_ca_certificate,
+// @Description local uses the Docker socket of the Starker host and is only available when LOCAL_DOCKER_SOCKET is configured.
+// @Description agent is for servers behind NAT that run starker-agent, the response contains the agent_token the agent connects with.
+// @Description The token is only returned once, agent servers are not tested on creation and come online when the agent connects.
 // @Tags server
 // @Accept json
 // @Produce json
 // @Param teamID path string true "Team ID"
 // @Param request body createServerRequest true "Server creation request"
 // @Success 201 {object} response.SuccessResponse{data=models.Server} "Server created successfully"
-// @Failure 400 {object} response.ErrorResponse "Invalid request body, team access denied, or server connection failed"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, invalid connection settings, invalid jump host, team access denied, or server connection failed"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the given one"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
 // @Router /teams/{teamID}/servers [post]
 // @Security BearerAuth
@@ -85,23 +100,48 @@ func (h *ServerHandler) CreateServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify the private key exists and belongs to the team
-	privateKey, err := repository.GetPrivateKeyByID(r.Context(), tx, createServerRequest.PrivateKeyID, teamID)
-	if err != nil {
-		zap.L().Error("Failed to verify private key", zap.Error(err))
-		response.RespondWithError(w, http.StatusBadRequest, "Private key not found or access denied", "PRIVATE_KEY_NOT_FOUND")
-		return
-	}
-
 	// Generate the server
 	server := generateServer(createServerRequest, teamID)
 
-	// Test the server connection before creating it
-	if err = utils.TestServerConnection(r.Context(), server, *privateKey, h.DockerPool); err != nil {
-		zap.L().Error("Failed to test server connection", zap.Error(err))
-		response.RespondWithError(w, http.StatusBadRequest, "Failed to connect to server with provided credentials", "SERVER_CONNECTION_FAILED")
+	// Normalize the expected host key
+	if createServerRequest.HostKey != nil && *createServerRequest.HostKey != "" {
+		hostKey, err := connection.ParseHostKey(*createServerRequest.HostKey)
+		if err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Invalid host key", "INVALID_HOST_KEY")
+			return
+		}
+		server.HostKey = &[]string{connection.MarshalHostKey(hostKey)}[0]
+	}
+
+	// Check that the server has the settings its connection type needs
+	if err := validateServerConnection(server); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_SERVER_CONNECTION")
 		return
 	}
+	if createServerRequest.Bootstrap && server.ConnectionType != models.ServerConnectionSSH {
+		response.RespondWithError(w, http.StatusBadRequest, "Only SSH servers can be bootstrapped", "INVALID_SERVER_CONNECTION")
+		return
+	}
+
+	// Agents dial Starker themselves, so they get a token instead of a connection test
+	if server.ConnectionType == models.ServerConnectionAgent {
+		if err := issueAgentToken(&server); err != nil {
+			zap.L().Error("Failed to generate agent token", zap.Error(err))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to generate agent token", "FAILED_TO_GENERATE_AGENT_TOKEN")
+			return
+		}
+	} else {
+		// Test the server connection before creating it, servers that will be bootstrapped only need SSH
+		hostKey, ok := h.testServerConnection(w, r, tx, server, createServerRequest.Bootstrap)
+		if !ok {
+			return
+		}
+
+		// Pin the host key presented during the test, servers reached without SSH have none
+		if hostKey != "" {
+			server.HostKey = &hostKey
+		}
+	}
 
 	// Create the server
 	if err = repository.CreateServer(r.Context(), tx, server); err != nil {
@@ -121,16 +161,26 @@ func (h *ServerHandler) CreateServer(w http.ResponseWriter, r *http.Request) {
 func generateServer(createServerRequest createServerRequest, teamID string) models.Server {
 	now := time.Now()
 
+	connectionType := createServerRequest.ConnectionType
+	if connectionType == "" {
+		connectionType = models.ServerConnectionSSH
+	}
+
 	return models.Server{
-		ID:           ksuid.New().String(),
-		TeamID:       teamID,
-		Name:         createServerRequest.Name,
-		Description:  createServerRequest.Description,
-		IP:           createServerRequest.IP,
-		Port:         createServerRequest.Port,
-		User:         createServerRequest.User,
-		PrivateKeyID: createServerRequest.PrivateKeyID,
-		CreatedAt:    now,
-		UpdatedAt:    now,
+		ID:               ksuid.New().String(),
+		TeamID:           teamID,
+		Name:             createServerRequest.Name,
+		Description:      createServerRequest.Description,
+		IP:               createServerRequest.IP,
+		Port:             createServerRequest.Port,
+		User:             createServerRequest.User,
+		PrivateKeyID:     createServerRequest.PrivateKeyID,
+		JumpHostID:       createServerRequest.JumpHostID,
+		ConnectionType:   connectionType,
+		Status:           models.ServerStatusUnknown,
+		TLSCACertificate: createServerRequest.TLSCACertificate,
+		TLSCertificate:   createServerRequest.TLSCertificate,
+		CreatedAt:        now,
+		UpdatedAt:        now,
 	}
 }
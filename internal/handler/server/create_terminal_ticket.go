@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/wsticket"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Create Terminal Ticket                       |
+// +----------------------------------------------+
+
+// CreateTerminalTicket godoc
+// @Summary Issue a ticket to open an SSH terminal to a server
+// @Description Browsers can not send the Authorization header on a WebSocket handshake. The ticket authenticates one terminal WebSocket of the server instead,
+// @Description passed as ticket query parameter or offered as subprotocol next to starker.ticket. It can be used once and expires after 30 seconds.
+// @Tags server
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 201 {object} response.SuccessResponse{data=wsticket.Ticket} "Ticket issued successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or server not connected over SSH"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/terminal/ticket [post]
+// @Security BearerAuth
+func (h *ServerHandler) CreateTerminalTicket(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get the user ID from the context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Get the server from the database
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+	if server.ConnectionType != models.ServerConnectionSSH {
+		response.RespondWithError(w, http.StatusBadRequest, "Terminals are only available for servers connected over SSH", "SSH_NOT_AVAILABLE")
+		return
+	}
+
+	ticket, err := wsticket.Issue(r.Context(), tx, userID, wsticket.ResourceServerTerminal, server.ID)
+	if err != nil {
+		zap.L().Error("Failed to issue terminal ticket", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to issue ticket", "FAILED_TO_ISSUE_TICKET")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusCreated, ticket)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
 	"github.com/yorukot/starker/pkg/response"
 )
 
@@ -27,6 +30,7 @@ import (
 // @Failure 400 {object} response.ErrorResponse "Team access denied"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
 // @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "Server is the jump host of other servers"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
 // @Router /teams/{teamID}/servers/{serverID} [delete]
 // @Security BearerAuth
@@ -72,6 +76,32 @@ func (h *ServerHandler) DeleteServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Servers behind this server would lose their connection
+	jumpHostUsers, err := repository.CountServersUsingJumpHost(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to check jump host usage", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check jump host usage", "FAILED_TO_CHECK_JUMP_HOST_USAGE")
+		return
+	}
+	if jumpHostUsers > 0 {
+		response.RespondWithError(w, http.StatusConflict, fmt.Sprintf("Server is the jump host of %d other servers", jumpHostUsers), "SERVER_IS_JUMP_HOST")
+		return
+	}
+
+	// Delete the collected host metrics
+	if err = repository.DeleteServerMetrics(r.Context(), tx, serverID); err != nil {
+		zap.L().Error("Failed to delete server metrics", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to delete server metrics", "FAILED_TO_DELETE_SERVER_METRICS")
+		return
+	}
+
+	// Delete the records of orphaned resources found on the server
+	if err = repository.DeleteOrphanedResources(r.Context(), tx, serverID); err != nil {
+		zap.L().Error("Failed to delete orphaned resources", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to delete orphaned resources", "FAILED_TO_DELETE_ORPHANED_RESOURCES")
+		return
+	}
+
 	// Delete the server
 	if err = repository.DeleteServerByID(r.Context(), tx, serverID, teamID); err != nil {
 		zap.L().Error("Failed to delete server", zap.Error(err))
@@ -82,6 +112,10 @@ func (h *ServerHandler) DeleteServer(w http.ResponseWriter, r *http.Request) {
 	// Commit the transaction
 	repository.CommitTransaction(tx, r.Context())
 
+	// Close the pooled connection of the deleted server
+	h.DockerPool.RemoveConnection(generator.ServerConnectionID(teamID, serverID))
+	connection.DisconnectAgent(serverID)
+
 	// Response
 	response.RespondWithJSON(w, http.StatusOK, nil)
 }
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Download Terminal Recording                  |
+// +----------------------------------------------+
+
+// DownloadTerminalRecording godoc
+// @Summary Download the recording of a terminal session
+// @Description Downloads a recorded SSH terminal session in the asciicast v2 format, playable with asciinema
+// @Tags server
+// @Produce application/x-asciicast
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Param sessionID path string true "Terminal session ID (audit log ID)"
+// @Success 200 {file} file "Asciicast recording"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Session or recording not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/terminal/sessions/{sessionID}/recording [get]
+// @Security BearerAuth
+func (h *ServerHandler) DownloadTerminalRecording(w http.ResponseWriter, r *http.Request) {
+	// Get the URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+	sessionID := chi.URLParam(r, "sessionID")
+
+	// Get the user ID from the context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Get the session and make sure it belongs to the server
+	session, err := repository.GetAuditLogByID(r.Context(), tx, sessionID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get terminal session", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get terminal session", "FAILED_TO_GET_TERMINAL_SESSION")
+		return
+	}
+	if session == nil || session.Action != models.AuditActionServerTerminal || session.ResourceID != serverID {
+		response.RespondWithError(w, http.StatusNotFound, "Terminal session not found", "TERMINAL_SESSION_NOT_FOUND")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	// Open the recording file
+	recordingFile, err := os.Open(recordingPath(session.ID))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			response.RespondWithError(w, http.StatusNotFound, "Session was not recorded", "RECORDING_NOT_FOUND")
+			return
+		}
+		zap.L().Error("Failed to open recording file", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to open recording", "FAILED_TO_OPEN_RECORDING")
+		return
+	}
+	defer recordingFile.Close()
+
+	stat, err := recordingFile.Stat()
+	if err != nil {
+		zap.L().Error("Failed to stat recording file", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to open recording", "FAILED_TO_OPEN_RECORDING")
+		return
+	}
+
+	// Serve the recording as a download
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", session.ID+".cast"))
+	http.ServeContent(w, r, session.ID+".cast", stat.ModTime(), recordingFile)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/composeadopt"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Get Compose Projects                         |
+// +----------------------------------------------+
+
+// GetComposeProjects godoc
+// @Summary List the compose projects Starker does not manage
+// @Description Lists the projects started with docker compose on the server, found by their com.docker.compose.project label. Every project contains a compose file reconstructed from its containers, it only covers the settings Starker deploys and the warnings list what is not carried over.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {object} response.SuccessResponse{data=[]composeadopt.Project} "Compose projects retrieved successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/compose-projects [get]
+// @Security BearerAuth
+func (h *ServerHandler) GetComposeProjects(w http.ResponseWriter, r *http.Request) {
+	// Get the Docker client of the server
+	_, dockerClient, ok := h.getServerDockerClient(w, r)
+	if !ok {
+		return
+	}
+
+	// Find the compose projects and reconstruct their compose files
+	projects, err := composeadopt.ListProjects(r.Context(), dockerClient)
+	if err != nil {
+		zap.L().Error("Failed to list compose projects", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to list compose projects", "FAILED_TO_LIST_COMPOSE_PROJECTS")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, projects)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/serverdocker"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Get Docker Images                            |
+// +----------------------------------------------+
+
+// GetDockerImages godoc
+// @Summary List the images on a server
+// @Description Lists all images on the server. service_ids lists the Starker services whose containers use the image.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {object} response.SuccessResponse{data=[]serverdocker.Image} "Images retrieved successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/docker/images [get]
+// @Security BearerAuth
+func (h *ServerHandler) GetDockerImages(w http.ResponseWriter, r *http.Request) {
+	// Get the Docker client of the server
+	_, dockerClient, ok := h.getServerDockerClient(w, r)
+	if !ok {
+		return
+	}
+
+	// List the images
+	images, err := serverdocker.ListImages(r.Context(), dockerClient)
+	if err != nil {
+		zap.L().Error("Failed to list images", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to list images", "FAILED_TO_LIST_IMAGES")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, images)
+}
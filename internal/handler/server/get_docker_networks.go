@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/serverdocker"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Get Docker Networks                          |
+// +----------------------------------------------+
+
+// GetDockerNetworks godoc
+// @Summary List the networks on a server
+// @Description Lists all networks on the server. service_id is set for networks of Starker services.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {object} response.SuccessResponse{data=[]serverdocker.Network} "Networks retrieved successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/docker/networks [get]
+// @Security BearerAuth
+func (h *ServerHandler) GetDockerNetworks(w http.ResponseWriter, r *http.Request) {
+	// Get the Docker client of the server
+	_, dockerClient, ok := h.getServerDockerClient(w, r)
+	if !ok {
+		return
+	}
+
+	// List the networks
+	networks, err := serverdocker.ListNetworks(r.Context(), dockerClient)
+	if err != nil {
+		zap.L().Error("Failed to list networks", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to list networks", "FAILED_TO_LIST_NETWORKS")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, networks)
+}
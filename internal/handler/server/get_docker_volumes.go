@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/serverdocker"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Get Docker Volumes                           |
+// +----------------------------------------------+
+
+// GetDockerVolumes godoc
+// @Summary List the volumes on a server
+// @Description Lists all volumes on the server. service_id is set for volumes of Starker services.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {object} response.SuccessResponse{data=[]serverdocker.Volume} "Volumes retrieved successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/docker/volumes [get]
+// @Security BearerAuth
+func (h *ServerHandler) GetDockerVolumes(w http.ResponseWriter, r *http.Request) {
+	// Get the Docker client of the server
+	_, dockerClient, ok := h.getServerDockerClient(w, r)
+	if !ok {
+		return
+	}
+
+	// List the volumes
+	volumes, err := serverdocker.ListVolumes(r.Context(), dockerClient)
+	if err != nil {
+		zap.L().Error("Failed to list volumes", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to list volumes", "FAILED_TO_LIST_VOLUMES")
+		return
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, volumes)
+}
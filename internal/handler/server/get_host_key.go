@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Get Host Key                                 |
+// +----------------------------------------------+
+
+// hostKeyResponse compares the pinned host key with the key the server currently presents
+type hostKeyResponse struct {
+	PinnedHostKey        *string `json:"pinned_host_key,omitempty" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI..."` // Host key pinned for the server
+	PinnedFingerprint    *string `json:"pinned_fingerprint,omitempty" example:"SHA256:abc123..."`                      // Fingerprint of the pinned host key
+	PresentedHostKey     string  `json:"presented_host_key" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI..."`        // Host key the server presents now
+	PresentedFingerprint string  `json:"presented_fingerprint" example:"SHA256:abc123..."`                             // Fingerprint of the presented host key
+	Matches              bool    `json:"matches" example:"true"`                                                       // Whether the presented key is the pinned key
+}
+
+// GetHostKey godoc
+// @Summary Get the server host key
+// @Description Reads the SSH host key the server presents and compares it with the pinned host key.
+// @Description Use the presented fingerprint to confirm a rotated host key.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {object} response.SuccessResponse{data=hostKeyResponse} "Host key retrieved successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or server not connected over SSH"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/host-key [get]
+// @Security BearerAuth
+func (h *ServerHandler) GetHostKey(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get user ID from context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Get the server from the database
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+	if server.ConnectionType != models.ServerConnectionSSH {
+		response.RespondWithError(w, http.StatusBadRequest, "Host keys are only used by servers connected over SSH", "SSH_NOT_AVAILABLE")
+		return
+	}
+	// Servers behind a bastion are scanned through their jump hosts
+	jumpHost, err := generator.ServerJumpHost(r.Context(), tx, *server)
+	if err != nil {
+		zap.L().Error("Failed to resolve jump host", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve jump host", "FAILED_TO_RESOLVE_JUMP_HOST")
+		return
+	}
+	repository.CommitTransaction(tx, r.Context())
+
+	// Read the host key the server presents now
+	presentedHostKey, err := h.DockerPool.ScanHostKey(generator.ServerHost(*server), jumpHost)
+	if err != nil {
+		zap.L().Error("Failed to scan host key", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to read the server host key", "FAILED_TO_SCAN_HOST_KEY")
+		return
+	}
+	presentedFingerprint, err := connection.HostKeyFingerprint(presentedHostKey)
+	if err != nil {
+		zap.L().Error("Failed to fingerprint host key", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to read the server host key", "FAILED_TO_SCAN_HOST_KEY")
+		return
+	}
+
+	hostKey := hostKeyResponse{
+		PinnedHostKey:        server.HostKey,
+		PresentedHostKey:     presentedHostKey,
+		PresentedFingerprint: presentedFingerprint,
+	}
+	if server.HostKey != nil {
+		if pinnedFingerprint, err := connection.HostKeyFingerprint(*server.HostKey); err == nil {
+			hostKey.PinnedFingerprint = &pinnedFingerprint
+			hostKey.Matches = pinnedFingerprint == presentedFingerprint
+		}
+	}
+
+	response.RespondWithJSON(w, http.StatusOK, hostKey)
+}
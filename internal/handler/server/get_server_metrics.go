@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// maxMetricsRange is the longest time range that can be requested at once
+const maxMetricsRange = 7 * 24 * time.Hour
+
+// +----------------------------------------------+
+// | Get Server Metrics                           |
+// +----------------------------------------------+
+
+// GetServerMetrics godoc
+// @Summary Get host metrics of a server
+// @Description Returns the collected CPU load, memory, disk and Docker disk usage samples of a server in a time range.
+// @Description Without parameters the last hour is returned, the range can be at most 7 days.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Param from query string false "Start of the range (RFC3339)" example("2023-01-01T00:00:00Z")
+// @Param to query string false "End of the range (RFC3339)" example("2023-01-01T01:00:00Z")
+// @Success 200 {object} response.SuccessResponse{data=[]models.ServerMetrics} "Metrics samples, oldest first"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or invalid range"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/metrics [get]
+// @Security BearerAuth
+func (h *ServerHandler) GetServerMetrics(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get the user ID from the context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Parse the time range
+	to := time.Now()
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Invalid to timestamp format", "INVALID_TO_TIMESTAMP")
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(-time.Hour)
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Invalid from timestamp format", "INVALID_FROM_TIMESTAMP")
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) || to.Sub(from) > maxMetricsRange {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid time range, from must be before to and the range at most 7 days", "INVALID_METRICS_RANGE")
+		return
+	}
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Check that the server exists
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+
+	// Get the metrics samples
+	metrics, err := repository.GetServerMetrics(r.Context(), tx, serverID, from, to)
+	if err != nil {
+		zap.L().Error("Failed to get server metrics", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server metrics", "FAILED_TO_GET_SERVER_METRICS")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	// Return the metrics
+	response.RespondWithJSON(w, http.StatusOK, metrics)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Get Terminal Sessions                        |
+// +----------------------------------------------+
+
+// GetTerminalSessions godoc
+// @Summary List terminal sessions of a server
+// @Description Lists the SSH terminal sessions opened on a server from the audit log, newest first.
+// @Description Sessions with metadata recorded=true can be downloaded as asciicast files.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {object} response.SuccessResponse{data=[]models.AuditLog} "Terminal sessions retrieved successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/terminal/sessions [get]
+// @Security BearerAuth
+func (h *ServerHandler) GetTerminalSessions(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get the user ID from the context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Check that the server exists
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+
+	// Get the terminal sessions from the audit log
+	sessions, err := repository.GetAuditLogsByResource(r.Context(), tx, teamID, serverID, models.AuditActionServerTerminal)
+	if err != nil {
+		zap.L().Error("Failed to get terminal sessions", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get terminal sessions", "FAILED_TO_GET_TERMINAL_SESSIONS")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	// Return the sessions
+	response.RespondWithJSON(w, http.StatusOK, sessions)
+}
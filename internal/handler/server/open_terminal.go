@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/yorukot/starker/internal/config"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+	"github.com/yorukot/starker/pkg/terminal"
+)
+
+// terminalType is the TERM value requested for the PTY
+const terminalType = "xterm-256color"
+
+// +----------------------------------------------+
+// | Open Terminal                                |
+// +----------------------------------------------+
+
+// OpenTerminal godoc
+// @Summary Open an SSH terminal to a server over WebSocket
+// @Description Upgrades the connection to a WebSocket and starts a login shell in a PTY over the pooled SSH connection.
+// @Description The server sends terminal output as binary frames. The client sends JSON messages:
+// @Description {"type":"input","data":"ls\r"} for keystrokes and {"type":"resize","cols":120,"rows":40} to resize.
+// @Description When the shell can not be opened, for example because the host key changed, the reason is sent as text message and the WebSocket is closed.
+// @Description The session is closed after TERMINAL_IDLE_TIMEOUT seconds without input. With record=true the session
+// @Description is saved in the asciicast v2 format and can be downloaded from the recordings endpoint. Browsers authenticate with a ticket
+// @Description from the terminal ticket endpoint, passed as ticket query parameter or offered as subprotocol next to starker.ticket. The Origin has to be the frontend.
+// @Tags server
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Param cols query int false "Initial terminal width" default(80)
+// @Param rows query int false "Initial terminal height" default(24)
+// @Param record query bool false "Record the session" default(false)
+// @Param ticket query string false "WebSocket ticket, instead of the Authorization header"
+// @Success 101 {string} string "Switching protocols to WebSocket"
+// @Failure 400 {object} response.ErrorResponse "Team access denied, invalid parameters or server not connected over SSH"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/terminal [get]
+// @Security BearerAuth
+func (h *ServerHandler) OpenTerminal(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get the user ID from the context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Parse the terminal size
+	cols, rows := 80, 24
+	if colsStr, rowsStr := r.URL.Query().Get("cols"), r.URL.Query().Get("rows"); colsStr != "" || rowsStr != "" {
+		var colsErr, rowsErr error
+		cols, colsErr = strconv.Atoi(colsStr)
+		rows, rowsErr = strconv.Atoi(rowsStr)
+		if colsErr != nil || rowsErr != nil || cols <= 0 || rows <= 0 {
+			response.RespondWithError(w, http.StatusBadRequest, "Invalid terminal size", "INVALID_TERMINAL_SIZE")
+			return
+		}
+	}
+	record := r.URL.Query().Get("record") == "true"
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Get the server from the database
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+	if server.ConnectionType != models.ServerConnectionSSH {
+		response.RespondWithError(w, http.StatusBadRequest, "Terminals are only available for servers connected over SSH", "SSH_NOT_AVAILABLE")
+		return
+	}
+
+	// Resolve the credentials, including the jump hosts in front of the server
+	credentials, err := generator.ServerCredentials(r.Context(), tx, *server)
+	if errors.Is(err, generator.ErrPrivateKeyNotFound) {
+		response.RespondWithError(w, http.StatusBadRequest, "Private key not found", "PRIVATE_KEY_NOT_FOUND")
+		return
+	}
+	if err != nil {
+		zap.L().Error("Failed to resolve server credentials", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve server credentials", "FAILED_TO_RESOLVE_CREDENTIALS")
+		return
+	}
+
+	// Commit the transaction since we're moving to streaming
+	repository.CommitTransaction(tx, r.Context())
+
+	// Upgrade to WebSocket first, the shell is only opened once there is a connection to bridge it to
+	websocket.Server{Handshake: middleware.WebSocketHandshake, Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		sshHost := generator.ServerHost(*server)
+		connectionID := generator.ServerConnectionID(teamID, serverID)
+		ptySession, err := h.DockerPool.OpenPTYSession(connectionID, sshHost, credentials, terminalType, cols, rows)
+		if errors.Is(err, connection.ErrHostKeyMismatch) {
+			websocket.Message.Send(ws, err.Error()+"\r\n")
+			return
+		}
+		if err != nil {
+			zap.L().Error("Failed to open terminal session", zap.Error(err))
+			websocket.Message.Send(ws, "Failed to open terminal session\r\n")
+			return
+		}
+		defer ptySession.Close()
+
+		// Record the session in the audit log
+		auditLog := models.AuditLog{
+			ID:           ksuid.New().String(),
+			TeamID:       teamID,
+			UserID:       userID,
+			Action:       models.AuditActionServerTerminal,
+			ResourceType: "server",
+			ResourceID:   server.ID,
+			Metadata: map[string]string{
+				"host":     sshHost,
+				"recorded": strconv.FormatBool(record),
+			},
+			IP:        &r.RemoteAddr,
+			CreatedAt: time.Now(),
+		}
+		if err := h.startTerminalAuditLog(r.Context(), auditLog); err != nil {
+			zap.L().Error("Failed to create audit log", zap.Error(err))
+			websocket.Message.Send(ws, "Failed to create audit log\r\n")
+			return
+		}
+
+		// Prepare the recording file, named after the audit log entry
+		var recorder *terminal.AsciicastRecorder
+		if record {
+			recordingFile, err := createRecordingFile(auditLog.ID)
+			if err != nil {
+				zap.L().Error("Failed to create recording file", zap.Error(err))
+				websocket.Message.Send(ws, "Failed to create recording file\r\n")
+				h.endTerminalAuditLog(auditLog.ID, "error", terminal.Stats{})
+				return
+			}
+			defer recordingFile.Close()
+
+			recorder, err = terminal.NewAsciicastRecorder(recordingFile, uint(cols), uint(rows), server.Name, terminalType)
+			if err != nil {
+				zap.L().Error("Failed to write recording header", zap.Error(err))
+				websocket.Message.Send(ws, "Failed to create recording file\r\n")
+				h.endTerminalAuditLog(auditLog.ID, "error", terminal.Stats{})
+				return
+			}
+		}
+
+		session := terminal.Session{
+			Stdin:  ptySession.Stdin,
+			Stdout: ptySession.Stdout,
+			Resize: func(cols, rows uint) error {
+				if recorder != nil {
+					recorder.Resize(cols, rows)
+				}
+				return ptySession.Resize(int(cols), int(rows))
+			},
+			IdleTimeout: time.Duration(config.Env().TerminalIdleTimeout) * time.Second,
+		}
+		if recorder != nil {
+			session.OnOutput = recorder.Output
+		}
+
+		stats, err := terminal.Bridge(ws, session)
+		reason := "closed"
+		if errors.Is(err, terminal.ErrIdleTimeout) {
+			reason = "idle_timeout"
+			websocket.Message.Send(ws, "\r\nSession closed after being idle\r\n")
+		} else if err != nil {
+			reason = "error"
+			zap.L().Warn("Terminal session ended with error", zap.String("audit_log_id", auditLog.ID), zap.Error(err))
+		}
+
+		ptySession.Close()
+		h.endTerminalAuditLog(auditLog.ID, reason, stats)
+	}}.ServeHTTP(w, r)
+}
+
+// createRecordingFile creates the asciicast file for a terminal session
+func createRecordingFile(auditLogID string) (*os.File, error) {
+	dir := config.Env().TerminalRecordingDir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(recordingPath(auditLogID), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+}
+
+// recordingPath returns the path of the asciicast file of a terminal session
+func recordingPath(auditLogID string) string {
+	return filepath.Join(config.Env().TerminalRecordingDir, auditLogID+".cast")
+}
+
+// startTerminalAuditLog records the start of a terminal session in the audit log
+func (h *ServerHandler) startTerminalAuditLog(ctx context.Context, auditLog models.AuditLog) error {
+	tx, err := repository.StartTransaction(h.DB, ctx)
+	if err != nil {
+		return err
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	if err := repository.CreateAuditLog(ctx, tx, auditLog); err != nil {
+		return err
+	}
+
+	repository.CommitTransaction(tx, ctx)
+	return nil
+}
+
+// endTerminalAuditLog stores the end time, reason and traffic of a terminal session in the audit log
+func (h *ServerHandler) endTerminalAuditLog(auditLogID, reason string, stats terminal.Stats) {
+	// The request context may already be canceled once the client disconnected
+	ctx := context.Background()
+
+	tx, err := repository.StartTransaction(h.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for terminal audit log", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	metadata := map[string]string{
+		"end_reason": reason,
+		"bytes_in":   strconv.FormatInt(stats.BytesIn, 10),
+		"bytes_out":  strconv.FormatInt(stats.BytesOut, 10),
+	}
+	if err := repository.EndAuditLog(ctx, tx, auditLogID, time.Now(), metadata); err != nil {
+		zap.L().Error("Failed to end terminal audit log", zap.String("audit_log_id", auditLogID), zap.Error(err))
+		return
+	}
+
+	repository.CommitTransaction(tx, ctx)
+}
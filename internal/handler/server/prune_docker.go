@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/serverdocker"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Prune Docker                                 |
+// +----------------------------------------------+
+
+// PruneDocker godoc
+// @Summary Prune unused Docker resources on a server
+// @Description Removes dangling images, stopped containers and unused networks that do not belong to a Starker service.
+// @Description Volumes and the resources of Starker services are never removed. With dry_run the resources are only listed,
+// @Description images and networks that only become unused by removing the listed containers show up in the next prune.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Param dry_run query bool false "Only list the Docker resources that would be removed" default(false)
+// @Success 200 {object} response.SuccessResponse{data=serverdocker.PruneResult} "Server pruned successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/docker/prune [post]
+// @Security BearerAuth
+func (h *ServerHandler) PruneDocker(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	// Get the Docker client of the server
+	server, dockerClient, ok := h.getServerDockerClient(w, r)
+	if !ok {
+		return
+	}
+
+	// Prune the server
+	result, err := serverdocker.Prune(r.Context(), dockerClient, dryRun)
+	if err != nil {
+		zap.L().Error("Failed to prune server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to prune server", "FAILED_TO_PRUNE_SERVER")
+		return
+	}
+	if dryRun {
+		response.RespondWithJSON(w, http.StatusOK, result)
+		return
+	}
+
+	// Record the prune in the audit log
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	auditLog := models.AuditLog{
+		ID:           ksuid.New().String(),
+		TeamID:       server.TeamID,
+		UserID:       r.Context().Value(middleware.UserIDKey).(string),
+		Action:       models.AuditActionServerDockerPrune,
+		ResourceType: "server",
+		ResourceID:   server.ID,
+		Metadata: map[string]string{
+			"containers":      strconv.Itoa(len(result.Containers)),
+			"images":          strconv.Itoa(len(result.Images)),
+			"networks":        strconv.Itoa(len(result.Networks)),
+			"space_reclaimed": strconv.FormatInt(result.SpaceReclaimed, 10),
+		},
+		IP:        &r.RemoteAddr,
+		CreatedAt: time.Now(),
+	}
+	if err := repository.CreateAuditLog(r.Context(), tx, auditLog); err != nil {
+		zap.L().Error("Failed to create audit log", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create audit log", "FAILED_TO_CREATE_AUDIT_LOG")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, result)
+}
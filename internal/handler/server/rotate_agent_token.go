@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// +----------------------------------------------+
+// | Rotate Agent Token                           |
+// +----------------------------------------------+
+
+// RotateAgentToken godoc
+// @Summary Rotate the agent token
+// @Description Generates a new token for the starker-agent of an agent server and returns it once in agent_token.
+// @Description The old token stops working and a connected agent is disconnected until it uses the new token.
+// @Tags server
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param serverID path string true "Server ID"
+// @Success 200 {object} response.SuccessResponse{data=models.Server} "Agent token rotated successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or server is not an agent server"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/servers/{serverID}/agent-token [post]
+// @Security BearerAuth
+func (h *ServerHandler) RotateAgentToken(w http.ResponseWriter, r *http.Request) {
+	// Get the team ID and server ID from the URL
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get user ID from context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Get the server from the database
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+	if server.ConnectionType != models.ServerConnectionAgent {
+		response.RespondWithError(w, http.StatusBadRequest, "Only agent servers have an agent token", "NOT_AN_AGENT_SERVER")
+		return
+	}
+
+	// Replace the token
+	if err := issueAgentToken(server); err != nil {
+		zap.L().Error("Failed to generate agent token", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to generate agent token", "FAILED_TO_GENERATE_AGENT_TOKEN")
+		return
+	}
+	if err = repository.UpdateServerAgentTokenHash(r.Context(), tx, serverID, teamID, *server.AgentTokenHash); err != nil {
+		zap.L().Error("Failed to update agent token", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to update agent token", "FAILED_TO_UPDATE_AGENT_TOKEN")
+		return
+	}
+
+	// Commit the transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	// The agent has to reconnect with the new token
+	connection.DisconnectAgent(serverID)
+
+	// Return the server with the new token
+	response.RespondWithJSON(w, http.StatusOK, server)
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/docker/docker/client"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/config"
+	"github.com/yorukot/starker/internal/handler/server/utils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/encrypt"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// validateServerConnection checks that a server has exactly the settings its connection type needs
+func validateServerConnection(server models.Server) error {
+	switch server.ConnectionType {
+	case models.ServerConnectionSSH:
+		if server.IP == "" || server.Port == "" || server.User == "" || server.PrivateKeyID == "" {
+			return fmt.Errorf("SSH servers need an ip, port, user and private key")
+		}
+		if server.TLSCACertificate != nil || server.TLSCertificate != nil {
+			return fmt.Errorf("TLS certificates are only used by TCP servers")
+		}
+	case models.ServerConnectionTCP:
+		if server.IP == "" || server.Port == "" || server.PrivateKeyID == "" {
+			return fmt.Errorf("TCP servers need an ip, port and the private key of the TLS client certificate")
+		}
+		if server.TLSCACertificate == nil || server.TLSCertificate == nil {
+			return fmt.Errorf("TCP servers need a TLS CA certificate and client certificate")
+		}
+		if server.JumpHostID != nil {
+			return fmt.Errorf("only SSH servers can be reached through a jump host")
+		}
+	case models.ServerConnectionLocal:
+		if config.Env().LocalDockerSocket == "" {
+			return fmt.Errorf("local servers are disabled, set LOCAL_DOCKER_SOCKET to enable them")
+		}
+		if server.PrivateKeyID != "" || server.JumpHostID != nil || server.TLSCACertificate != nil || server.TLSCertificate != nil {
+			return fmt.Errorf("local servers do not use a private key, jump host or TLS certificates")
+		}
+	case models.ServerConnectionAgent:
+		if server.PrivateKeyID != "" || server.JumpHostID != nil || server.TLSCACertificate != nil || server.TLSCertificate != nil {
+			return fmt.Errorf("agent servers do not use a private key, jump host or TLS certificates")
+		}
+	default:
+		return fmt.Errorf("unknown connection type %q", server.ConnectionType)
+	}
+
+	if server.ConnectionType != models.ServerConnectionSSH && server.HostKey != nil {
+		return fmt.Errorf("host keys are only used by SSH servers")
+	}
+
+	return nil
+}
+
+// testServerConnection resolves the credentials of a server and tests its connection.
+// With sshOnly only the SSH connection is tested. It returns the presented host key,
+// on failure the error response is written and false is returned.
+func (h *ServerHandler) testServerConnection(w http.ResponseWriter, r *http.Request, tx pgx.Tx, server models.Server, sshOnly bool) (string, bool) {
+	// Resolve the credentials, including the jump hosts in front of the server
+	credentials, err := generator.ServerCredentials(r.Context(), tx, server)
+	if errors.Is(err, generator.ErrPrivateKeyNotFound) {
+		response.RespondWithError(w, http.StatusBadRequest, "Private key not found or access denied", "PRIVATE_KEY_NOT_FOUND")
+		return "", false
+	}
+	if errors.Is(err, generator.ErrJumpHostNotFound) || errors.Is(err, generator.ErrInvalidJumpHost) {
+		response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_JUMP_HOST")
+		return "", false
+	}
+	if err != nil {
+		zap.L().Error("Failed to resolve server credentials", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve server credentials", "FAILED_TO_RESOLVE_CREDENTIALS")
+		return "", false
+	}
+
+	var hostKey string
+	if sshOnly {
+		hostKey, err = utils.TestServerSSHConnection(server, credentials, h.DockerPool)
+	} else {
+		hostKey, err = utils.TestServerConnection(r.Context(), server, credentials, h.DockerPool)
+	}
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return "", false
+	}
+	if err != nil {
+		zap.L().Error("Failed to test server connection", zap.Error(err))
+		response.RespondWithError(w, http.StatusBadRequest, "Failed to connect to server with provided credentials", "SERVER_CONNECTION_FAILED")
+		return "", false
+	}
+
+	return hostKey, true
+}
+
+// issueAgentToken generates a new agent token for the server.
+// Only the hash is stored, the token itself is returned once in the response.
+func issueAgentToken(server *models.Server) error {
+	token, err := encrypt.GenerateAgentToken()
+	if err != nil {
+		return err
+	}
+	server.AgentTokenHash = &[]string{encrypt.HashAgentToken(token)}[0]
+	server.AgentToken = &token
+	return nil
+}
+
+// getServerDockerClient checks the team access of the user, loads the server of the URL and returns
+// its pooled Docker client. On failure the error response is written and false is returned.
+func (h *ServerHandler) getServerDockerClient(w http.ResponseWriter, r *http.Request) (*models.Server, *client.Client, bool) {
+	// Get the team ID and server ID from the URL
+	teamID := chi.URLParam(r, "teamID")
+	serverID := chi.URLParam(r, "serverID")
+
+	// Get user ID from context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return nil, nil, false
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return nil, nil, false
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return nil, nil, false
+	}
+
+	// Get the server from the database
+	server, err := repository.GetServerByID(r.Context(), tx, serverID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return nil, nil, false
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Server not found", "SERVER_NOT_FOUND")
+		return nil, nil, false
+	}
+
+	// Resolve the credentials, including the jump hosts in front of the server
+	credentials, err := generator.ServerCredentials(r.Context(), tx, *server)
+	if errors.Is(err, generator.ErrPrivateKeyNotFound) {
+		response.RespondWithError(w, http.StatusBadRequest, "Private key not found or access denied", "PRIVATE_KEY_NOT_FOUND")
+		return nil, nil, false
+	}
+	if err != nil {
+		zap.L().Error("Failed to resolve server credentials", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve server credentials", "FAILED_TO_RESOLVE_CREDENTIALS")
+		return nil, nil, false
+	}
+	repository.CommitTransaction(tx, r.Context())
+
+	// Get the pooled Docker client of the server
+	dockerClient, err := h.DockerPool.GetDockerConnection(generator.ServerConnectionID(teamID, serverID), generator.ServerHost(*server), credentials)
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return nil, nil, false
+	}
+	if err != nil {
+		zap.L().Error("Failed to get Docker connection", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get Docker connection", "FAILED_TO_GET_DOCKER_CONNECTION")
+		return nil, nil, false
+	}
+
+	return server, dockerClient, true
+}
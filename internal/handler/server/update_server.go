@@ -9,10 +9,11 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
-	"github.com/yorukot/starker/internal/handler/server/utils"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
 	"github.com/yorukot/starker/pkg/response"
 )
 
@@ -21,17 +22,26 @@ import (
 // +----------------------------------------------+
 
 type updateServerRequest struct {
-	Name         *string `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
-	Description  *string `json:"description,omitempty" validate:"omitempty,max=500"`
-	IP           *string `json:"ip,omitempty" validate:"omitempty,ip"`
-	Port         *string `json:"port,omitempty" validate:"omitempty,min=1,max=5"`
-	User         *string `json:"user,omitempty" validate:"omitempty,min=1,max=255"`
-	PrivateKeyID *string `json:"private_key_id,omitempty" validate:"omitempty"`
+	Name             *string                      `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
+	Description      *string                      `json:"description,omitempty" validate:"omitempty,max=500"`
+	ConnectionType   *models.ServerConnectionType `json:"connection_type,omitempty" validate:"omitempty,oneof=ssh local tcp agent"`
+	IP               *string                      `json:"ip,omitempty" validate:"omitempty,ip"`
+	Port             *string                      `json:"port,omitempty" validate:"omitempty,min=1,max=5"`
+	User             *string                      `json:"user,omitempty" validate:"omitempty,min=1,max=255"`
+	PrivateKeyID     *string                      `json:"private_key_id,omitempty" validate:"omitempty"`               // An empty string removes the key of a local server
+	TLSCACertificate *string                      `json:"tls_ca_certificate,omitempty" validate:"omitempty,max=16384"` // An empty string removes the certificate
+	TLSCertificate   *string                      `json:"tls_certificate,omitempty" validate:"omitempty,max=16384"`    // An empty string removes the certificate
+	HostKey          *string                      `json:"host_key,omitempty" validate:"omitempty,max=16384"`           // Expected SSH host key, replaces the pinned key
+	JumpHostID       *string                      `json:"jump_host_id,omitempty" validate:"omitempty"`                 // Server of the team used as bastion, an empty string connects directly
 }
 
 // UpdateServer godoc
 // @Summary Update a server
-// @Description Updates an existing server configuration within a team
+// @Description Updates an existing server configuration within a team.
+// @Description The pinned SSH host key is kept unless host_key is given or the IP or port changes, then the first presented key is trusted.
+// @Description jump_host_id sets the server of the team the server is reached through, an empty string removes the jump host.
+// @Description connection_type switches between ssh, tcp, local and agent, the updated server needs the same settings as on creation.
+// @Description A server that becomes an agent server returns its agent_token once.
 // @Tags server
 // @Accept json
 // @Produce json
@@ -39,9 +49,10 @@ type updateServerRequest struct {
 // @Param serverID path string true "Server ID"
 // @Param request body updateServerRequest true "Server update request"
 // @Success 200 {object} response.SuccessResponse{data=models.Server} "Server updated successfully"
-// @Failure 400 {object} response.ErrorResponse "Invalid request body or team access denied"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, invalid connection settings, invalid jump host or team access denied"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
 // @Failure 404 {object} response.ErrorResponse "Server not found"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
 // @Router /teams/{teamID}/servers/{serverID} [put]
 // @Security BearerAuth
@@ -99,36 +110,50 @@ func (h *ServerHandler) UpdateServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Determine which private key to use for connection testing
-	var privateKeyForTest *models.PrivateKey
-	if updateServerRequest.PrivateKeyID != nil {
-		// If private key ID is being updated, verify the new private key exists and belongs to the team
-		privateKeyForTest, err = repository.GetPrivateKeyByID(r.Context(), tx, *updateServerRequest.PrivateKeyID, teamID)
-		if err != nil {
-			zap.L().Error("Failed to verify private key", zap.Error(err))
-			response.RespondWithError(w, http.StatusBadRequest, "Private key not found or access denied", "PRIVATE_KEY_NOT_FOUND")
-			return
-		}
-	} else {
-		// Use the current private key
-		privateKeyForTest, err = repository.GetPrivateKeyByID(r.Context(), tx, currentServer.PrivateKeyID, teamID)
+	// Create a test server object with updated values
+	testServer := *currentServer
+
+	newServer := updateServerFromRequest(testServer, updateServerRequest)
+
+	// A new address is a different machine, so the pinned host key no longer applies
+	if newServer.IP != currentServer.IP || newServer.Port != currentServer.Port || newServer.ConnectionType != currentServer.ConnectionType {
+		newServer.HostKey = nil
+	}
+	if updateServerRequest.HostKey != nil && *updateServerRequest.HostKey != "" {
+		hostKey, err := connection.ParseHostKey(*updateServerRequest.HostKey)
 		if err != nil {
-			zap.L().Error("Failed to get current private key", zap.Error(err))
-			response.RespondWithError(w, http.StatusInternalServerError, "Failed to get current private key", "PRIVATE_KEY_NOT_FOUND")
+			response.RespondWithError(w, http.StatusBadRequest, "Invalid host key", "INVALID_HOST_KEY")
 			return
 		}
+		newServer.HostKey = &[]string{connection.MarshalHostKey(hostKey)}[0]
 	}
 
-	// Create a test server object with updated values
-	testServer := *currentServer
+	// Check that the updated server has the settings its connection type needs
+	if err := validateServerConnection(newServer); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_SERVER_CONNECTION")
+		return
+	}
 
-	newServer := updateServerFromRequest(testServer, updateServerRequest)
+	if newServer.ConnectionType == models.ServerConnectionAgent {
+		// Agents dial Starker themselves, a server that becomes an agent server gets its token
+		if currentServer.ConnectionType != models.ServerConnectionAgent {
+			if err := issueAgentToken(&newServer); err != nil {
+				zap.L().Error("Failed to generate agent token", zap.Error(err))
+				response.RespondWithError(w, http.StatusInternalServerError, "Failed to generate agent token", "FAILED_TO_GENERATE_AGENT_TOKEN")
+				return
+			}
+		}
+	} else {
+		newServer.AgentTokenHash = nil
 
-	// Test the server connection before updating it
-	if err = utils.TestServerConnection(r.Context(), newServer, *privateKeyForTest, h.DockerPool); err != nil {
-		zap.L().Error("Failed to test server connection", zap.Error(err))
-		response.RespondWithError(w, http.StatusBadRequest, "Failed to connect to server with provided credentials", "SERVER_CONNECTION_FAILED")
-		return
+		// Test the server connection before updating it
+		hostKey, ok := h.testServerConnection(w, r, tx, newServer, false)
+		if !ok {
+			return
+		}
+		if hostKey != "" {
+			newServer.HostKey = &hostKey
+		}
 	}
 
 	// Update the server in the database
@@ -148,6 +173,12 @@ func (h *ServerHandler) UpdateServer(w http.ResponseWriter, r *http.Request) {
 	// Commit the transaction
 	repository.CommitTransaction(tx, r.Context())
 
+	// Drop the pooled connection so the next one uses the new address, key and jump host
+	h.DockerPool.RemoveConnection(generator.ServerConnectionID(teamID, serverID))
+	if currentServer.ConnectionType == models.ServerConnectionAgent && newServer.ConnectionType != models.ServerConnectionAgent {
+		connection.DisconnectAgent(serverID)
+	}
+
 	// Return success response with the updated server
 	response.RespondWithJSON(w, http.StatusOK, server)
 }
@@ -172,6 +203,27 @@ func updateServerFromRequest(existingServer models.Server, updateServerRequest u
 	if updateServerRequest.PrivateKeyID != nil {
 		existingServer.PrivateKeyID = *updateServerRequest.PrivateKeyID
 	}
+	if updateServerRequest.ConnectionType != nil {
+		existingServer.ConnectionType = *updateServerRequest.ConnectionType
+	}
+	if updateServerRequest.TLSCACertificate != nil {
+		existingServer.TLSCACertificate = updateServerRequest.TLSCACertificate
+		if *updateServerRequest.TLSCACertificate == "" {
+			existingServer.TLSCACertificate = nil
+		}
+	}
+	if updateServerRequest.TLSCertificate != nil {
+		existingServer.TLSCertificate = updateServerRequest.TLSCertificate
+		if *updateServerRequest.TLSCertificate == "" {
+			existingServer.TLSCertificate = nil
+		}
+	}
+	if updateServerRequest.JumpHostID != nil {
+		existingServer.JumpHostID = updateServerRequest.JumpHostID
+		if *updateServerRequest.JumpHostID == "" {
+			existingServer.JumpHostID = nil
+		}
+	}
 	existingServer.UpdatedAt = time.Now()
 
 	return existingServer
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// StreamBootstrapOutput handles SSE streaming of the server bootstrap progress
+func StreamBootstrapOutput(ctx context.Context, w http.ResponseWriter, streamChan *core.StreamChan) {
+	// Set SSE headers
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	// Get flusher for real-time streaming
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		zap.L().Error("Streaming unsupported")
+		response.RespondWithError(w, http.StatusInternalServerError, "Streaming unsupported", "STREAMING_UNSUPPORTED")
+		return
+	}
+
+	sendEvent := func(payload map[string]any) {
+		data, _ := json.Marshal(payload)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+
+	// Send initial event
+	sendEvent(map[string]any{
+		"message": "Starting server bootstrap",
+		"type":    core.LogTypeInfo,
+	})
+
+	// Stream the bootstrap progress
+	for {
+		select {
+		case <-ctx.Done():
+			zap.L().Info("Client disconnected")
+			return
+
+		case logMsg := <-streamChan.LogChan:
+			sendEvent(map[string]any{
+				"message": logMsg.Message,
+				"type":    logMsg.Type,
+			})
+
+		case errMsg := <-streamChan.ErrChan:
+			sendEvent(map[string]any{
+				"message": errMsg.Message,
+				"type":    errMsg.Type,
+			})
+
+		case finalErr := <-streamChan.FinalError:
+			sendEvent(map[string]any{
+				"message": fmt.Sprintf("Bootstrap failed: %v", finalErr),
+				"type":    core.LogTypeError,
+			})
+			return
+
+		case <-streamChan.DoneChan:
+			// Drain the remaining log messages before reporting success
+			for len(streamChan.LogChan) > 0 {
+				logMsg := <-streamChan.LogChan
+				sendEvent(map[string]any{
+					"message": logMsg.Message,
+					"type":    logMsg.Type,
+				})
+			}
+
+			sendEvent(map[string]any{
+				"message": "Server bootstrapped successfully",
+				"type":    core.LogTypeInfo,
+				"state":   "ready",
+			})
+			return
+		}
+	}
+}
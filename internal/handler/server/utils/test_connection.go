@@ -6,17 +6,38 @@ import (
 
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
 )
 
-// TestServerConnection tests a Docker connection using the provided server and private key
-func TestServerConnection(ctx context.Context, server models.Server, privateKey models.PrivateKey, dockerPool *connection.ConnectionPool) error {
-	// Build SSH connection string for Docker
+// TestServerConnection tests a Docker connection using the provided server and credentials.
+// The host key is verified against the pinned key of the server, the presented host key is returned.
+// Servers reached without SSH present no host key, so "" is returned for them.
+func TestServerConnection(ctx context.Context, server models.Server, credentials connection.Credentials, dockerPool *connection.ConnectionPool) (string, error) {
+	// Test the connection using the docker pool's test function with the credentials
+	hostKey, err := dockerPool.TestConnection(generator.ServerHost(server), credentials, pinnedHostKey(server))
+	if err != nil {
+		return "", fmt.Errorf("failed to test Docker connection: %w", err)
+	}
+
+	return hostKey, nil
+}
+
+// TestServerSSHConnection tests only the SSH connection, for servers that do not have Docker yet
+func TestServerSSHConnection(server models.Server, credentials connection.Credentials, dockerPool *connection.ConnectionPool) (string, error) {
 	host := fmt.Sprintf("ssh://%s@%s:%s", server.User, server.IP, server.Port)
 
-	// Test the connection using the docker pool's test function with private key content
-	if err := dockerPool.TestConnection(host, []byte(privateKey.PrivateKey)); err != nil {
-		return fmt.Errorf("failed to test Docker connection: %w", err)
+	hostKey, err := dockerPool.TestSSHConnection(host, credentials, pinnedHostKey(server))
+	if err != nil {
+		return "", fmt.Errorf("failed to test SSH connection: %w", err)
 	}
 
-	return nil
+	return hostKey, nil
+}
+
+// pinnedHostKey returns the pinned host key of the server or "" to trust the first key
+func pinnedHostKey(server models.Server) string {
+	if server.HostKey == nil {
+		return ""
+	}
+	return *server.HostKey
 }
@@ -0,0 +1,262 @@
+// +----------------------------------------------+
+// | Adopt Compose Project                        |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/composeadopt"
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/handler/service/utils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// adoptComposeProjectRequest represents a request to take over a compose project running on a server
+type adoptComposeProjectRequest struct {
+	Name           string  `json:"name" validate:"required,min=3,max=255" example:"blog"`                             // Service name
+	Description    *string `json:"description,omitempty" validate:"omitempty,max=500" example:"Blog started by hand"` // Optional service description
+	ServerID       string  `json:"server_id" validate:"required" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                // Server the compose project runs on
+	ComposeProject string  `json:"compose_project" validate:"required" example:"blog"`                                // Value of the com.docker.compose.project label
+	ComposeFile    *string `json:"compose_file,omitempty" example:"services:\n  web:\n    image: nginx:1.27"`         // Compose file to deploy instead of the reconstructed one
+}
+
+// AdoptComposeProject godoc
+// @Summary Adopt a compose project running on a server
+// @Description Creates a service from a project started with docker compose outside of Starker and takes it over with real-time progress streaming via Server-Sent Events.
+// @Description The compose file is reconstructed from the containers unless compose_file is given. The existing resources are not relabeled, the project is copied and redeployed:
+// @Description the images of the service are pulled first, images that can not be pulled, like locally built ones, are used from the server. Then the containers of the project are stopped,
+// @Description the data of its volumes is copied to the service volumes, the old containers and networks are removed and the service is started with Starker labels.
+// @Description The old volumes are kept. If an image is missing nothing is stopped, if the data can not be copied the old containers are started again.
+// @Tags service
+// @Accept json
+// @Produce text/event-stream
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param request body adoptComposeProjectRequest true "Compose project adoption request"
+// @Success 200 {string} string "SSE stream of the adoption"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, team access denied, project or server not found or invalid compose file"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Compose project not found on the server"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/adopt [post]
+// @Security BearerAuth
+func (h *ServiceHandler) AdoptComposeProject(w http.ResponseWriter, r *http.Request) {
+	// Get teamID and projectID from the request
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+
+	// Decode and validate request body
+	var adoptComposeProjectRequest adoptComposeProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&adoptComposeProjectRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := validator.New().Struct(adoptComposeProjectRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start the transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Check if the user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Check if the project exists
+	project, err := repository.GetProject(r.Context(), tx, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to get project", zap.Error(err))
+		response.RespondWithError(w, http.StatusBadRequest, "Project not found", "PROJECT_NOT_FOUND")
+		return
+	}
+
+	// Check if the server exists
+	server, err := repository.GetServerByID(r.Context(), tx, adoptComposeProjectRequest.ServerID, teamID)
+	if err != nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+	if server == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Server not found", "SERVER_NOT_FOUND")
+		return
+	}
+
+	// Generate the service model, it starts stopped and is deployed by the adoption
+	now := time.Now()
+	service := models.Service{
+		ID:          ksuid.New().String(),
+		TeamID:      teamID,
+		ServerID:    server.ID,
+		ProjectID:   project.ID,
+		Name:        adoptComposeProjectRequest.Name,
+		Description: adoptComposeProjectRequest.Description,
+		Type:        "compose",
+		State:       models.ServiceStateStopped,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// Inspect the compose project on the server
+	dockerClient, err := h.getDockerClient(r.Context(), tx, &service)
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return
+	}
+	if err != nil {
+		zap.L().Error("Failed to get Docker client", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get Docker connection", "FAILED_TO_GET_DOCKER_CONNECTION")
+		return
+	}
+
+	composeProject, err := composeadopt.GetProject(r.Context(), dockerClient, adoptComposeProjectRequest.ComposeProject)
+	if err != nil {
+		zap.L().Error("Failed to get compose project", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get compose project", "FAILED_TO_GET_COMPOSE_PROJECT")
+		return
+	}
+	if composeProject == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Compose project not found", "COMPOSE_PROJECT_NOT_FOUND")
+		return
+	}
+
+	// Deploy the reconstructed compose file unless the user reviewed and changed it
+	composeFile := composeProject.ComposeFile
+	if adoptComposeProjectRequest.ComposeFile != nil {
+		composeFile = *adoptComposeProjectRequest.ComposeFile
+	}
+
+	namingGenerator := generator.NewNamingGenerator(service.ID, teamID, server.ID)
+	parsedProject, err := dockeryaml.ParseComposeContent(composeFile, namingGenerator.ProjectName())
+	if err != nil {
+		zap.L().Error("Failed to parse compose file", zap.Error(err))
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid compose file", "INVALID_COMPOSE_FILE")
+		return
+	}
+	if err := dockeryaml.Validate(parsedProject); err != nil {
+		zap.L().Error("Compose file validation failed", zap.Error(err))
+		response.RespondWithError(w, http.StatusBadRequest, "Compose file validation failed", "COMPOSE_FILE_VALIDATION_FAILED")
+		return
+	}
+
+	// Create the service and its compose config
+	if err = repository.CreateService(r.Context(), tx, service); err != nil {
+		zap.L().Error("Failed to create service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create service", "FAILED_TO_CREATE_SERVICE")
+		return
+	}
+
+	if err = repository.CreateServiceComposeConfig(r.Context(), tx, generateServiceComposeConfig(service.ID, composeFile)); err != nil {
+		zap.L().Error("Failed to create compose config", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create compose config", "FAILED_TO_CREATE_COMPOSE_CONFIG")
+		return
+	}
+
+	// Record the adoption in the audit log, it replaces containers Starker did not create
+	auditLog := models.AuditLog{
+		ID:           ksuid.New().String(),
+		TeamID:       teamID,
+		UserID:       userID,
+		Action:       models.AuditActionServiceAdopt,
+		ResourceType: "service",
+		ResourceID:   service.ID,
+		Metadata: map[string]string{
+			"server_id":       server.ID,
+			"compose_project": composeProject.Name,
+		},
+		IP:        &r.RemoteAddr,
+		CreatedAt: now,
+	}
+	if err := repository.CreateAuditLog(r.Context(), tx, auditLog); err != nil {
+		zap.L().Error("Failed to create audit log", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create audit log", "FAILED_TO_CREATE_AUDIT_LOG")
+		return
+	}
+
+	// The deployment records its containers, networks and volumes for the service, so it has to exist first
+	repository.CommitTransaction(tx, r.Context())
+
+	tx, err = repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	dockerHandler, streamChan, err := h.setupDockerHandler(r.Context(), tx, &service)
+	if err != nil {
+		zap.L().Error("Failed to set up Docker handler", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to set up Docker handler", "FAILED_TO_SETUP_DOCKER_HANDLER")
+		return
+	}
+	dockerHandler.VolumeStrategy = dockerutils.VolumeStrategyAbort
+
+	// Copy the project volumes the compose file still declares
+	volumes := make(map[string]string)
+	for _, volume := range composeProject.Volumes {
+		if _, ok := parsedProject.Volumes[volume.Key]; ok {
+			volumes[volume.Key] = volume.Name
+		}
+	}
+
+	// Update service to initial status before streaming
+	service.State = models.ServiceStateStarting
+	if err := repository.UpdateService(r.Context(), tx, service); err != nil {
+		zap.L().Error("Failed to update initial service status", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to update service status", "FAILED_TO_UPDATE_SERVICE_STATUS")
+		return
+	}
+
+	// The take over stops and removes the old project, it must not stop halfway when the client disconnects
+	err = dockerHandler.AdoptComposeProject(context.WithoutCancel(r.Context()), dockerutils.ComposeAdoption{
+		ContainerIDs: composeProject.ContainerIDs(),
+		NetworkIDs:   composeProject.NetworkIDs(),
+		Volumes:      volumes,
+	})
+	if err != nil {
+		zap.L().Error("Failed to adopt compose project", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to adopt compose project", "FAILED_TO_ADOPT_COMPOSE_PROJECT")
+		return
+	}
+
+	// Stream the adoption with real-time updates
+	utils.StreamServiceOutputWithUpdate(r.Context(), w, streamChan, &service, &tx, "start")
+}
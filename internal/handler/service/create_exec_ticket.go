@@ -0,0 +1,101 @@
+// +----------------------------------------------+
+// | Create Exec Ticket                           |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/wsticket"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// CreateExecTicket godoc
+// @Summary Issue a ticket to open a shell in a container
+// @Description Browsers can not send the Authorization header on a WebSocket handshake. The ticket authenticates one exec WebSocket of the container instead,
+// @Description passed as ticket query parameter or offered as subprotocol next to starker.ticket. It can be used once and expires after 30 seconds.
+// @Tags service
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Param containerID path string true "Container ID (from service_containers table)"
+// @Success 201 {object} response.SuccessResponse{data=wsticket.Ticket} "Ticket issued successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied, service or container not found"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/containers/{containerID}/exec/ticket [post]
+// @Security BearerAuth
+func (h *ServiceHandler) CreateExecTicket(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+	containerID := chi.URLParam(r, "containerID")
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	// Get the specific container by ID
+	container, err := repository.GetServiceContainerByID(r.Context(), tx, containerID, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to find container", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find container", "FAILED_TO_FIND_CONTAINER")
+		return
+	}
+	if container == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Container not found", "CONTAINER_NOT_FOUND")
+		return
+	}
+
+	ticket, err := wsticket.Issue(r.Context(), tx, userID, wsticket.ResourceContainerExec, container.ID)
+	if err != nil {
+		zap.L().Error("Failed to issue exec ticket", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to issue ticket", "FAILED_TO_ISSUE_TICKET")
+		return
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusCreated, ticket)
+}
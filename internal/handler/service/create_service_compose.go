@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/yorukot/starker/internal/core/dockersync"
+	"github.com/yorukot/starker/internal/core/dockerutils"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
@@ -25,16 +26,34 @@ import (
 
 // createServiceRequest represents a request to create a new service with Docker compose
 type createServiceRequest struct {
-	Name        string  `json:"name" validate:"required,min=3,max=255" example:"web-app"`                             // Service name
-	Description *string `json:"description,omitempty" validate:"omitempty,max=500" example:"Web application service"` // Optional service description
-	Type        string  `json:"type" validate:"required,oneof=docker compose" example:"docker"`                       // Service type (docker or compose)
-	ServerID    string  `json:"server_id" validate:"required" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                   // Server ID where service will be deployed
-	ComposeFile string  `json:"compose_file" validate:"required" example:"version: '3.8'..."`                         // Docker compose file content
+	Name        string               `json:"name" validate:"required,min=3,max=255" example:"web-app"`                             // Service name
+	Description *string              `json:"description,omitempty" validate:"omitempty,max=500" example:"Web application service"` // Optional service description
+	Type        string               `json:"type" validate:"required,oneof=docker compose" example:"docker"`                       // Service type (docker or compose)
+	ServerID    string               `json:"server_id" validate:"required" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                   // Server ID where service will be deployed
+	ComposeFile string               `json:"compose_file" validate:"required" example:"version: '3.8'..."`                         // Docker compose file content
+	Files       []composeFileRequest `json:"files,omitempty" validate:"omitempty,max=20,dive"`                                     // Additional compose files in load order
+	Profiles    []string             `json:"profiles,omitempty" validate:"omitempty,dive,required,max=100" example:"debug"`        // Compose profiles activated on deploy
+	EnvFiles    []envFileRequest     `json:"env_files,omitempty" validate:"omitempty,max=20,dive"`                                 // Named env files env_file entries resolve against
+}
+
+// composeFileRequest represents an additional compose file of a service
+type composeFileRequest struct {
+	Filename string `json:"filename" validate:"required,max=255" example:"docker-compose.prod.yml"` // Relative path extends and include reference the file by
+	Content  string `json:"content" validate:"required" example:"services:..."`                     // Docker compose file content
+	Override *bool  `json:"override,omitempty" example:"true"`                                      // Whether it is merged on top of the main file (default true), otherwise only extends and include read it
+}
+
+// envFileRequest represents a named env file of a service
+type envFileRequest struct {
+	Name    string `json:"name" validate:"required,max=255" example:".env.production"` // Relative path env_file references the file by
+	Content string `json:"content" example:"NODE_ENV=production"`                      // Dotenv content
 }
 
 // CreateServiceCompose godoc
 // @Summary Create a new service
-// @Description Creates a new service with Docker compose configuration within a team and project
+// @Description Creates a new service with Docker compose configuration within a team and project.
+// @Description Additional files are loaded on top of the main compose file in order like docker compose -f, files with override false are only read by extends and include.
+// @Description env_file entries of the compose files resolve against the names of the env files.
 // @Tags service
 // @Accept json
 // @Produce json
@@ -65,6 +84,11 @@ func (h *ServiceHandler) CreateServiceCompose(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if err := validateEnvFiles(createServiceRequest.EnvFiles); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_ENV_FILE")
+		return
+	}
+
 	// Get user ID from context
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 
@@ -115,6 +139,9 @@ func (h *ServiceHandler) CreateServiceCompose(w http.ResponseWriter, r *http.Req
 
 	// Generate compose config model
 	composeConfig := generateServiceComposeConfig(service.ID, createServiceRequest.ComposeFile)
+	composeConfig.Profiles = createServiceRequest.Profiles
+	composeConfig.Files = generateServiceComposeFiles(service.ID, createServiceRequest.Files)
+	envFiles := generateServiceEnvFiles(service.ID, createServiceRequest.EnvFiles)
 
 	// Create the service
 	if err = repository.CreateService(r.Context(), tx, service); err != nil {
@@ -130,9 +157,23 @@ func (h *ServiceHandler) CreateServiceCompose(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Generate the composeProject from the compose file
+	// Create the additional compose files
+	if err = repository.ReplaceServiceComposeFiles(r.Context(), tx, service.ID, composeConfig.Files); err != nil {
+		zap.L().Error("Failed to create compose files", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create compose files", "FAILED_TO_CREATE_COMPOSE_FILES")
+		return
+	}
+
+	// Create the env files
+	if err = repository.ReplaceServiceEnvFiles(r.Context(), tx, service.ID, envFiles); err != nil {
+		zap.L().Error("Failed to create env files", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create env files", "FAILED_TO_CREATE_ENV_FILES")
+		return
+	}
+
+	// Generate the composeProject from the compose files
 	namingGenerator := generator.NewNamingGenerator(service.ID, teamID, server.ID)
-	composeProject, err := dockeryaml.ParseComposeContent(createServiceRequest.ComposeFile, namingGenerator.ProjectName())
+	composeProject, err := dockerutils.ParseServiceCompose(composeConfig, envFiles, namingGenerator.ProjectName())
 	if err != nil {
 		zap.L().Error("Failed to parse compose file", zap.Error(err))
 		response.RespondWithError(w, http.StatusBadRequest, "Invalid compose file", "INVALID_COMPOSE_FILE")
@@ -195,3 +236,45 @@ func generateServiceComposeConfig(serviceID, composeFile string) models.ServiceC
 		UpdatedAt:   now,
 	}
 }
+
+// generateServiceComposeFiles generates the compose file models in the order of the request
+func generateServiceComposeFiles(serviceID string, files []composeFileRequest) []models.ServiceComposeFile {
+	now := time.Now()
+
+	composeFiles := []models.ServiceComposeFile{}
+	for i, file := range files {
+		override := true
+		if file.Override != nil {
+			override = *file.Override
+		}
+		composeFiles = append(composeFiles, models.ServiceComposeFile{
+			ID:        ksuid.New().String(),
+			ServiceID: serviceID,
+			Filename:  file.Filename,
+			Content:   file.Content,
+			Override:  override,
+			Position:  i + 1,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return composeFiles
+}
+
+// generateServiceEnvFiles generates the env file models of the request
+func generateServiceEnvFiles(serviceID string, files []envFileRequest) []models.ServiceEnvFile {
+	now := time.Now()
+
+	envFiles := []models.ServiceEnvFile{}
+	for _, file := range files {
+		envFiles = append(envFiles, models.ServiceEnvFile{
+			ID:        ksuid.New().String(),
+			ServiceID: serviceID,
+			Name:      file.Name,
+			Content:   file.Content,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return envFiles
+}
@@ -2,30 +2,44 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/dockerutils"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/generator"
 	"github.com/yorukot/starker/pkg/response"
 )
 
 // DeleteService godoc
 // @Summary Delete service with complete cleanup
-// @Description Deletes a service including stopping Docker containers, removing networks/volumes, and cleaning up all related database records
+// @Description Deletes a service including removing its Docker containers and networks from the server, and cleaning up all related database records.
+// @Description Volumes are kept unless purge_volumes is set, which requires confirm to match the service name. With dry_run the resources are only listed.
+// @Description With force the service is deleted even when its Docker resources can not be removed, for example because the server is unreachable.
+// @Description The resources left on the server are listed in the warnings and reported as orphans once the server can be reached.
 // @Tags service
 // @Produce json
 // @Param teamID path string true "Team ID"
 // @Param projectID path string true "Project ID"
 // @Param serviceID path string true "Service ID"
-// @Success 200 {object} response.SuccessResponse "Service deleted successfully"
+// @Param purge_volumes query bool false "Also remove the service volumes (deletes data)" default(false)
+// @Param dry_run query bool false "Only list the Docker resources that would be removed" default(false)
+// @Param confirm query string false "Service name, required when purging volumes"
+// @Param force query bool false "Delete the service even when its Docker resources can not be removed" default(false)
+// @Success 200 {object} response.SuccessResponse{data=dockerutils.PurgePlan} "Service deleted successfully, or the dry run plan"
 // @Failure 400 {object} response.ErrorResponse "Invalid request or team access denied"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
 // @Failure 404 {object} response.ErrorResponse "Service not found"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
 // @Router /teams/{teamID}/projects/{projectID}/services/{serviceID} [delete]
 // @Security BearerAuth
@@ -35,6 +49,11 @@ func (h *ServiceHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
 	projectID := chi.URLParam(r, "projectID")
 	serviceID := chi.URLParam(r, "serviceID")
 
+	// Parse the purge options
+	purgeVolumes := r.URL.Query().Get("purge_volumes") == "true"
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	force := r.URL.Query().Get("force") == "true"
+
 	// Get userID
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 
@@ -77,6 +96,60 @@ func (h *ServiceHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Purging volumes deletes data, so the user has to confirm it with the service name
+	if purgeVolumes && !dryRun && r.URL.Query().Get("confirm") != service.Name {
+		response.RespondWithError(w, http.StatusBadRequest, "Purging volumes requires confirming with the service name", "PURGE_CONFIRMATION_REQUIRED")
+		return
+	}
+
+	// The Docker work can take long and its lookups can fail, so it runs outside the transaction that deletes the service
+	repository.CommitTransaction(tx, r.Context())
+
+	// Remove the Docker resources from the server, or only list them on a dry run
+	plan, err := h.removeServiceResources(r.Context(), service, purgeVolumes, dryRun)
+	if err != nil && (dryRun || !force) {
+		if errors.Is(err, connection.ErrHostKeyMismatch) {
+			response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+			return
+		}
+		zap.L().Error("Failed to remove service Docker resources", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to remove service Docker resources, use force to delete the service without them", "FAILED_TO_REMOVE_DOCKER_RESOURCES")
+		return
+	}
+	if err != nil {
+		// The orphan collector reports the resources left behind once the server can be reached
+		zap.L().Warn("Deleting service without removing its Docker resources", zap.String("service_id", service.ID), zap.Error(err))
+		plan = dockerutils.NewPurgePlan()
+		plan.PurgeVolumes = purgeVolumes
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("the Docker resources of the service may be left on the server: %v", err))
+	}
+
+	// Dry run only reports what would be removed
+	if dryRun {
+		response.RespondWithJSON(w, http.StatusOK, plan)
+		return
+	}
+
+	tx, err = repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// The service may have been started while its Docker resources were removed
+	service, err = repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service.State == models.ServiceStateRunning || service.State == models.ServiceStateStarting {
+		response.RespondWithError(w, http.StatusBadRequest, "You need to stop the service before deleting it", "FAILED_TO_DELETE_SERVICE")
+		return
+	}
+
 	// Delete all related service data
 	err = h.deleteAllServiceData(r.Context(), tx, serviceID)
 	if err != nil {
@@ -88,7 +161,44 @@ func (h *ServiceHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
 	// Commit the transaction
 	repository.CommitTransaction(tx, r.Context())
 
-	response.RespondWithJSON(w, http.StatusOK, nil)
+	response.RespondWithJSON(w, http.StatusOK, plan)
+}
+
+// removeServiceResources lists every Docker resource of the service left on the server and removes them unless dryRun is set.
+// The server credentials and resource records are read in their own transaction, a failed lookup leaves the caller's untouched.
+func (h *ServiceHandler) removeServiceResources(ctx context.Context, service *models.Service, purgeVolumes, dryRun bool) (*dockerutils.PurgePlan, error) {
+	tx, err := repository.StartTransaction(h.DB, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	// Get Docker client from connection pool
+	dockerClient, err := h.getDockerClient(ctx, tx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerHandler := &dockerutils.DockerHandler{
+		Client:          dockerClient,
+		NamingGenerator: generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID),
+		StreamChan:      core.NewStreamChan(),
+	}
+
+	plan, err := dockerHandler.ListServiceResources(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	repository.CommitTransaction(tx, ctx)
+	plan.PurgeVolumes = purgeVolumes
+	if dryRun {
+		return plan, nil
+	}
+
+	if err := dockerHandler.RemoveServiceResources(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
 }
 
 // deleteAllServiceData deletes all service-related data from the database
@@ -116,7 +226,26 @@ func (h *ServiceHandler) deleteAllServiceData(ctx context.Context, tx pgx.Tx, se
 		return err
 	}
 
-	// Delete service compose config
+	// Delete the available image updates and the events of the service
+	if err := repository.DeleteServiceImageUpdates(ctx, tx, serviceID); err != nil {
+		return err
+	}
+	if err := repository.DeleteServiceEvents(ctx, tx, serviceID); err != nil {
+		return err
+	}
+
+	// Delete service stats history
+	if err := repository.DeleteServiceContainerStats(ctx, tx, serviceID); err != nil {
+		return err
+	}
+
+	// Delete service env files, compose files and config
+	if err := repository.DeleteServiceEnvFiles(ctx, tx, serviceID); err != nil {
+		return err
+	}
+	if err := repository.DeleteServiceComposeFiles(ctx, tx, serviceID); err != nil {
+		return err
+	}
 	if err := repository.DeleteServiceComposeConfig(ctx, tx, serviceID); err != nil {
 		return err
 	}
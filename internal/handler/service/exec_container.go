@@ -0,0 +1,248 @@
+// +----------------------------------------------+
+// | Exec Container                               |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/response"
+	"github.com/yorukot/starker/pkg/terminal"
+)
+
+// ExecContainer godoc
+// @Summary Open an interactive shell in a container over WebSocket
+// @Description Upgrades the connection to a WebSocket and attaches to a new TTY exec process in the container.
+// @Description The server sends terminal output as binary frames. The client sends JSON messages:
+// @Description {"type":"input","data":"ls\r"} for keystrokes and {"type":"resize","cols":120,"rows":40} to resize.
+// @Description Every session is recorded in the audit log. Browsers authenticate with a ticket from the exec ticket endpoint,
+// @Description passed as ticket query parameter or offered as subprotocol next to starker.ticket. The Origin has to be the frontend.
+// @Tags service
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Param containerID path string true "Container ID (from service_containers table)"
+// @Param cmd query string false "Command to run" default(/bin/sh)
+// @Param cols query int false "Initial terminal width"
+// @Param rows query int false "Initial terminal height"
+// @Param ticket query string false "WebSocket ticket, instead of the Authorization header"
+// @Success 101 {string} string "Switching protocols to WebSocket"
+// @Failure 400 {object} response.ErrorResponse "Team access denied, service/container not found, or invalid parameters"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/containers/{containerID}/exec [get]
+// @Security BearerAuth
+func (h *ServiceHandler) ExecContainer(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+	containerID := chi.URLParam(r, "containerID")
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Parse query parameters for the exec options
+	execOptions := dockerutils.ExecOptions{
+		Cmd: strings.Fields(r.URL.Query().Get("cmd")),
+	}
+	if len(execOptions.Cmd) == 0 {
+		execOptions.Cmd = []string{"/bin/sh"}
+	}
+	if colsStr, rowsStr := r.URL.Query().Get("cols"), r.URL.Query().Get("rows"); colsStr != "" || rowsStr != "" {
+		cols, colsErr := strconv.ParseUint(colsStr, 10, 16)
+		rows, rowsErr := strconv.ParseUint(rowsStr, 10, 16)
+		if colsErr != nil || rowsErr != nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Invalid terminal size", "INVALID_TERMINAL_SIZE")
+			return
+		}
+		execOptions.Cols = uint(cols)
+		execOptions.Rows = uint(rows)
+	}
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	// Get the specific container by ID
+	container, err := repository.GetServiceContainerByID(r.Context(), tx, containerID, serviceID)
+	if err != nil {
+		zap.L().Error("Failed to find container", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find container", "FAILED_TO_FIND_CONTAINER")
+		return
+	}
+	if container == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Container not found", "CONTAINER_NOT_FOUND")
+		return
+	}
+
+	// Check if container has a Docker container ID
+	if container.ContainerID == nil || *container.ContainerID == "" {
+		response.RespondWithError(w, http.StatusBadRequest, "Container has no Docker container ID", "NO_DOCKER_CONTAINER_ID")
+		return
+	}
+
+	// Get Docker client from connection pool
+	dockerClient, err := h.getDockerClient(r.Context(), tx, service)
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return
+	}
+	if err != nil {
+		zap.L().Error("Failed to get Docker connection", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get Docker connection", "FAILED_TO_GET_DOCKER_CONNECTION")
+		return
+	}
+
+	dockerHandler := &dockerutils.DockerHandler{
+		Client:     dockerClient,
+		StreamChan: core.NewStreamChan(),
+	}
+
+	// Commit transaction since we're moving to streaming
+	repository.CommitTransaction(tx, r.Context())
+
+	// Upgrade to WebSocket first, the exec process is only started once there is a connection to attach it to
+	websocket.Server{Handshake: middleware.WebSocketHandshake, Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		execSession, err := dockerHandler.StartContainerExec(r.Context(), *container.ContainerID, execOptions)
+		if err != nil {
+			zap.L().Error("Failed to start exec session", zap.Error(err))
+			websocket.Message.Send(ws, "Failed to start exec session\r\n")
+			return
+		}
+		defer execSession.Conn.Close()
+
+		// Record the session in the audit log
+		auditLog := models.AuditLog{
+			ID:           ksuid.New().String(),
+			TeamID:       teamID,
+			UserID:       userID,
+			Action:       models.AuditActionContainerExec,
+			ResourceType: "service_container",
+			ResourceID:   container.ID,
+			Metadata: map[string]string{
+				"service_id":     service.ID,
+				"container_name": container.ContainerName,
+				"exec_id":        execSession.ID,
+				"cmd":            strings.Join(execOptions.Cmd, " "),
+			},
+			IP:        &r.RemoteAddr,
+			CreatedAt: time.Now(),
+		}
+		if err := h.startExecAuditLog(r.Context(), auditLog); err != nil {
+			zap.L().Error("Failed to create audit log", zap.Error(err))
+			websocket.Message.Send(ws, "Failed to create audit log\r\n")
+			return
+		}
+
+		stats, err := terminal.Bridge(ws, terminal.Session{
+			Stdin:  execSession.Conn.Conn,
+			Stdout: execSession.Conn.Reader,
+			Resize: func(cols, rows uint) error {
+				return dockerHandler.ResizeContainerExec(r.Context(), execSession.ID, cols, rows)
+			},
+		})
+		if err != nil {
+			zap.L().Warn("Exec session ended with error", zap.String("exec_id", execSession.ID), zap.Error(err))
+		}
+
+		// Close the exec stream so the process gets EOF on stdin
+		execSession.Conn.Close()
+		h.endExecAuditLog(dockerHandler, auditLog.ID, execSession.ID, stats)
+	}}.ServeHTTP(w, r)
+}
+
+// startExecAuditLog records the start of an exec session in the audit log
+func (h *ServiceHandler) startExecAuditLog(ctx context.Context, auditLog models.AuditLog) error {
+	tx, err := repository.StartTransaction(h.DB, ctx)
+	if err != nil {
+		return err
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	if err := repository.CreateAuditLog(ctx, tx, auditLog); err != nil {
+		return err
+	}
+
+	repository.CommitTransaction(tx, ctx)
+	return nil
+}
+
+// endExecAuditLog stores the end time and traffic of an exec session in the audit log
+func (h *ServiceHandler) endExecAuditLog(dockerHandler *dockerutils.DockerHandler, auditLogID, execID string, stats terminal.Stats) {
+	// The request context may already be canceled once the client disconnected
+	ctx := context.Background()
+
+	metadata := map[string]string{
+		"bytes_in":  strconv.FormatInt(stats.BytesIn, 10),
+		"bytes_out": strconv.FormatInt(stats.BytesOut, 10),
+	}
+	if exitCode, err := dockerHandler.GetContainerExecExitCode(ctx, execID); err == nil {
+		metadata["exit_code"] = strconv.Itoa(exitCode)
+	}
+
+	tx, err := repository.StartTransaction(h.DB, ctx)
+	if err != nil {
+		zap.L().Error("Failed to begin transaction for exec audit log", zap.Error(err))
+		return
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	if err := repository.EndAuditLog(ctx, tx, auditLogID, time.Now(), metadata); err != nil {
+		zap.L().Error("Failed to end exec audit log", zap.String("audit_log_id", auditLogID), zap.Error(err))
+		return
+	}
+
+	repository.CommitTransaction(tx, ctx)
+	zap.L().Info("Exec session ended", zap.String("audit_log_id", auditLogID), zap.String("exec_id", execID), zap.String("traffic", fmt.Sprintf("%d in / %d out", stats.BytesIn, stats.BytesOut)))
+}
@@ -0,0 +1,192 @@
+// +----------------------------------------------+
+// | Export Service                               |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/core/servicebundle"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// ExportService godoc
+// @Summary Export a service as docker compose bundle
+// @Description Downloads a zip with the compose file rewritten with the container, network and volume names Starker deploys it with, the additional compose files of the service,
+// @Description a .env file of the service environment, the env files of the service and a README of the deployment.
+// @Description The environment and env file values are left empty unless include_secrets is true. Every export is recorded in the audit log.
+// @Tags service
+// @Produce application/zip
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Param include_secrets query bool false "Write the environment values to the .env file"
+// @Success 200 {file} file "Zip bundle with docker-compose.yml, .env and README.md"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or service not found"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/export [get]
+// @Security BearerAuth
+func (h *ServiceHandler) ExportService(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+	includeSecrets := r.URL.Query().Get("include_secrets") == "true"
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	server, err := repository.GetServerByID(r.Context(), tx, service.ServerID, teamID)
+	if err != nil || server == nil {
+		zap.L().Error("Failed to get server", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+		return
+	}
+
+	composeConfig, err := repository.GetServiceComposeConfig(r.Context(), tx, service.ID)
+	if err != nil || composeConfig == nil {
+		zap.L().Error("Failed to get compose config", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get compose config", "FAILED_TO_GET_COMPOSE_CONFIG")
+		return
+	}
+
+	envFiles, err := repository.GetServiceEnvFiles(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get env files", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get env files", "FAILED_TO_GET_ENV_FILES")
+		return
+	}
+
+	// Parse the compose file the same way a deployment does, so the names match
+	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
+	project, err := dockerutils.ParseServiceCompose(*composeConfig, envFiles, namingGenerator.ProjectName())
+	if err != nil {
+		zap.L().Error("Failed to parse compose file", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to parse compose file", "INVALID_COMPOSE_FILE")
+		return
+	}
+
+	environments, err := repository.GetServiceEnvironments(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get service environments", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get service environments", "FAILED_TO_GET_SERVICE_ENVIRONMENTS")
+		return
+	}
+
+	images, err := repository.GetServiceImages(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get service images", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get service images", "FAILED_TO_GET_SERVICE_IMAGES")
+		return
+	}
+
+	// Build the bundle
+	now := time.Now()
+	bundle, err := servicebundle.Build(servicebundle.Bundle{
+		Service:         *service,
+		Server:          *server,
+		ComposeConfig:   *composeConfig,
+		Project:         project,
+		NamingGenerator: namingGenerator,
+		Environments:    environments,
+		EnvFiles:        envFiles,
+		Images:          lastDeploymentImages(images),
+		IncludeSecrets:  includeSecrets,
+		ExportedAt:      now,
+	})
+	if err != nil {
+		zap.L().Error("Failed to build service bundle", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to build service bundle", "FAILED_TO_BUILD_SERVICE_BUNDLE")
+		return
+	}
+
+	// Record the export in the audit log, it can contain the environment values
+	auditLog := models.AuditLog{
+		ID:           ksuid.New().String(),
+		TeamID:       teamID,
+		UserID:       userID,
+		Action:       models.AuditActionServiceExport,
+		ResourceType: "service",
+		ResourceID:   service.ID,
+		Metadata: map[string]string{
+			"include_secrets": strconv.FormatBool(includeSecrets),
+		},
+		IP:        &r.RemoteAddr,
+		CreatedAt: now,
+	}
+	if err := repository.CreateAuditLog(r.Context(), tx, auditLog); err != nil {
+		zap.L().Error("Failed to create audit log", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to create audit log", "FAILED_TO_CREATE_AUDIT_LOG")
+		return
+	}
+
+	repository.CommitTransaction(tx, r.Context())
+
+	// Serve the bundle as a download
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", namingGenerator.ProjectName()+".zip"))
+	w.Header().Set("Content-Length", strconv.Itoa(len(bundle)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(bundle); err != nil {
+		zap.L().Warn("Failed to write service bundle", zap.Error(err))
+	}
+}
+
+// lastDeploymentImages returns the images of the newest deployment from images ordered newest first
+func lastDeploymentImages(images []models.ServiceImage) []models.ServiceImage {
+	var last []models.ServiceImage
+	for _, image := range images {
+		if len(last) > 0 && image.DeploymentID != last[0].DeploymentID {
+			break
+		}
+		last = append(last, image)
+	}
+	return last
+}
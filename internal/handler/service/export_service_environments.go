@@ -0,0 +1,104 @@
+// +----------------------------------------------+
+// | Export Service Environments                  |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/dotenv"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// ExportServiceEnvironments godoc
+// @Summary Export the environment variables of a service as dotenv file
+// @Description Returns the environment variables of a service in dotenv format, sorted by key with their comments written above them.
+// @Description Values are quoted so docker compose and the dotenv import read them back unchanged, multiline values included.
+// @Tags service
+// @Produce plain
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Success 200 {string} string "Environment variables in dotenv format"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Service not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/env/dotenv [get]
+// @Security BearerAuth
+func (h *ServiceHandler) ExportServiceEnvironments(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	environments, err := repository.GetServiceEnvironments(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get service environments", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get service environments", "FAILED_TO_GET_SERVICE_ENVIRONMENTS")
+		return
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	entries := make([]dotenv.Entry, 0, len(environments))
+	for _, environment := range environments {
+		entry := dotenv.Entry{Key: environment.Key, Value: environment.Value}
+		if environment.Comment != nil {
+			entry.Comment = *environment.Comment
+		}
+		entries = append(entries, entry)
+	}
+
+	// Serve the variables as a download
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", service.Name+".env"))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(dotenv.Marshal(entries))); err != nil {
+		zap.L().Warn("Failed to write dotenv file", zap.Error(err))
+	}
+}
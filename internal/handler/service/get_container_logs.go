@@ -5,7 +5,7 @@
 package service
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 	"time"
 
@@ -17,6 +17,7 @@ import (
 	"github.com/yorukot/starker/internal/handler/service/utils"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
 	"github.com/yorukot/starker/pkg/generator"
 	"github.com/yorukot/starker/pkg/response"
 )
@@ -38,8 +39,9 @@ import (
 // @Success 200 {string} string "SSE stream of container logs"
 // @Failure 400 {object} response.ErrorResponse "Team access denied, service/container not found, or invalid parameters"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
-// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/containers/{contUNAUTHORIZEDainerID}/logs [get]
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/containers/{containerID}/logs [get]
 // @Security BearerAuth
 func (h *ServiceHandler) GetContainerLogs(w http.ResponseWriter, r *http.Request) {
 	// Get URL parameters
@@ -136,23 +138,27 @@ func (h *ServiceHandler) GetContainerLogs(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get private key for SSH connection
-	privateKey, err := repository.GetPrivateKeyByID(r.Context(), tx, server.PrivateKeyID, service.TeamID)
-	if err != nil {
-		zap.L().Error("Failed to get private key", zap.Error(err))
-		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get private key", "FAILED_TO_GET_PRIVATE_KEY")
+	// Resolve the credentials, including the jump hosts in front of the server
+	credentials, err := generator.ServerCredentials(r.Context(), tx, *server)
+	if errors.Is(err, generator.ErrPrivateKeyNotFound) {
+		response.RespondWithError(w, http.StatusBadRequest, "Private key not found", "PRIVATE_KEY_NOT_FOUND")
 		return
 	}
-	if privateKey == nil {
-		response.RespondWithError(w, http.StatusBadRequest, "Private key not found", "PRIVATE_KEY_NOT_FOUND")
+	if err != nil {
+		zap.L().Error("Failed to resolve server credentials", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve server credentials", "FAILED_TO_RESOLVE_CREDENTIALS")
 		return
 	}
 
 	// Get Docker client from connection pool
 	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
 	connectionID := namingGenerator.ConnectionID()
-	sshHost := fmt.Sprintf("%s@%s:%s", server.User, server.IP, server.Port)
-	dockerClient, err := h.ConnectionPool.GetDockerConnection(connectionID, sshHost, []byte(privateKey.PrivateKey))
+	sshHost := generator.ServerHost(*server)
+	dockerClient, err := h.ConnectionPool.GetDockerConnection(connectionID, sshHost, credentials)
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return
+	}
 	if err != nil {
 		zap.L().Error("Failed to get Docker connection", zap.Error(err))
 		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get Docker connection", "FAILED_TO_GET_DOCKER_CONNECTION")
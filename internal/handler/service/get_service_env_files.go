@@ -0,0 +1,86 @@
+// +----------------------------------------------+
+// | Get Service Env Files                        |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// GetServiceEnvFiles godoc
+// @Summary Get the env files of a service
+// @Description Retrieves the named env files that env_file entries of the compose files resolve against
+// @Tags service
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Success 200 {object} response.SuccessResponse{data=[]models.ServiceEnvFile} "Env files retrieved successfully"
+// @Failure 400 {object} response.ErrorResponse "Team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Service not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/env-files [get]
+// @Security BearerAuth
+func (h *ServiceHandler) GetServiceEnvFiles(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	envFiles, err := repository.GetServiceEnvFiles(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get env files", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get env files", "FAILED_TO_GET_ENV_FILES")
+		return
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, envFiles)
+}
@@ -0,0 +1,90 @@
+// +----------------------------------------------+
+// | Get Service Events                           |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// maxServiceEvents is the number of events returned at once
+const maxServiceEvents = 100
+
+// GetServiceEvents godoc
+// @Summary Get events of a service
+// @Description Returns the latest notification events of a service, such as available, deployed or failed image updates, newest first.
+// @Tags service
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Success 200 {object} response.SuccessResponse{data=[]models.ServiceEvent} "Latest events, newest first"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or service not found"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/events [get]
+// @Security BearerAuth
+func (h *ServiceHandler) GetServiceEvents(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	// Get the latest events
+	events, err := repository.GetServiceEvents(r.Context(), tx, service.ID, maxServiceEvents)
+	if err != nil {
+		zap.L().Error("Failed to get service events", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get service events", "FAILED_TO_GET_SERVICE_EVENTS")
+		return
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, events)
+}
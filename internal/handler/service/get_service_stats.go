@@ -0,0 +1,139 @@
+// +----------------------------------------------+
+// | Get Service Stats                            |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/handler/service/utils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// GetServiceStats godoc
+// @Summary Stream container resource stats with SSE
+// @Description Streams CPU, memory, network and block IO usage of all running containers of a service via Server-Sent Events.
+// @Description Each event has type "stats" and a models.ContainerStats in data, roughly one per container per second.
+// @Tags service
+// @Accept json
+// @Produce text/event-stream
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Success 200 {string} string "SSE stream of container stats"
+// @Failure 400 {object} response.ErrorResponse "Team access denied or service not found"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/stats [get]
+// @Security BearerAuth
+func (h *ServiceHandler) GetServiceStats(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	// Get service containers
+	containers, err := repository.GetServiceContainers(r.Context(), tx, serviceID)
+	if err != nil {
+		zap.L().Error("Failed to get service containers", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get service containers", "FAILED_TO_GET_SERVICE_CONTAINERS")
+		return
+	}
+
+	// Get Docker client from connection pool
+	dockerClient, err := h.getDockerClient(r.Context(), tx, service)
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return
+	}
+	if err != nil {
+		zap.L().Error("Failed to get Docker connection", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get Docker connection", "FAILED_TO_GET_DOCKER_CONNECTION")
+		return
+	}
+
+	dockerHandler := &dockerutils.DockerHandler{
+		Client:     dockerClient,
+		StreamChan: core.NewStreamChan(),
+	}
+
+	// Commit transaction since we're moving to streaming
+	repository.CommitTransaction(tx, r.Context())
+
+	// Stream the stats of every running container into one channel
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	statsChan := make(chan models.ContainerStats, 100)
+	doneChan := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, container := range containers {
+		if container.ContainerID == nil || *container.ContainerID == "" || container.State != models.ContainerStateRunning {
+			continue
+		}
+
+		wg.Add(1)
+		go func(container models.ServiceContainer) {
+			defer wg.Done()
+			if err := dockerHandler.StreamContainerStats(ctx, *container.ContainerID, container.ContainerName, statsChan); err != nil {
+				zap.L().Warn("Container stats stream ended with error", zap.String("container", container.ContainerName), zap.Error(err))
+			}
+		}(container)
+	}
+	go func() {
+		wg.Wait()
+		close(doneChan)
+	}()
+
+	utils.StreamContainerStats(ctx, w, statsChan, doneChan)
+}
@@ -0,0 +1,106 @@
+// +----------------------------------------------+
+// | Get Service Stats History                    |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// GetServiceStatsHistory godoc
+// @Summary Get container resource stats history
+// @Description Returns the stored resource usage samples of all containers of a service for charts.
+// @Description The 24h range returns one sample per minute, the 7d range one averaged sample per hour.
+// @Tags service
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Param range query string false "Time range" Enums(24h, 7d) default(24h)
+// @Success 200 {object} response.SuccessResponse{data=[]models.ContainerStats} "Stats samples, oldest first"
+// @Failure 400 {object} response.ErrorResponse "Team access denied, service not found, or invalid range"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/stats/history [get]
+// @Security BearerAuth
+func (h *ServiceHandler) GetServiceStatsHistory(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Pick the resolution for the requested range
+	var resolution models.StatsResolution
+	var since time.Time
+	switch r.URL.Query().Get("range") {
+	case "", "24h":
+		resolution = models.StatsResolutionMinute
+		since = time.Now().Add(-24 * time.Hour)
+	case "7d":
+		resolution = models.StatsResolutionHour
+		since = time.Now().Add(-7 * 24 * time.Hour)
+	default:
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid range, use 24h or 7d", "INVALID_STATS_RANGE")
+		return
+	}
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	// Get the stored samples
+	history, err := repository.GetContainerStatsHistory(r.Context(), tx, serviceID, resolution, since)
+	if err != nil {
+		zap.L().Error("Failed to get stats history", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get stats history", "FAILED_TO_GET_STATS_HISTORY")
+		return
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, history)
+}
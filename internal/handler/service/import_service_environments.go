@@ -0,0 +1,197 @@
+// +----------------------------------------------+
+// | Import Service Environments                  |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/segmentio/ksuid"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/dotenv"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// importServiceEnvironmentsRequest represents a request to import environment variables from a dotenv file
+type importServiceEnvironmentsRequest struct {
+	Content string `json:"content" validate:"max=1048576" example:"# Runtime mode\nNODE_ENV=production"` // Dotenv file content
+	Replace bool   `json:"replace" example:"false"`                                                      // Delete the variables missing from the file, otherwise they are kept
+}
+
+// ImportServiceEnvironments godoc
+// @Summary Import environment variables of a service from a dotenv file
+// @Description Creates or updates the environment variables of a dotenv file. Comment lines directly above a variable are stored as its comment,
+// @Description quoted values can span multiple lines and are not interpolated. With replace the variables missing from the file are deleted.
+// @Tags service
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Param request body importServiceEnvironmentsRequest true "Dotenv import request"
+// @Success 200 {object} response.SuccessResponse{data=[]models.ServiceEnvironment} "Environment variables imported successfully"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, invalid dotenv content or team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Service not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/env/dotenv [put]
+// @Security BearerAuth
+func (h *ServiceHandler) ImportServiceEnvironments(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Decode and validate request body
+	var importRequest importServiceEnvironmentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&importRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := validator.New().Struct(importRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	entries, err := dotenv.Parse(importRequest.Content)
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid dotenv content: "+err.Error(), "INVALID_DOTENV")
+		return
+	}
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	environments, err := repository.GetServiceEnvironments(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get service environments", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get service environments", "FAILED_TO_GET_SERVICE_ENVIRONMENTS")
+		return
+	}
+
+	existingEnvMap := make(map[string]models.ServiceEnvironment, len(environments))
+	for _, env := range environments {
+		existingEnvMap[env.Key] = env
+	}
+
+	// Create or update the variables of the file
+	now := time.Now()
+	imported := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		imported[entry.Key] = true
+		var comment *string
+		if entry.Comment != "" {
+			comment = &entry.Comment
+		}
+
+		existingEnv, exists := existingEnvMap[entry.Key]
+		if !exists {
+			newEnv := models.ServiceEnvironment{
+				ID:        ksuid.New().String(),
+				ServiceID: service.ID,
+				Key:       entry.Key,
+				Value:     entry.Value,
+				Comment:   comment,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if err := repository.CreateServiceEnvironment(r.Context(), tx, newEnv); err != nil {
+				zap.L().Error("Failed to create service environment", zap.Error(err), zap.String("key", entry.Key))
+				response.RespondWithError(w, http.StatusInternalServerError, "Failed to create service environment", "FAILED_TO_CREATE_SERVICE_ENVIRONMENT")
+				return
+			}
+			continue
+		}
+
+		// Skip the variables that did not change
+		if existingEnv.Value == entry.Value && sameComment(existingEnv.Comment, comment) {
+			continue
+		}
+		existingEnv.Value = entry.Value
+		existingEnv.Comment = comment
+		existingEnv.UpdatedAt = now
+		if err := repository.UpdateServiceEnvironment(r.Context(), tx, existingEnv); err != nil {
+			zap.L().Error("Failed to update service environment", zap.Error(err), zap.String("key", entry.Key))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to update service environment", "FAILED_TO_UPDATE_SERVICE_ENVIRONMENT")
+			return
+		}
+	}
+
+	// Delete the variables missing from the file
+	if importRequest.Replace {
+		for key, existingEnv := range existingEnvMap {
+			if imported[key] {
+				continue
+			}
+			if err := repository.DeleteServiceEnvironment(r.Context(), tx, existingEnv.ID, service.ID); err != nil {
+				zap.L().Error("Failed to delete service environment", zap.Error(err), zap.String("key", key))
+				response.RespondWithError(w, http.StatusInternalServerError, "Failed to delete service environment", "FAILED_TO_DELETE_SERVICE_ENVIRONMENT")
+				return
+			}
+		}
+	}
+
+	// Get updated environments after all operations
+	updatedEnvironments, err := repository.GetServiceEnvironments(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get updated service environments", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get updated service environments", "FAILED_TO_GET_UPDATED_SERVICE_ENVIRONMENTS")
+		return
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, updatedEnvironments)
+}
+
+// sameComment reports whether two optional comments are equal
+func sameComment(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
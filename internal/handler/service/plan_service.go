@@ -0,0 +1,178 @@
+// +----------------------------------------------+
+// | Plan Service Deployment                      |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// planServiceRequest represents a request to plan the next deployment of a service
+type planServiceRequest struct {
+	ComposeFile    *string `json:"compose_file,omitempty" validate:"omitempty,min=1" example:"services:\n  web:\n    image: nginx:1.27"` // Main compose file to plan instead of the stored one
+	VolumeStrategy string  `json:"volume_strategy,omitempty" validate:"omitempty,oneof=abort recreate migrate" example:"abort"`          // How incompatible volumes would be handled
+}
+
+// PlanService godoc
+// @Summary Plan the deployment of a service
+// @Description Inspects the server and returns what starting the service would do without changing anything: images to pull, networks and volumes to create or reuse,
+// @Description containers to create or recreate, published ports already in use, warnings and errors that would fail the deployment.
+// @Description The stored compose files are planned unless compose_file is given for the main file, so a change can be reviewed before it is saved.
+// @Tags service
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Param request body planServiceRequest false "Deployment plan request"
+// @Success 200 {object} response.SuccessResponse{data=dockerutils.DeploymentPlan} "Plan of the next deployment"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, team access denied, service not found or invalid compose file"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 409 {object} response.ErrorResponse "Server presented a different host key than the pinned one"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/plan [post]
+// @Security BearerAuth
+func (h *ServiceHandler) PlanService(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Decode and validate request body, the body is optional
+	var planServiceRequest planServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&planServiceRequest); err != nil && !errors.Is(err, io.EOF) {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := validator.New().Struct(planServiceRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	composeConfig, err := repository.GetServiceComposeConfig(r.Context(), tx, service.ID)
+	if err != nil || composeConfig == nil {
+		zap.L().Error("Failed to get compose config", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get compose config", "FAILED_TO_GET_COMPOSE_CONFIG")
+		return
+	}
+
+	// Plan the given main compose file, otherwise the stored one, with the stored overrides and profiles
+	if planServiceRequest.ComposeFile != nil {
+		composeConfig.ComposeFile = *planServiceRequest.ComposeFile
+	}
+
+	envFiles, err := repository.GetServiceEnvFiles(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get env files", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get env files", "FAILED_TO_GET_ENV_FILES")
+		return
+	}
+
+	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
+	project, err := dockerutils.ParseServiceCompose(*composeConfig, envFiles, namingGenerator.ProjectName())
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid compose file", "INVALID_COMPOSE_FILE")
+		return
+	}
+	if err := dockeryaml.Validate(project); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Compose file validation failed", "COMPOSE_FILE_VALIDATION_FAILED")
+		return
+	}
+
+	// Inspect the server through the pooled connection
+	dockerClient, err := h.getDockerClient(r.Context(), tx, service)
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return
+	}
+	if err != nil {
+		zap.L().Error("Failed to get Docker client", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get Docker connection", "FAILED_TO_GET_DOCKER_CONNECTION")
+		return
+	}
+
+	volumeStrategy := dockerutils.VolumeStrategy(planServiceRequest.VolumeStrategy)
+	if volumeStrategy == "" {
+		volumeStrategy = dockerutils.VolumeStrategyAbort
+	}
+
+	dockerHandler := &dockerutils.DockerHandler{
+		Client:          dockerClient,
+		Project:         project,
+		NamingGenerator: namingGenerator,
+		StreamChan:      core.NewStreamChan(),
+		VolumeStrategy:  volumeStrategy,
+	}
+
+	plan, err := dockerHandler.PlanDeployment(r.Context())
+	if err != nil {
+		zap.L().Error("Failed to plan deployment", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to plan deployment", "FAILED_TO_PLAN_DEPLOYMENT")
+		return
+	}
+
+	// Containers are recreated, a running service is down until they start again
+	if service.State == models.ServiceStateRunning {
+		plan.Warnings = append(plan.Warnings, "the service is running and is unavailable while its containers are recreated")
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, plan)
+}
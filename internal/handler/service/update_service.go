@@ -9,6 +9,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
+	"github.com/yorukot/starker/internal/core/imageupdates"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
@@ -20,15 +21,17 @@ import (
 // +----------------------------------------------+
 
 type updateServiceRequest struct {
-	Name        *string              `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
-	Description *string              `json:"description,omitempty" validate:"omitempty,max=500"`
-	Type        *string              `json:"type,omitempty" validate:"omitempty,oneof=docker compose"`
-	State       *models.ServiceState `json:"status,omitempty" validate:"omitempty,oneof=running stopped starting stopping"`
+	Name             *string              `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
+	Description      *string              `json:"description,omitempty" validate:"omitempty,max=500"`
+	Type             *string              `json:"type,omitempty" validate:"omitempty,oneof=docker compose"`
+	State            *models.ServiceState `json:"status,omitempty" validate:"omitempty,oneof=running stopped starting stopping"`
+	AutoUpdate       *bool                `json:"auto_update,omitempty" example:"true"`
+	AutoUpdateWindow *string              `json:"auto_update_window,omitempty" example:"02:00-05:00"`
 }
 
 // UpdateService godoc
 // @Summary Update service metadata
-// @Description Updates service metadata (name, description, type) within a team and project
+// @Description Updates service metadata (name, description, type) and the automatic image update settings within a team and project
 // @Tags service
 // @Accept json
 // @Produce json
@@ -62,6 +65,14 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate the update window
+	if updateServiceRequest.AutoUpdateWindow != nil && *updateServiceRequest.AutoUpdateWindow != "" {
+		if _, _, err := imageupdates.ParseUpdateWindow(*updateServiceRequest.AutoUpdateWindow); err != nil {
+			response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_UPDATE_WINDOW")
+			return
+		}
+	}
+
 	// Get user ID from context
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 
@@ -130,6 +141,15 @@ func updateServiceFromRequest(existingService models.Service, updateServiceReque
 	if updateServiceRequest.State != nil {
 		existingService.State = *updateServiceRequest.State
 	}
+	if updateServiceRequest.AutoUpdate != nil {
+		existingService.AutoUpdate = *updateServiceRequest.AutoUpdate
+	}
+	if updateServiceRequest.AutoUpdateWindow != nil {
+		existingService.AutoUpdateWindow = updateServiceRequest.AutoUpdateWindow
+		if *updateServiceRequest.AutoUpdateWindow == "" {
+			existingService.AutoUpdateWindow = nil
+		}
+	}
 	existingService.UpdatedAt = time.Now()
 
 	return existingService
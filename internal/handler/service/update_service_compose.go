@@ -13,20 +13,26 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
+	"github.com/yorukot/starker/internal/core/dockerutils"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/generator"
 	"github.com/yorukot/starker/pkg/response"
 )
 
 type updateServiceComposeRequest struct {
-	ComposeFile     *string `json:"compose_file,omitempty" validate:"omitempty,required"`
-	ComposeFilePath *string `json:"compose_file_path,omitempty" validate:"omitempty,max=500"`
+	ComposeFile     *string               `json:"compose_file,omitempty" validate:"omitempty,required"`
+	ComposeFilePath *string               `json:"compose_file_path,omitempty" validate:"omitempty,max=500"`
+	Files           *[]composeFileRequest `json:"files,omitempty" validate:"omitempty,max=20,dive"`                              // Replaces the additional compose files, in load order
+	Profiles        *[]string             `json:"profiles,omitempty" validate:"omitempty,dive,required,max=100" example:"debug"` // Replaces the compose profiles activated on deploy
 }
 
 // UpdateServiceCompose godoc
 // @Summary Update service Docker Compose configuration
-// @Description Updates the Docker Compose configuration for a specific service
+// @Description Updates the Docker Compose configuration for a specific service. files and profiles replace the stored ones when given.
+// @Description The compose files are loaded with the profiles before saving, a configuration that can not be deployed is rejected.
 // @Tags service
 // @Accept json
 // @Produce json
@@ -35,7 +41,7 @@ type updateServiceComposeRequest struct {
 // @Param serviceID path string true "Service ID"
 // @Param request body updateServiceComposeRequest true "Service compose update request"
 // @Success 200 {object} response.SuccessResponse{data=models.ServiceComposeConfig} "Compose config updated successfully"
-// @Failure 400 {object} response.ErrorResponse "Invalid request body, team access denied, or service not found"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, team access denied, service not found or invalid compose files"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
 // @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/compose [patch]
@@ -110,6 +116,25 @@ func (h *ServiceHandler) UpdateServiceCompose(w http.ResponseWriter, r *http.Req
 	// Update compose config with new values
 	updatedComposeConfig := updateServiceComposeFromRequest(*composeConfig, updateServiceComposeRequest)
 
+	envFiles, err := repository.GetServiceEnvFiles(r.Context(), tx, service.ID)
+	if err != nil {
+		zap.L().Error("Failed to get env files", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get env files", "FAILED_TO_GET_ENV_FILES")
+		return
+	}
+
+	// Load the compose files the way a deploy does, so broken overrides or references are caught now
+	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
+	composeProject, err := dockerutils.ParseServiceCompose(updatedComposeConfig, envFiles, namingGenerator.ProjectName())
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid compose file", "INVALID_COMPOSE_FILE")
+		return
+	}
+	if err := dockeryaml.Validate(composeProject); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Compose file validation failed", "COMPOSE_FILE_VALIDATION_FAILED")
+		return
+	}
+
 	// Update the compose config in database
 	if err := repository.UpdateServiceComposeConfig(r.Context(), tx, updatedComposeConfig); err != nil {
 		zap.L().Error("Failed to update compose config", zap.Error(err))
@@ -117,6 +142,15 @@ func (h *ServiceHandler) UpdateServiceCompose(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Replace the additional compose files
+	if updateServiceComposeRequest.Files != nil {
+		if err := repository.ReplaceServiceComposeFiles(r.Context(), tx, service.ID, updatedComposeConfig.Files); err != nil {
+			zap.L().Error("Failed to update compose files", zap.Error(err))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to update compose files", "FAILED_TO_UPDATE_COMPOSE_FILES")
+			return
+		}
+	}
+
 	// Commit transaction
 	repository.CommitTransaction(tx, r.Context())
 
@@ -129,6 +163,12 @@ func updateServiceComposeFromRequest(existingConfig models.ServiceComposeConfig,
 	if updateServiceComposeRequest.ComposeFile != nil {
 		existingConfig.ComposeFile = *updateServiceComposeRequest.ComposeFile
 	}
+	if updateServiceComposeRequest.Files != nil {
+		existingConfig.Files = generateServiceComposeFiles(existingConfig.ServiceID, *updateServiceComposeRequest.Files)
+	}
+	if updateServiceComposeRequest.Profiles != nil {
+		existingConfig.Profiles = *updateServiceComposeRequest.Profiles
+	}
 	existingConfig.UpdatedAt = time.Now()
 
 	return existingConfig
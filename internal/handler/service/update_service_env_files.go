@@ -0,0 +1,147 @@
+// +----------------------------------------------+
+// | Update Service Env Files                     |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/dotenv"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// updateServiceEnvFilesRequest represents a request to replace the env files of a service
+type updateServiceEnvFilesRequest struct {
+	EnvFiles []envFileRequest `json:"env_files" validate:"max=20,dive"` // Env files of the service, the ones missing are deleted
+}
+
+// UpdateServiceEnvFiles godoc
+// @Summary Replace the env files of a service
+// @Description Replaces the named env files that env_file entries of the compose files resolve against. Every file must be valid dotenv content
+// @Description and the compose files must still load with the new env files, otherwise nothing is saved.
+// @Tags service
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param serviceID path string true "Service ID"
+// @Param request body updateServiceEnvFilesRequest true "Env files update request"
+// @Success 200 {object} response.SuccessResponse{data=[]models.ServiceEnvFile} "Env files updated successfully"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, invalid env file, invalid compose file or team access denied"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 404 {object} response.ErrorResponse "Service not found"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/env-files [put]
+// @Security BearerAuth
+func (h *ServiceHandler) UpdateServiceEnvFiles(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+	serviceID := chi.URLParam(r, "serviceID")
+
+	// Decode and validate request body
+	var updateRequest updateServiceEnvFilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := validator.New().Struct(updateRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := validateEnvFiles(updateRequest.EnvFiles); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, err.Error(), "INVALID_ENV_FILE")
+		return
+	}
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// Verify service exists and belongs to the team/project
+	service, err := repository.GetServiceByID(r.Context(), tx, serviceID, teamID, projectID)
+	if err != nil {
+		zap.L().Error("Failed to find service", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+		return
+	}
+	if service == nil {
+		response.RespondWithError(w, http.StatusNotFound, "Service not found", "SERVICE_NOT_FOUND")
+		return
+	}
+
+	composeConfig, err := repository.GetServiceComposeConfig(r.Context(), tx, service.ID)
+	if err != nil || composeConfig == nil {
+		zap.L().Error("Failed to get compose config", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to get compose config", "FAILED_TO_GET_COMPOSE_CONFIG")
+		return
+	}
+
+	// Load the compose files with the new env files, so env_file entries that no longer resolve are caught now
+	envFiles := generateServiceEnvFiles(service.ID, updateRequest.EnvFiles)
+	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
+	composeProject, err := dockerutils.ParseServiceCompose(*composeConfig, envFiles, namingGenerator.ProjectName())
+	if err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid compose file", "INVALID_COMPOSE_FILE")
+		return
+	}
+	if err := dockeryaml.Validate(composeProject); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Compose file validation failed", "COMPOSE_FILE_VALIDATION_FAILED")
+		return
+	}
+
+	if err := repository.ReplaceServiceEnvFiles(r.Context(), tx, service.ID, envFiles); err != nil {
+		zap.L().Error("Failed to update env files", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to update env files", "FAILED_TO_UPDATE_ENV_FILES")
+		return
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, envFiles)
+}
+
+// validateEnvFiles checks that the env files are dotenv files docker compose can read
+func validateEnvFiles(envFiles []envFileRequest) error {
+	for _, envFile := range envFiles {
+		if _, err := dotenv.Parse(envFile.Content); err != nil {
+			return fmt.Errorf("invalid env file %s: %w", envFile.Name, err)
+		}
+	}
+	return nil
+}
@@ -7,20 +7,25 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/docker/docker/client"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 
+	"github.com/yorukot/starker/internal/config"
 	"github.com/yorukot/starker/internal/core"
 	"github.com/yorukot/starker/internal/core/dockerutils"
 	"github.com/yorukot/starker/internal/handler/service/utils"
 	"github.com/yorukot/starker/internal/middleware"
 	"github.com/yorukot/starker/internal/models"
 	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/connection"
 	"github.com/yorukot/starker/pkg/dockeryaml"
 	"github.com/yorukot/starker/pkg/generator"
 	"github.com/yorukot/starker/pkg/response"
@@ -28,7 +33,11 @@ import (
 
 // updateServiceStateRequest represents a request to update service state
 type updateServiceStateRequest struct {
-	State string `json:"state" validate:"required,oneof=start stop restart" example:"start"` // Service state action (start, stop, restart)
+	State        string `json:"state" validate:"required,oneof=start stop restart" example:"start"` // Service state action (start, stop, restart)
+	PurgeVolumes bool   `json:"purge_volumes,omitempty" example:"false"`                            // Remove the service volumes when stopping (deletes data)
+	Confirm      string `json:"confirm,omitempty" example:"web-app"`                                // Service name, required to confirm a volume purge
+	// What to do with existing volumes that do not match the compose definition (abort, recreate, migrate)
+	VolumeStrategy string `json:"volume_strategy,omitempty" validate:"omitempty,oneof=abort recreate migrate" example:"abort"`
 }
 
 // UpdateServiceState godoc
@@ -45,6 +54,7 @@ type updateServiceStateRequest struct {
 // @Failure 400 {object} response.ErrorResponse "Invalid request body or team access denied"
 // @Failure 401 {object} response.ErrorResponse "User not authenticated"
 // @Failure 404 {object} response.ErrorResponse "Service not found"
+// @Failure 409 {object} response.ErrorResponse "Existing volumes do not match the compose definition or the server host key does not match"
 // @Failure 500 {object} response.ErrorResponse "Internal server error"
 // @Router /teams/{teamID}/projects/{projectID}/services/{serviceID}/state [patch]
 // @Security BearerAuth
@@ -68,6 +78,18 @@ func (h *ServiceHandler) UpdateServiceState(w http.ResponseWriter, r *http.Reque
 
 	newState := updateServiceStateRequest.State
 
+	// Volumes can only be purged when stopping the service
+	if updateServiceStateRequest.PurgeVolumes && newState != "stop" {
+		response.RespondWithError(w, http.StatusBadRequest, "Volumes can only be purged when stopping the service", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// The volume strategy only applies when containers are started
+	if updateServiceStateRequest.VolumeStrategy != "" && newState == "stop" {
+		response.RespondWithError(w, http.StatusBadRequest, "Volume strategy can only be used when starting the service", "INVALID_REQUEST_BODY")
+		return
+	}
+
 	// Get userID
 	userID := r.Context().Value(middleware.UserIDKey).(string)
 
@@ -110,8 +132,22 @@ func (h *ServiceHandler) UpdateServiceState(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// Purging volumes deletes data, so the user has to confirm it with the service name
+	if updateServiceStateRequest.PurgeVolumes && updateServiceStateRequest.Confirm != service.Name {
+		response.RespondWithError(w, http.StatusBadRequest, "Purging volumes requires confirming with the service name", "PURGE_CONFIRMATION_REQUIRED")
+		return
+	}
+
 	// Execute the service operation
-	result, err := h.executeServiceOperation(r.Context(), tx, newState, service)
+	result, err := h.executeServiceOperation(r.Context(), tx, newState, service, updateServiceStateRequest)
+	if errors.Is(err, connection.ErrHostKeyMismatch) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "HOST_KEY_MISMATCH")
+		return
+	}
+	if errors.Is(err, dockerutils.ErrIncompatibleVolumes) {
+		response.RespondWithError(w, http.StatusConflict, err.Error(), "VOLUME_INCOMPATIBLE")
+		return
+	}
 	if err != nil {
 		zap.L().Error("Failed to execute service command", zap.Error(err))
 		response.RespondWithError(w, http.StatusInternalServerError, "Failed to execute service command", "FAILED_TO_EXECUTE_COMMAND")
@@ -151,14 +187,19 @@ func checkStateIsRight(state models.ServiceState, newState string) bool {
 }
 
 // executeServiceOperation executes the Docker service operation and returns streaming result
-func (h *ServiceHandler) executeServiceOperation(ctx context.Context, tx pgx.Tx, operation string, service *models.Service) (*core.StreamChan, error) {
+func (h *ServiceHandler) executeServiceOperation(ctx context.Context, tx pgx.Tx, operation string, service *models.Service, request updateServiceStateRequest) (*core.StreamChan, error) {
+	volumeStrategy := dockerutils.VolumeStrategy(request.VolumeStrategy)
+	if volumeStrategy == "" {
+		volumeStrategy = dockerutils.VolumeStrategyAbort
+	}
+
 	switch operation {
 	case "start":
-		return h.executeStartOperation(ctx, tx, service)
+		return h.executeStartOperation(ctx, tx, service, volumeStrategy)
 	case "stop":
-		return h.executeStopOperation(ctx, tx, service)
+		return h.executeStopOperation(ctx, tx, service, request.PurgeVolumes)
 	case "restart":
-		return h.executeRestartOperation(ctx, tx, service)
+		return h.executeRestartOperation(ctx, tx, service, volumeStrategy)
 	default:
 		return nil, fmt.Errorf("unsupported operation: %s", operation)
 	}
@@ -175,27 +216,14 @@ func (h *ServiceHandler) setupDockerHandler(ctx context.Context, tx pgx.Tx, serv
 		return nil, nil, fmt.Errorf("no compose configuration found for service")
 	}
 
-	// Get server details for connection
-	server, err := repository.GetServerByID(ctx, tx, service.ServerID, service.TeamID)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get server: %w", err)
-	}
-	if server == nil {
-		return nil, nil, fmt.Errorf("server not found")
-	}
-
-	// Get private key for SSH connection
-	privateKey, err := repository.GetPrivateKeyByID(ctx, tx, server.PrivateKeyID, service.TeamID)
+	envFiles, err := repository.GetServiceEnvFiles(ctx, tx, service.ID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get private key: %w", err)
-	}
-	if privateKey == nil {
-		return nil, nil, fmt.Errorf("private key not found")
+		return nil, nil, fmt.Errorf("failed to get service env files: %w", err)
 	}
 
 	// Parse the Docker Compose configuration
 	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
-	project, err := dockeryaml.ParseComposeContent(composeConfig.ComposeFile, namingGenerator.ProjectName())
+	project, err := dockerutils.ParseServiceCompose(*composeConfig, envFiles, namingGenerator.ProjectName())
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to parse compose file: %w", err)
 	}
@@ -206,12 +234,9 @@ func (h *ServiceHandler) setupDockerHandler(ctx context.Context, tx pgx.Tx, serv
 	}
 
 	// Get Docker client from connection pool
-	connectionID := namingGenerator.ConnectionID()
-	// Build SSH connection string
-	sshHost := fmt.Sprintf("%s@%s:%s", server.User, server.IP, server.Port)
-	dockerClient, err := h.ConnectionPool.GetDockerConnection(connectionID, sshHost, []byte(privateKey.PrivateKey))
+	dockerClient, err := h.getDockerClient(ctx, tx, service)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get Docker connection: %w", err)
+		return nil, nil, err
 	}
 
 	// Create streaming channels
@@ -225,19 +250,55 @@ func (h *ServiceHandler) setupDockerHandler(ctx context.Context, tx pgx.Tx, serv
 		DB:              h.DB,
 		ConnectionPool:  h.ConnectionPool,
 		StreamChan:      streamChan,
+		ImageRetention:  config.Env().ImageRetentionDeployments,
 	}
 
 	return dockerHandler, &streamChan, nil
 }
 
+// getDockerClient returns a pooled Docker client for the server the service is deployed on
+func (h *ServiceHandler) getDockerClient(ctx context.Context, tx pgx.Tx, service *models.Service) (*client.Client, error) {
+	// Get server details for connection
+	server, err := repository.GetServerByID(ctx, tx, service.ServerID, service.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("server not found")
+	}
+
+	// Get the credentials for the connection
+	credentials, err := generator.ServerCredentials(ctx, tx, *server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve server credentials: %w", err)
+	}
+
+	// Get Docker client from connection pool
+	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
+	connectionID := namingGenerator.ConnectionID()
+	// Build SSH connection string
+	sshHost := generator.ServerHost(*server)
+	dockerClient, err := h.ConnectionPool.GetDockerConnection(connectionID, sshHost, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Docker connection: %w", err)
+	}
+
+	return dockerClient, nil
+}
+
 // executeStartOperation handles the Docker compose start operation
-func (h *ServiceHandler) executeStartOperation(ctx context.Context, tx pgx.Tx, service *models.Service) (*core.StreamChan, error) {
+func (h *ServiceHandler) executeStartOperation(ctx context.Context, tx pgx.Tx, service *models.Service, volumeStrategy dockerutils.VolumeStrategy) (*core.StreamChan, error) {
 	// Setup Docker handler and streaming
 	dockerHandler, streamChan, err := h.setupDockerHandler(ctx, tx, service)
 	if err != nil {
 		return nil, err
 	}
 
+	// Refuse to start before anything is touched when volumes need a decision
+	if err := checkVolumeStrategy(ctx, dockerHandler, volumeStrategy); err != nil {
+		return nil, err
+	}
+
 	// Start the Docker compose operation
 	err = dockerHandler.StartDockerCompose(ctx)
 	if err != nil {
@@ -249,7 +310,7 @@ func (h *ServiceHandler) executeStartOperation(ctx context.Context, tx pgx.Tx, s
 }
 
 // executeStopOperation handles the Docker compose stop operation
-func (h *ServiceHandler) executeStopOperation(ctx context.Context, tx pgx.Tx, service *models.Service) (*core.StreamChan, error) {
+func (h *ServiceHandler) executeStopOperation(ctx context.Context, tx pgx.Tx, service *models.Service, purgeVolumes bool) (*core.StreamChan, error) {
 	// Setup Docker handler and streaming
 	dockerHandler, streamChan, err := h.setupDockerHandler(ctx, tx, service)
 	if err != nil {
@@ -257,7 +318,7 @@ func (h *ServiceHandler) executeStopOperation(ctx context.Context, tx pgx.Tx, se
 	}
 
 	// Stop the Docker compose operation
-	err = dockerHandler.StopDockerCompose(ctx)
+	err = dockerHandler.StopDockerCompose(ctx, purgeVolumes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stop Docker compose: %w", err)
 	}
@@ -267,13 +328,18 @@ func (h *ServiceHandler) executeStopOperation(ctx context.Context, tx pgx.Tx, se
 }
 
 // executeRestartOperation handles the Docker compose restart operation
-func (h *ServiceHandler) executeRestartOperation(ctx context.Context, tx pgx.Tx, service *models.Service) (*core.StreamChan, error) {
+func (h *ServiceHandler) executeRestartOperation(ctx context.Context, tx pgx.Tx, service *models.Service, volumeStrategy dockerutils.VolumeStrategy) (*core.StreamChan, error) {
 	// Setup Docker handler and streaming
 	dockerHandler, streamChan, err := h.setupDockerHandler(ctx, tx, service)
 	if err != nil {
 		return nil, err
 	}
 
+	// Refuse to restart before anything is stopped when volumes need a decision
+	if err := checkVolumeStrategy(ctx, dockerHandler, volumeStrategy); err != nil {
+		return nil, err
+	}
+
 	// Restart the Docker compose operation
 	err = dockerHandler.RestartDockerCompose(ctx)
 	if err != nil {
@@ -283,3 +349,28 @@ func (h *ServiceHandler) executeRestartOperation(ctx context.Context, tx pgx.Tx,
 	// Return the streaming result
 	return streamChan, nil
 }
+
+// checkVolumeStrategy sets the volume strategy on the handler and, when aborting,
+// checks up front that all existing volumes match the compose definition
+func checkVolumeStrategy(ctx context.Context, dockerHandler *dockerutils.DockerHandler, volumeStrategy dockerutils.VolumeStrategy) error {
+	dockerHandler.VolumeStrategy = volumeStrategy
+	if volumeStrategy != dockerutils.VolumeStrategyAbort {
+		return nil
+	}
+
+	results, err := dockerHandler.CheckVolumeCompatibility(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check volume compatibility: %w", err)
+	}
+
+	incompatible := dockerutils.IncompatibleVolumes(results)
+	if len(incompatible) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(incompatible))
+	for _, volume := range incompatible {
+		names = append(names, volume.Name)
+	}
+	return fmt.Errorf("%w: %s, use volume_strategy recreate or migrate", dockerutils.ErrIncompatibleVolumes, strings.Join(names, ", "))
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/models"
+)
+
+// StreamContainerStats handles SSE streaming of container stats until the client disconnects
+// or all stats streams ended
+func StreamContainerStats(ctx context.Context, w http.ResponseWriter, statsChan <-chan models.ContainerStats, doneChan <-chan struct{}) {
+	flusher := setupSSEHeaders(w)
+	if flusher == nil {
+		return
+	}
+
+	sendEvent := createEventSender(flusher, w)
+	sendEvent(core.LogInfo("Starting stats stream"))
+
+	// Keep proxies from closing the connection while containers are idle
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			zap.L().Info("Client disconnected from stats stream")
+			return
+		case <-doneChan:
+			sendEvent(core.LogInfo("Stats stream ended"))
+			return
+		case stats := <-statsChan:
+			sendEvent(core.LogMessage{Type: core.LogTypeStats, Data: stats})
+		case <-keepAlive.C:
+			w.Write([]byte(": keep-alive\n\n"))
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// +----------------------------------------------+
+// | Validate Compose                             |
+// +----------------------------------------------+
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/core"
+	"github.com/yorukot/starker/internal/core/dockerutils"
+	"github.com/yorukot/starker/internal/middleware"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/dockeryaml"
+	"github.com/yorukot/starker/pkg/generator"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// validateComposeRequest represents a request to check compose files before saving them
+type validateComposeRequest struct {
+	ComposeFile string               `json:"compose_file" validate:"required" example:"services:\n  web:\n    image: nginx:1.27"` // Main compose file content
+	Files       []composeFileRequest `json:"files,omitempty" validate:"omitempty,max=20,dive"`                                    // Additional compose files in load order
+	Profiles    []string             `json:"profiles,omitempty" validate:"omitempty,dive,required,max=100" example:"debug"`       // Compose profiles to activate
+	EnvFiles    *[]envFileRequest    `json:"env_files,omitempty" validate:"omitempty,max=20,dive"`                                // Env files env_file entries resolve against, defaults to the env files of the service
+	ServiceID   *string              `json:"service_id,omitempty" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                           // Service the files are for, its data path and server are used
+	ServerID    *string              `json:"server_id,omitempty" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                            // Server whose published ports are checked, defaults to the server of the service
+}
+
+// validateComposeResponse represents the result of checking compose files
+type validateComposeResponse struct {
+	Valid       bool                    `json:"valid" example:"true"` // Whether there are no errors, warnings do not prevent a deployment
+	Diagnostics []dockeryaml.Diagnostic `json:"diagnostics"`          // Errors and warnings found in the compose files
+}
+
+// ValidateCompose godoc
+// @Summary Validate compose files
+// @Description Loads the compose files like a deployment and returns the problems found with file, line and column: YAML syntax errors, compose specification errors,
+// @Description interpolation errors and the lint warnings of Starker (unpinned images, missing restart policy, privileged mode, host network, bind mounts outside the service data path).
+// @Description With service_id or server_id the published ports are checked against the running containers of the server. Nothing is saved.
+// @Tags service
+// @Accept json
+// @Produce json
+// @Param teamID path string true "Team ID"
+// @Param projectID path string true "Project ID"
+// @Param request body validateComposeRequest true "Compose validation request"
+// @Success 200 {object} response.SuccessResponse{data=validateComposeResponse} "Diagnostics of the compose files"
+// @Failure 400 {object} response.ErrorResponse "Invalid request body, team access denied, service or server not found"
+// @Failure 401 {object} response.ErrorResponse "User not authenticated"
+// @Failure 500 {object} response.ErrorResponse "Internal server error"
+// @Router /teams/{teamID}/projects/{projectID}/services/compose/validate [post]
+// @Security BearerAuth
+func (h *ServiceHandler) ValidateCompose(w http.ResponseWriter, r *http.Request) {
+	// Get URL parameters
+	teamID := chi.URLParam(r, "teamID")
+	projectID := chi.URLParam(r, "projectID")
+
+	// Decode and validate request body
+	var validateComposeRequest validateComposeRequest
+	if err := json.NewDecoder(r.Body).Decode(&validateComposeRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := validator.New().Struct(validateComposeRequest); err != nil {
+		response.RespondWithError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Get userID
+	userID := r.Context().Value(middleware.UserIDKey).(string)
+
+	// Start database transaction
+	tx, err := repository.StartTransaction(h.DB, r.Context())
+	if err != nil {
+		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+		return
+	}
+	defer repository.DeferRollback(tx, r.Context())
+
+	// Verify user has access to the team
+	hasAccess, err := repository.CheckTeamAccess(r.Context(), tx, teamID, userID)
+	if err != nil {
+		zap.L().Error("Failed to check team access", zap.Error(err))
+		response.RespondWithError(w, http.StatusInternalServerError, "Failed to check team access", "FAILED_TO_CHECK_TEAM_ACCESS")
+		return
+	}
+	if !hasAccess {
+		response.RespondWithError(w, http.StatusBadRequest, "Team access denied", "TEAM_ACCESS_DENIED")
+		return
+	}
+
+	// The service the files are for, a new service has no ID or server yet
+	service := models.Service{TeamID: teamID}
+	var envFiles []models.ServiceEnvFile
+	if validateComposeRequest.ServiceID != nil {
+		existing, err := repository.GetServiceByID(r.Context(), tx, *validateComposeRequest.ServiceID, teamID, projectID)
+		if err != nil {
+			zap.L().Error("Failed to find service", zap.Error(err))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to find service", "FAILED_TO_FIND_SERVICE")
+			return
+		}
+		if existing == nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Service not found", "SERVICE_NOT_FOUND")
+			return
+		}
+		service = *existing
+
+		envFiles, err = repository.GetServiceEnvFiles(r.Context(), tx, service.ID)
+		if err != nil {
+			zap.L().Error("Failed to get env files", zap.Error(err))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to get env files", "FAILED_TO_GET_ENV_FILES")
+			return
+		}
+	}
+	if validateComposeRequest.EnvFiles != nil {
+		envFiles = generateServiceEnvFiles(service.ID, *validateComposeRequest.EnvFiles)
+	}
+
+	if validateComposeRequest.ServerID != nil {
+		server, err := repository.GetServerByID(r.Context(), tx, *validateComposeRequest.ServerID, teamID)
+		if err != nil {
+			zap.L().Error("Failed to get server", zap.Error(err))
+			response.RespondWithError(w, http.StatusInternalServerError, "Failed to get server", "FAILED_TO_GET_SERVER")
+			return
+		}
+		if server == nil {
+			response.RespondWithError(w, http.StatusBadRequest, "Server not found", "SERVER_NOT_FOUND")
+			return
+		}
+		service.ServerID = server.ID
+	}
+
+	// Load the compose files the way a deployment does
+	files := dockerutils.ComposeFiles(models.ServiceComposeConfig{
+		ComposeFile: validateComposeRequest.ComposeFile,
+		Files:       generateServiceComposeFiles(service.ID, validateComposeRequest.Files),
+	})
+	namingGenerator := generator.NewNamingGenerator(service.ID, service.TeamID, service.ServerID)
+	project, diagnostics := dockeryaml.Diagnose(files, dockerutils.EnvFiles(envFiles), validateComposeRequest.Profiles, namingGenerator.ProjectName())
+
+	if project != nil {
+		// Without a service the data path is the directory of all services
+		diagnostics = append(diagnostics, dockeryaml.Lint(project, namingGenerator.GenerateServiceDataPath())...)
+
+		if service.ServerID != "" {
+			diagnostics = append(diagnostics, h.portDiagnostics(r.Context(), tx, &service, project, namingGenerator)...)
+		}
+	}
+	diagnostics = dockeryaml.Locate(files, diagnostics)
+
+	valid := true
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == dockeryaml.SeverityError {
+			valid = false
+		}
+	}
+
+	// Commit transaction
+	repository.CommitTransaction(tx, r.Context())
+
+	response.RespondWithJSON(w, http.StatusOK, validateComposeResponse{Valid: valid, Diagnostics: diagnostics})
+}
+
+// portDiagnostics warns about published ports already used on the server, a server that can not be reached is a warning as well
+func (h *ServiceHandler) portDiagnostics(ctx context.Context, tx pgx.Tx, service *models.Service, project *types.Project, namingGenerator *generator.NamingGenerator) []dockeryaml.Diagnostic {
+	dockerClient, err := h.getDockerClient(ctx, tx, service)
+	if err != nil {
+		zap.L().Warn("Failed to get Docker client for compose validation", zap.Error(err))
+		return []dockeryaml.Diagnostic{{
+			Severity: dockeryaml.SeverityWarning,
+			Code:     dockeryaml.LintPortInUse,
+			Message:  fmt.Sprintf("published ports were not checked, the server could not be reached: %v", err),
+		}}
+	}
+
+	dockerHandler := &dockerutils.DockerHandler{
+		Client:          dockerClient,
+		Project:         project,
+		NamingGenerator: namingGenerator,
+		StreamChan:      core.NewStreamChan(),
+	}
+	conflicts, err := dockerHandler.PortConflicts(ctx)
+	if err != nil {
+		zap.L().Warn("Failed to check published ports", zap.Error(err))
+		return []dockeryaml.Diagnostic{{
+			Severity: dockeryaml.SeverityWarning,
+			Code:     dockeryaml.LintPortInUse,
+			Message:  fmt.Sprintf("published ports were not checked: %v", err),
+		}}
+	}
+
+	diagnostics := []dockeryaml.Diagnostic{}
+	for _, conflict := range conflicts {
+		diagnostics = append(diagnostics, dockeryaml.Diagnostic{
+			Severity: dockeryaml.SeverityWarning,
+			Code:     dockeryaml.LintPortInUse,
+			Message:  fmt.Sprintf("port %d/%s of service %s is already published by %s", conflict.HostPort, conflict.Protocol, conflict.Service, conflict.Container),
+			Path:     "services." + conflict.Service + ".ports",
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/yorukot/starker/internal/config"
+	"github.com/yorukot/starker/internal/core/wsticket"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/pkg/response"
+)
+
+// WebSocketAuthMiddleware authenticates a WebSocket handshake with the Authorization header or, since browsers can not set it,
+// with a ticket issued for the resource in the URL parameter resourceParam
+func WebSocketAuthMiddleware(db *pgxpool.Pool, resourceType, resourceParam string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				AuthRequiredMiddleware(next).ServeHTTP(w, r)
+				return
+			}
+
+			ticket := wsticket.FromRequest(r)
+			if ticket == "" {
+				response.RespondWithError(w, http.StatusUnauthorized, "Unauthorized", "UNAUTHORIZED")
+				return
+			}
+
+			// Start database transaction
+			tx, err := repository.StartTransaction(db, r.Context())
+			if err != nil {
+				zap.L().Error("Failed to begin transaction", zap.Error(err))
+				response.RespondWithError(w, http.StatusInternalServerError, "Failed to begin transaction", "FAILED_TO_BEGIN_TRANSACTION")
+				return
+			}
+			defer repository.DeferRollback(tx, r.Context())
+
+			userID, err := wsticket.Consume(r.Context(), tx, ticket, resourceType, chi.URLParam(r, resourceParam))
+			if err != nil {
+				zap.L().Error("Failed to consume WebSocket ticket", zap.Error(err))
+				response.RespondWithError(w, http.StatusInternalServerError, "Failed to consume ticket", "FAILED_TO_CONSUME_TICKET")
+				return
+			}
+			if userID == "" {
+				response.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired ticket", "INVALID_TICKET")
+				return
+			}
+
+			// Commit transaction so the ticket can not be used again
+			repository.CommitTransaction(tx, r.Context())
+
+			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WebSocketHandshake accepts handshakes from the frontend CORS allows only, so other sites can not open WebSockets in the name of a user.
+// Clients that are not browsers send no Origin and are accepted. The ticket subprotocol is selected when offered.
+func WebSocketHandshake(websocketConfig *websocket.Config, r *http.Request) error {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		originURL, err := url.Parse(origin)
+		if err != nil || originURL.Host != config.Env().FrontendDomain || (originURL.Scheme != "http" && originURL.Scheme != "https") {
+			return fmt.Errorf("origin %s is not allowed", origin)
+		}
+		websocketConfig.Origin = originURL
+	}
+
+	// The selected subprotocol has to be one the client offered, the ticket itself is not echoed
+	if slices.Contains(websocketConfig.Protocol, wsticket.Protocol) {
+		websocketConfig.Protocol = []string{wsticket.Protocol}
+	} else {
+		websocketConfig.Protocol = nil
+	}
+	return nil
+}
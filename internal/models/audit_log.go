@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditActionContainerExec       AuditAction = "container.exec"
+	AuditActionServerTerminal      AuditAction = "server.terminal"
+	AuditActionServerDockerPrune   AuditAction = "server.docker_prune"
+	AuditActionServerOrphanApprove AuditAction = "server.orphan_approve"
+	AuditActionServiceAdopt        AuditAction = "service.adopt"
+	AuditActionServiceExport       AuditAction = "service.export"
+)
+
+// AuditLog records a sensitive action a user performed on a team resource
+type AuditLog struct {
+	ID           string            `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`           // Unique identifier for the audit log entry
+	TeamID       string            `json:"team_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`      // Associated team ID
+	UserID       string            `json:"user_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`      // User who performed the action
+	Action       AuditAction       `json:"action" example:"container.exec"`                   // Action that was performed
+	ResourceType string            `json:"resource_type" example:"service_container"`         // Type of the resource the action was performed on
+	ResourceID   string            `json:"resource_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`  // ID of the resource the action was performed on
+	Metadata     map[string]string `json:"metadata,omitempty"`                                // Extra details about the action
+	IP           *string           `json:"ip,omitempty" example:"203.0.113.10"`               // IP address of the user
+	EndedAt      *time.Time        `json:"ended_at,omitempty" example:"2023-01-01T12:05:00Z"` // Timestamp when the session ended
+	CreatedAt    time.Time         `json:"created_at" example:"2023-01-01T12:00:00Z"`         // Timestamp when the action started
+}
@@ -19,6 +19,17 @@ type RefreshToken struct {
 	CreatedAt time.Time  `json:"created_at" example:"2023-01-01T12:00:00Z"`                                                   // Timestamp when the token was created
 }
 
+// WebSocketTicket is a short lived single use credential for opening a WebSocket, browsers can not send the Authorization header on the handshake
+type WebSocketTicket struct {
+	ID           string    `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`          // Unique identifier for the ticket
+	TicketHash   string    `json:"-"`                                                // SHA-256 hash of the ticket, the ticket itself is not stored
+	UserID       string    `json:"user_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`     // User the ticket authenticates
+	ResourceType string    `json:"resource_type" example:"service_container"`        // Type of the resource the ticket opens a WebSocket for
+	ResourceID   string    `json:"resource_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // ID of the resource the ticket opens a WebSocket for
+	ExpiresAt    time.Time `json:"expires_at" example:"2023-01-01T12:00:30Z"`        // Timestamp after which the ticket is rejected
+	CreatedAt    time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`        // Timestamp when the ticket was issued
+}
+
 // Provider represents the authentication provider type
 type Provider string
 
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type StatsResolution string
+
+const (
+	StatsResolutionLive   StatsResolution = "live"   // Streamed directly from Docker, never stored
+	StatsResolutionMinute StatsResolution = "minute" // Raw samples, kept for 24 hours
+	StatsResolutionHour   StatsResolution = "hour"   // Hourly rollups, kept for 7 days
+)
+
+// ContainerStats is a resource usage sample of a single container
+type ContainerStats struct {
+	ID            string          `json:"id,omitempty" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Unique identifier of the stored sample
+	ServiceID     string          `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`   // Associated service ID
+	ContainerName string          `json:"container_name" example:"web-app-container"`        // Docker container name
+	Resolution    StatsResolution `json:"resolution" example:"minute"`                       // Resolution of the sample
+	CPUPercent    float64         `json:"cpu_percent" example:"12.5"`                        // CPU usage, 100 means one full core
+	MemoryUsage   int64           `json:"memory_usage" example:"104857600"`                  // Memory usage in bytes without page cache
+	MemoryLimit   int64           `json:"memory_limit" example:"2147483648"`                 // Memory limit in bytes
+	MemoryPercent float64         `json:"memory_percent" example:"4.88"`                     // Memory usage relative to the limit
+	NetworkRx     int64           `json:"network_rx" example:"1048576"`                      // Total bytes received
+	NetworkTx     int64           `json:"network_tx" example:"524288"`                       // Total bytes sent
+	BlockRead     int64           `json:"block_read" example:"4096"`                         // Total bytes read from block devices
+	BlockWrite    int64           `json:"block_write" example:"8192"`                        // Total bytes written to block devices
+	SampledAt     time.Time       `json:"sampled_at" example:"2023-01-01T12:00:00Z"`         // Timestamp of the sample
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// OrphanedResourceType is the kind of Docker resource that was left behind
+type OrphanedResourceType string
+
+const (
+	OrphanedResourceContainer OrphanedResourceType = "container"
+	OrphanedResourceNetwork   OrphanedResourceType = "network"
+	OrphanedResourceVolume    OrphanedResourceType = "volume"
+)
+
+// OrphanedResource is a Docker resource labelled with a Starker service that no longer exists
+type OrphanedResource struct {
+	ID           string               `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`              // Unique identifier for the orphaned resource
+	ServerID     string               `json:"server_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`       // Server the resource is on
+	ServiceID    string               `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`      // Deleted service the resource was labelled with
+	ResourceType OrphanedResourceType `json:"resource_type" example:"container"`                    // Kind of Docker resource
+	ResourceID   string               `json:"resource_id" example:"abc123def456"`                   // Docker ID of the resource, the name for volumes
+	Name         string               `json:"name" example:"web-01ARZ3NDEKTSV4RRFFQ69G5FAV"`        // Docker name of the resource
+	ApprovedAt   *time.Time           `json:"approved_at,omitempty" example:"2023-01-01T12:00:00Z"` // Timestamp when the removal of the volume was approved
+	RemovalError *string              `json:"removal_error,omitempty" example:"volume is in use"`   // Error of the last removal attempt
+	FirstSeenAt  time.Time            `json:"first_seen_at" example:"2023-01-01T12:00:00Z"`         // Timestamp when the resource was first found orphaned
+	LastSeenAt   time.Time            `json:"last_seen_at" example:"2023-01-01T12:00:00Z"`          // Timestamp when the resource was last found orphaned
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ServerDiskUsage is the usage of a single mounted filesystem
+type ServerDiskUsage struct {
+	Filesystem string `json:"filesystem" example:"/dev/sda1"`  // Device or filesystem name
+	Mount      string `json:"mount" example:"/"`               // Mount point
+	Total      int64  `json:"total" example:"107374182400"`    // Size in bytes
+	Used       int64  `json:"used" example:"53687091200"`      // Used bytes
+	Available  int64  `json:"available" example:"53687091200"` // Available bytes
+}
+
+// ServerMetrics is a host metrics sample of a server
+type ServerMetrics struct {
+	ID                   string            `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`        // Unique identifier for the sample
+	ServerID             string            `json:"server_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated server ID
+	Load1                float64           `json:"load1" example:"0.42"`                           // Load average over 1 minute
+	Load5                float64           `json:"load5" example:"0.35"`                           // Load average over 5 minutes
+	Load15               float64           `json:"load15" example:"0.30"`                          // Load average over 15 minutes
+	CPUCount             int               `json:"cpu_count" example:"4"`                          // Number of online CPUs
+	MemoryTotal          int64             `json:"memory_total" example:"8589934592"`              // Total memory in bytes
+	MemoryAvailable      int64             `json:"memory_available" example:"4294967296"`          // Available memory in bytes
+	SwapTotal            int64             `json:"swap_total" example:"2147483648"`                // Total swap in bytes
+	SwapFree             int64             `json:"swap_free" example:"2147483648"`                 // Free swap in bytes
+	Disks                []ServerDiskUsage `json:"disks"`                                          // Usage per mounted filesystem
+	DockerImagesSize     int64             `json:"docker_images_size" example:"2147483648"`        // Disk used by Docker images
+	DockerContainersSize int64             `json:"docker_containers_size" example:"104857600"`     // Disk used by container writable layers
+	DockerVolumesSize    int64             `json:"docker_volumes_size" example:"1073741824"`       // Disk used by Docker volumes
+	DockerBuildCacheSize int64             `json:"docker_build_cache_size" example:"536870912"`    // Disk used by the build cache
+	SampledAt            time.Time         `json:"sampled_at" example:"2023-01-01T12:00:00Z"`      // Timestamp of the sample
+}
@@ -23,27 +23,43 @@ const (
 
 // Service represents a service definition with Docker containers
 type Service struct {
-	ID             string       `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                      // Unique identifier for the service
-	TeamID         string       `json:"team_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                 // Associated team ID
-	ServerID       string       `json:"server_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`               // Associated server ID
-	ProjectID      string       `json:"project_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`              // Associated project ID
-	Name           string       `json:"name" example:"web-app"`                                       // Service name
-	Description    *string      `json:"description,omitempty" example:"Main web application service"` // Service description
-	Type           string       `json:"type" example:"docker"`                                        // Service type (e.g., docker, compose)
-	State          ServiceState `json:"state" example:"running"`                                      // Service state (running, stopped, etc.)
-	ContainerID    *string      `json:"container_id,omitempty" example:"abc123..."`                   // Docker container ID
-	LastDeployedAt *time.Time   `json:"last_deployed_at,omitempty" example:"2023-01-01T12:00:00Z"`    // Timestamp when the service was last deployed
-	CreatedAt      time.Time    `json:"created_at" example:"2023-01-01T12:00:00Z"`                    // Timestamp when the service was created
-	UpdatedAt      time.Time    `json:"updated_at" example:"2023-01-01T12:00:00Z"`                    // Timestamp when the service was last updated
+	ID               string       `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                      // Unique identifier for the service
+	TeamID           string       `json:"team_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                 // Associated team ID
+	ServerID         string       `json:"server_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`               // Associated server ID
+	ProjectID        string       `json:"project_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`              // Associated project ID
+	Name             string       `json:"name" example:"web-app"`                                       // Service name
+	Description      *string      `json:"description,omitempty" example:"Main web application service"` // Service description
+	Type             string       `json:"type" example:"docker"`                                        // Service type (e.g., docker, compose)
+	State            ServiceState `json:"state" example:"running"`                                      // Service state (running, stopped, etc.)
+	ContainerID      *string      `json:"container_id,omitempty" example:"abc123..."`                   // Docker container ID
+	LastDeployedAt   *time.Time   `json:"last_deployed_at,omitempty" example:"2023-01-01T12:00:00Z"`    // Timestamp when the service was last deployed
+	AutoUpdate       bool         `json:"auto_update" example:"false"`                                  // Whether the service is redeployed when its image tags point to a new digest
+	AutoUpdateWindow *string      `json:"auto_update_window,omitempty" example:"02:00-05:00"`           // UTC time window automatic updates are deployed in, any time when empty
+	CreatedAt        time.Time    `json:"created_at" example:"2023-01-01T12:00:00Z"`                    // Timestamp when the service was created
+	UpdatedAt        time.Time    `json:"updated_at" example:"2023-01-01T12:00:00Z"`                    // Timestamp when the service was last updated
 }
 
 // ServiceComposeConfig represents Docker compose configurations
 type ServiceComposeConfig struct {
-	ID          string    `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`         // Unique identifier for the compose config
-	ServiceID   string    `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated service ID
-	ComposeFile string    `json:"compose_file" example:"version: '3.8'..."`        // Docker compose file content
-	CreatedAt   time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the config was created
-	UpdatedAt   time.Time `json:"updated_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the config was last updated
+	ID          string               `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`         // Unique identifier for the compose config
+	ServiceID   string               `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated service ID
+	ComposeFile string               `json:"compose_file" example:"version: '3.8'..."`        // Docker compose file content
+	Profiles    []string             `json:"profiles" example:"debug"`                        // Compose profiles activated on deploy
+	Files       []ServiceComposeFile `json:"files"`                                           // Additional compose files in load order
+	CreatedAt   time.Time            `json:"created_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the config was created
+	UpdatedAt   time.Time            `json:"updated_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the config was last updated
+}
+
+// ServiceComposeFile represents an additional compose file of a service, loaded after the main compose file
+type ServiceComposeFile struct {
+	ID        string    `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`         // Unique identifier for the compose file
+	ServiceID string    `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated service ID
+	Filename  string    `json:"filename" example:"docker-compose.prod.yml"`      // Relative path extends and include reference the file by
+	Content   string    `json:"content" example:"services:..."`                  // Docker compose file content
+	Override  bool      `json:"override" example:"true"`                         // Whether it is merged on top of the main file, otherwise only extends and include read it
+	Position  int       `json:"position" example:"1"`                            // Load order after the main compose file
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the file was created
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the file was last updated
 }
 
 // ServiceSourceGit represents Git source configuration for services
@@ -70,14 +86,49 @@ type ServiceContainer struct {
 	CreatedAt     time.Time      `json:"created_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the container were created
 }
 
-// ServiceImage represents Docker images associated with a service
+// ServiceImage represents a Docker image pulled for a service in a deployment
 type ServiceImage struct {
-	ID        string    `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`         // Unique identifier for the image record
-	ServiceID string    `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated service ID
-	ImageID   *string   `json:"image_id,omitempty" example:"sha256:abc123..."`   // Docker image ID
-	ImageName string    `json:"image_name" example:"nginx:latest"`               // Docker image name
-	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the image was last updated
-	CreatedAt time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the image was created
+	ID           string    `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`                // Unique identifier for the image record
+	ServiceID    string    `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`        // Associated service ID
+	ImageID      *string   `json:"image_id,omitempty" example:"sha256:abc123..."`          // Docker image ID
+	ImageName    string    `json:"image_name" example:"nginx:latest"`                      // Docker image name
+	RepoDigest   *string   `json:"repo_digest,omitempty" example:"nginx@sha256:abc123..."` // Repository digest the image was pulled as, the containers are pinned to it
+	ServiceName  string    `json:"service_name" example:"web"`                             // Compose service that uses the image
+	DeploymentID string    `json:"deployment_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`     // Deployment the image was pulled for
+	UpdatedAt    time.Time `json:"updated_at" example:"2023-01-01T12:00:00Z"`              // Timestamp when the image was last updated
+	CreatedAt    time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`              // Timestamp when the image was created
+}
+
+// ServiceImageUpdate is a newer digest found in the registry for the image tag a compose service runs
+type ServiceImageUpdate struct {
+	ID              string    `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`         // Unique identifier for the update
+	ServiceID       string    `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated service ID
+	ServiceName     string    `json:"service_name" example:"web"`                      // Compose service that runs the image
+	ImageName       string    `json:"image_name" example:"nginx:1.27"`                 // Image tag as written in the compose file
+	CurrentDigest   string    `json:"current_digest" example:"sha256:abc123..."`       // Digest the running deployment is pinned to
+	AvailableDigest string    `json:"available_digest" example:"sha256:def456..."`     // Digest the tag points to in the registry
+	DetectedAt      time.Time `json:"detected_at" example:"2023-01-01T12:00:00Z"`      // Timestamp when the available digest was first found
+	CheckedAt       time.Time `json:"checked_at" example:"2023-01-01T12:00:00Z"`       // Timestamp of the last registry check
+}
+
+// ServiceEventType is the kind of event that happened to a service
+type ServiceEventType string
+
+const (
+	ServiceEventImageUpdateAvailable ServiceEventType = "image_update.available" // A tag the service runs points to a new digest
+	ServiceEventImageUpdateDeployed  ServiceEventType = "image_update.deployed"  // The service was redeployed with the new digests
+	ServiceEventImageUpdateFailed    ServiceEventType = "image_update.failed"    // Redeploying the service with the new digests failed
+)
+
+// ServiceEvent is a notification about something that happened to a service without a user triggering it
+type ServiceEvent struct {
+	ID        string            `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`              // Unique identifier for the event
+	TeamID    string            `json:"team_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`         // Associated team ID
+	ServiceID string            `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`      // Associated service ID
+	Type      ServiceEventType  `json:"type" example:"image_update.available"`                // Kind of event
+	Message   string            `json:"message" example:"nginx:1.27 of web has a new digest"` // Human readable description
+	Metadata  map[string]string `json:"metadata,omitempty"`                                   // Extra details about the event
+	CreatedAt time.Time         `json:"created_at" example:"2023-01-01T12:00:00Z"`            // Timestamp when the event happened
 }
 
 // ServiceNetwork represents Docker networks associated with a service
@@ -116,6 +167,17 @@ type ServiceEnvironment struct {
 	ServiceID string    `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated service ID
 	Key       string    `json:"key" example:"NODE_ENV"`                          // Environment variable key
 	Value     string    `json:"value" example:"production"`                      // Environment variable value
+	Comment   *string   `json:"comment,omitempty" example:"Runtime mode"`        // Comment written above the variable in dotenv files
 	CreatedAt time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the environment variable was created
 	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the environment variable was last updated
 }
+
+// ServiceEnvFile represents a named env file that env_file entries of the compose files resolve against
+type ServiceEnvFile struct {
+	ID        string    `json:"id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"`         // Unique identifier for the env file
+	ServiceID string    `json:"service_id" example:"01ARZ3NDEKTSV4RRFFQ69G5FAV"` // Associated service ID
+	Name      string    `json:"name" example:".env.production"`                  // Relative path env_file references the file by
+	Content   string    `json:"content" example:"NODE_ENV=production"`           // Dotenv content
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the env file was created
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T12:00:00Z"`       // Timestamp when the env file was last updated
+}
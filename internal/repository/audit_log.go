@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// CreateAuditLog creates a new audit log entry
+func CreateAuditLog(ctx context.Context, db pgx.Tx, auditLog models.AuditLog) error {
+	query := `
+		INSERT INTO audit_logs (id, team_id, user_id, action, resource_type, resource_id, metadata, ip, ended_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := db.Exec(ctx, query,
+		auditLog.ID,
+		auditLog.TeamID,
+		auditLog.UserID,
+		auditLog.Action,
+		auditLog.ResourceType,
+		auditLog.ResourceID,
+		auditLog.Metadata,
+		auditLog.IP,
+		auditLog.EndedAt,
+		auditLog.CreatedAt,
+	)
+	return err
+}
+
+// EndAuditLog marks a session audit log entry as ended and merges the extra metadata
+func EndAuditLog(ctx context.Context, db pgx.Tx, auditLogID string, endedAt time.Time, metadata map[string]string) error {
+	query := `
+		UPDATE audit_logs
+		SET ended_at = $2, metadata = COALESCE(metadata, '{}'::jsonb) || $3::jsonb
+		WHERE id = $1
+	`
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	_, err := db.Exec(ctx, query, auditLogID, endedAt, metadata)
+	return err
+}
+
+// GetAuditLogByID gets an audit log entry by ID and team ID
+func GetAuditLogByID(ctx context.Context, db pgx.Tx, auditLogID, teamID string) (*models.AuditLog, error) {
+	query := `
+		SELECT id, team_id, user_id, action, resource_type, resource_id, metadata, ip, ended_at, created_at
+		FROM audit_logs
+		WHERE id = $1 AND team_id = $2
+	`
+	var auditLog models.AuditLog
+	err := db.QueryRow(ctx, query, auditLogID, teamID).Scan(
+		&auditLog.ID,
+		&auditLog.TeamID,
+		&auditLog.UserID,
+		&auditLog.Action,
+		&auditLog.ResourceType,
+		&auditLog.ResourceID,
+		&auditLog.Metadata,
+		&auditLog.IP,
+		&auditLog.EndedAt,
+		&auditLog.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil // No audit log found
+		}
+		return nil, err
+	}
+
+	return &auditLog, nil
+}
+
+// GetAuditLogsByResource gets all audit log entries of an action on a resource, newest first
+func GetAuditLogsByResource(ctx context.Context, db pgx.Tx, teamID, resourceID string, action models.AuditAction) ([]models.AuditLog, error) {
+	query := `
+		SELECT id, team_id, user_id, action, resource_type, resource_id, metadata, ip, ended_at, created_at
+		FROM audit_logs
+		WHERE team_id = $1 AND resource_id = $2 AND action = $3
+		ORDER BY created_at DESC
+	`
+	rows, err := db.Query(ctx, query, teamID, resourceID, action)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	auditLogs := []models.AuditLog{}
+	for rows.Next() {
+		var auditLog models.AuditLog
+		err := rows.Scan(
+			&auditLog.ID,
+			&auditLog.TeamID,
+			&auditLog.UserID,
+			&auditLog.Action,
+			&auditLog.ResourceType,
+			&auditLog.ResourceID,
+			&auditLog.Metadata,
+			&auditLog.IP,
+			&auditLog.EndedAt,
+			&auditLog.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	return auditLogs, rows.Err()
+}
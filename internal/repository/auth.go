@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
@@ -89,3 +90,49 @@ func UpdateRefreshTokenUsedAt(ctx context.Context, db pgx.Tx, refreshToken model
 	_, err := db.Exec(ctx, query, refreshToken.UsedAt, refreshToken.ID)
 	return err
 }
+
+// CreateWebSocketTicket creates a new WebSocket ticket
+func CreateWebSocketTicket(ctx context.Context, db pgx.Tx, ticket models.WebSocketTicket) error {
+	query := `
+		INSERT INTO websocket_tickets (id, ticket_hash, user_id, resource_type, resource_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := db.Exec(ctx,
+		query,
+		ticket.ID,
+		ticket.TicketHash,
+		ticket.UserID,
+		ticket.ResourceType,
+		ticket.ResourceID,
+		ticket.ExpiresAt,
+		ticket.CreatedAt,
+	)
+	return err
+}
+
+// ConsumeWebSocketTicket deletes the ticket of the hash and returns it when it is issued for the resource and not expired.
+// The ticket is deleted in any case, so it can only be used once.
+func ConsumeWebSocketTicket(ctx context.Context, db pgx.Tx, ticketHash, resourceType, resourceID string, now time.Time) (*models.WebSocketTicket, error) {
+	query := `
+		DELETE FROM websocket_tickets WHERE ticket_hash = $1
+		RETURNING id, ticket_hash, user_id, resource_type, resource_id, expires_at, created_at
+	`
+	var ticket models.WebSocketTicket
+	err := pgxscan.Get(ctx, db, &ticket, query, ticketHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if ticket.ResourceType != resourceType || ticket.ResourceID != resourceID || !now.Before(ticket.ExpiresAt) {
+		return nil, nil
+	}
+	return &ticket, nil
+}
+
+// DeleteExpiredWebSocketTickets deletes the tickets that expired before the time
+func DeleteExpiredWebSocketTickets(ctx context.Context, db pgx.Tx, before time.Time) error {
+	query := `DELETE FROM websocket_tickets WHERE expires_at < $1`
+	_, err := db.Exec(ctx, query, before)
+	return err
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/segmentio/ksuid"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// CreateContainerStats stores a container stats sample, a sample for the same time is ignored
+func CreateContainerStats(ctx context.Context, db pgx.Tx, stats models.ContainerStats) error {
+	query := `
+		INSERT INTO container_stats (id, service_id, container_name, resolution, cpu_percent, memory_usage, memory_limit,
+		                             memory_percent, network_rx, network_tx, block_read, block_write, sampled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (service_id, container_name, resolution, sampled_at) DO NOTHING
+	`
+	_, err := db.Exec(ctx, query,
+		stats.ID,
+		stats.ServiceID,
+		stats.ContainerName,
+		stats.Resolution,
+		stats.CPUPercent,
+		stats.MemoryUsage,
+		stats.MemoryLimit,
+		stats.MemoryPercent,
+		stats.NetworkRx,
+		stats.NetworkTx,
+		stats.BlockRead,
+		stats.BlockWrite,
+		stats.SampledAt,
+	)
+	return err
+}
+
+// GetContainerStatsHistory gets the stored samples of a service since the given time, oldest first
+func GetContainerStatsHistory(ctx context.Context, db pgx.Tx, serviceID string, resolution models.StatsResolution, since time.Time) ([]models.ContainerStats, error) {
+	query := `
+		SELECT id, service_id, container_name, resolution, cpu_percent, memory_usage, memory_limit,
+		       memory_percent, network_rx, network_tx, block_read, block_write, sampled_at
+		FROM container_stats
+		WHERE service_id = $1 AND resolution = $2 AND sampled_at >= $3
+		ORDER BY sampled_at ASC, container_name ASC
+	`
+	rows, err := db.Query(ctx, query, serviceID, resolution, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.ContainerStats{}
+	for rows.Next() {
+		var stats models.ContainerStats
+		err := rows.Scan(
+			&stats.ID,
+			&stats.ServiceID,
+			&stats.ContainerName,
+			&stats.Resolution,
+			&stats.CPUPercent,
+			&stats.MemoryUsage,
+			&stats.MemoryLimit,
+			&stats.MemoryPercent,
+			&stats.NetworkRx,
+			&stats.NetworkTx,
+			&stats.BlockRead,
+			&stats.BlockWrite,
+			&stats.SampledAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, stats)
+	}
+
+	return history, rows.Err()
+}
+
+// RollupContainerStats aggregates the minute samples between from and to into hourly samples.
+// Usage values are averaged, cumulative counters keep their highest value. Hours that were
+// already rolled up are skipped, so overlapping ranges are safe.
+func RollupContainerStats(ctx context.Context, db pgx.Tx, from, to time.Time) error {
+	query := `
+		SELECT service_id, container_name, date_trunc('hour', sampled_at) AS hour,
+		       AVG(cpu_percent), AVG(memory_usage)::bigint, MAX(memory_limit), AVG(memory_percent),
+		       MAX(network_rx), MAX(network_tx), MAX(block_read), MAX(block_write)
+		FROM container_stats
+		WHERE resolution = $1 AND sampled_at >= $2 AND sampled_at < $3
+		GROUP BY service_id, container_name, hour
+	`
+	rows, err := db.Query(ctx, query, models.StatsResolutionMinute, from, to)
+	if err != nil {
+		return err
+	}
+
+	var rollups []models.ContainerStats
+	for rows.Next() {
+		stats := models.ContainerStats{
+			ID:         ksuid.New().String(),
+			Resolution: models.StatsResolutionHour,
+		}
+		err := rows.Scan(
+			&stats.ServiceID,
+			&stats.ContainerName,
+			&stats.SampledAt,
+			&stats.CPUPercent,
+			&stats.MemoryUsage,
+			&stats.MemoryLimit,
+			&stats.MemoryPercent,
+			&stats.NetworkRx,
+			&stats.NetworkTx,
+			&stats.BlockRead,
+			&stats.BlockWrite,
+		)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		rollups = append(rollups, stats)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, stats := range rollups {
+		if err := CreateContainerStats(ctx, db, stats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteContainerStatsBefore deletes the samples of a resolution older than the given time
+func DeleteContainerStatsBefore(ctx context.Context, db pgx.Tx, resolution models.StatsResolution, before time.Time) error {
+	query := `DELETE FROM container_stats WHERE resolution = $1 AND sampled_at < $2`
+	_, err := db.Exec(ctx, query, resolution, before)
+	return err
+}
+
+// DeleteServiceContainerStats deletes all stats samples of a service
+func DeleteServiceContainerStats(ctx context.Context, db pgx.Tx, serviceID string) error {
+	query := `DELETE FROM container_stats WHERE service_id = $1`
+	_, err := db.Exec(ctx, query, serviceID)
+	return err
+}
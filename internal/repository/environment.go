@@ -16,7 +16,7 @@ import (
 // GetServiceEnvironments gets all environment variables for a service
 func GetServiceEnvironments(ctx context.Context, db pgx.Tx, serviceID string) ([]models.ServiceEnvironment, error) {
 	query := `
-		SELECT id, service_id, key, value, created_at, updated_at
+		SELECT id, service_id, key, value, comment, created_at, updated_at
 		FROM service_environments
 		WHERE service_id = $1
 		ORDER BY key ASC
@@ -35,6 +35,7 @@ func GetServiceEnvironments(ctx context.Context, db pgx.Tx, serviceID string) ([
 			&env.ServiceID,
 			&env.Key,
 			&env.Value,
+			&env.Comment,
 			&env.CreatedAt,
 			&env.UpdatedAt,
 		)
@@ -50,7 +51,7 @@ func GetServiceEnvironments(ctx context.Context, db pgx.Tx, serviceID string) ([
 // GetServiceEnvironment gets a single environment variable by ID and service ID
 func GetServiceEnvironment(ctx context.Context, db pgx.Tx, id int64, serviceID string) (*models.ServiceEnvironment, error) {
 	query := `
-		SELECT id, service_id, key, value, created_at, updated_at
+		SELECT id, service_id, key, value, comment, created_at, updated_at
 		FROM service_environments
 		WHERE id = $1 AND service_id = $2
 	`
@@ -60,6 +61,7 @@ func GetServiceEnvironment(ctx context.Context, db pgx.Tx, id int64, serviceID s
 		&env.ServiceID,
 		&env.Key,
 		&env.Value,
+		&env.Comment,
 		&env.CreatedAt,
 		&env.UpdatedAt,
 	)
@@ -76,14 +78,15 @@ func GetServiceEnvironment(ctx context.Context, db pgx.Tx, id int64, serviceID s
 // CreateServiceEnvironment creates a new environment variable
 func CreateServiceEnvironment(ctx context.Context, db pgx.Tx, env models.ServiceEnvironment) error {
 	query := `
-		INSERT INTO service_environments (id, service_id, key, value, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO service_environments (id, service_id, key, value, comment, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 	_, err := db.Exec(ctx, query,
 		env.ID,
 		env.ServiceID,
 		env.Key,
 		env.Value,
+		env.Comment,
 		env.CreatedAt,
 		env.UpdatedAt,
 	)
@@ -97,8 +100,8 @@ func CreateServiceEnvironments(ctx context.Context, db pgx.Tx, environments []mo
 	}
 
 	query := `
-		INSERT INTO service_environments (id, service_id, key, value, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO service_environments (id, service_id, key, value, comment, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	for _, env := range environments {
@@ -107,6 +110,7 @@ func CreateServiceEnvironments(ctx context.Context, db pgx.Tx, environments []mo
 			env.ServiceID,
 			env.Key,
 			env.Value,
+			env.Comment,
 			env.CreatedAt,
 			env.UpdatedAt,
 		)
@@ -121,7 +125,7 @@ func CreateServiceEnvironments(ctx context.Context, db pgx.Tx, environments []mo
 func UpdateServiceEnvironment(ctx context.Context, db pgx.Tx, env models.ServiceEnvironment) error {
 	query := `
 		UPDATE service_environments
-		SET key = $3, value = $4, updated_at = $5
+		SET key = $3, value = $4, comment = $5, updated_at = $6
 		WHERE id = $1 AND service_id = $2
 	`
 	result, err := db.Exec(ctx, query,
@@ -129,6 +133,7 @@ func UpdateServiceEnvironment(ctx context.Context, db pgx.Tx, env models.Service
 		env.ServiceID,
 		env.Key,
 		env.Value,
+		env.Comment,
 		env.UpdatedAt,
 	)
 	if err != nil {
@@ -151,7 +156,7 @@ func UpdateServiceEnvironments(ctx context.Context, db pgx.Tx, environments []mo
 
 	query := `
 		UPDATE service_environments
-		SET key = $3, value = $4, updated_at = $5
+		SET key = $3, value = $4, comment = $5, updated_at = $6
 		WHERE id = $1 AND service_id = $2
 	`
 
@@ -161,6 +166,7 @@ func UpdateServiceEnvironments(ctx context.Context, db pgx.Tx, environments []mo
 			env.ServiceID,
 			env.Key,
 			env.Value,
+			env.Comment,
 			env.UpdatedAt,
 		)
 		if err != nil {
@@ -197,3 +203,75 @@ func DeleteServiceEnvironments(ctx context.Context, db pgx.Tx, serviceID string)
 	_, err := db.Exec(ctx, query, serviceID)
 	return err
 }
+
+// +----------------------------------------------+
+// | Service Env File Functions                   |
+// +----------------------------------------------+
+
+// GetServiceEnvFiles gets the named env files of a service
+func GetServiceEnvFiles(ctx context.Context, db pgx.Tx, serviceID string) ([]models.ServiceEnvFile, error) {
+	query := `
+		SELECT id, service_id, name, content, created_at, updated_at
+		FROM service_env_files
+		WHERE service_id = $1
+		ORDER BY name ASC
+	`
+	rows, err := db.Query(ctx, query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	envFiles := []models.ServiceEnvFile{}
+	for rows.Next() {
+		var envFile models.ServiceEnvFile
+		err := rows.Scan(
+			&envFile.ID,
+			&envFile.ServiceID,
+			&envFile.Name,
+			&envFile.Content,
+			&envFile.CreatedAt,
+			&envFile.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		envFiles = append(envFiles, envFile)
+	}
+
+	return envFiles, rows.Err()
+}
+
+// ReplaceServiceEnvFiles replaces the named env files of a service
+func ReplaceServiceEnvFiles(ctx context.Context, db pgx.Tx, serviceID string, envFiles []models.ServiceEnvFile) error {
+	if err := DeleteServiceEnvFiles(ctx, db, serviceID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO service_env_files (id, service_id, name, content, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for _, envFile := range envFiles {
+		_, err := db.Exec(ctx, query,
+			envFile.ID,
+			envFile.ServiceID,
+			envFile.Name,
+			envFile.Content,
+			envFile.CreatedAt,
+			envFile.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteServiceEnvFiles deletes the named env files of a service
+func DeleteServiceEnvFiles(ctx context.Context, db pgx.Tx, serviceID string) error {
+	query := `DELETE FROM service_env_files WHERE service_id = $1`
+	_, err := db.Exec(ctx, query, serviceID)
+	return err
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// GetOrphanedResources gets the orphaned resources found on a server, oldest first
+func GetOrphanedResources(ctx context.Context, db pgx.Tx, serverID string) ([]models.OrphanedResource, error) {
+	query := `
+		SELECT id, server_id, service_id, resource_type, resource_id, name, approved_at, removal_error,
+		       first_seen_at, last_seen_at
+		FROM orphaned_resources
+		WHERE server_id = $1
+		ORDER BY first_seen_at ASC, id ASC
+	`
+	rows, err := db.Query(ctx, query, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resources := []models.OrphanedResource{}
+	for rows.Next() {
+		var resource models.OrphanedResource
+		err := rows.Scan(
+			&resource.ID,
+			&resource.ServerID,
+			&resource.ServiceID,
+			&resource.ResourceType,
+			&resource.ResourceID,
+			&resource.Name,
+			&resource.ApprovedAt,
+			&resource.RemovalError,
+			&resource.FirstSeenAt,
+			&resource.LastSeenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, rows.Err()
+}
+
+// GetOrphanedResourceByID gets an orphaned resource of a server by ID
+func GetOrphanedResourceByID(ctx context.Context, db pgx.Tx, orphanID, serverID string) (*models.OrphanedResource, error) {
+	query := `
+		SELECT id, server_id, service_id, resource_type, resource_id, name, approved_at, removal_error,
+		       first_seen_at, last_seen_at
+		FROM orphaned_resources
+		WHERE id = $1 AND server_id = $2
+	`
+	var resource models.OrphanedResource
+	err := db.QueryRow(ctx, query, orphanID, serverID).Scan(
+		&resource.ID,
+		&resource.ServerID,
+		&resource.ServiceID,
+		&resource.ResourceType,
+		&resource.ResourceID,
+		&resource.Name,
+		&resource.ApprovedAt,
+		&resource.RemovalError,
+		&resource.FirstSeenAt,
+		&resource.LastSeenAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &resource, nil
+}
+
+// UpsertOrphanedResource records an orphaned resource, a resource that is already known only gets its last_seen_at and name updated
+func UpsertOrphanedResource(ctx context.Context, db pgx.Tx, resource models.OrphanedResource) error {
+	query := `
+		INSERT INTO orphaned_resources (id, server_id, service_id, resource_type, resource_id, name, approved_at,
+		                                removal_error, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (server_id, resource_type, resource_id)
+		DO UPDATE SET name = EXCLUDED.name, last_seen_at = EXCLUDED.last_seen_at
+	`
+	_, err := db.Exec(ctx, query,
+		resource.ID,
+		resource.ServerID,
+		resource.ServiceID,
+		resource.ResourceType,
+		resource.ResourceID,
+		resource.Name,
+		resource.ApprovedAt,
+		resource.RemovalError,
+		resource.FirstSeenAt,
+		resource.LastSeenAt,
+	)
+	return err
+}
+
+// ApproveOrphanedResource records that the orphaned resource may be removed
+func ApproveOrphanedResource(ctx context.Context, db pgx.Tx, orphanID string, approvedAt time.Time) error {
+	query := `UPDATE orphaned_resources SET approved_at = $1 WHERE id = $2`
+	_, err := db.Exec(ctx, query, approvedAt, orphanID)
+	return err
+}
+
+// UpdateOrphanedResourceRemovalError stores the error of the last removal attempt
+func UpdateOrphanedResourceRemovalError(ctx context.Context, db pgx.Tx, orphanID string, removalError *string) error {
+	query := `UPDATE orphaned_resources SET removal_error = $1 WHERE id = $2`
+	_, err := db.Exec(ctx, query, removalError, orphanID)
+	return err
+}
+
+// DeleteOrphanedResource deletes an orphaned resource record after the resource was removed
+func DeleteOrphanedResource(ctx context.Context, db pgx.Tx, orphanID string) error {
+	query := `DELETE FROM orphaned_resources WHERE id = $1`
+	_, err := db.Exec(ctx, query, orphanID)
+	return err
+}
+
+// DeleteOrphanedResourcesSeenBefore deletes the records of a server that were not found orphaned since the given time,
+// the resource is gone or its service exists again
+func DeleteOrphanedResourcesSeenBefore(ctx context.Context, db pgx.Tx, serverID string, before time.Time) error {
+	query := `DELETE FROM orphaned_resources WHERE server_id = $1 AND last_seen_at < $2`
+	_, err := db.Exec(ctx, query, serverID, before)
+	return err
+}
+
+// DeleteOrphanedResources deletes all orphaned resource records of a server
+func DeleteOrphanedResources(ctx context.Context, db pgx.Tx, serverID string) error {
+	query := `DELETE FROM orphaned_resources WHERE server_id = $1`
+	_, err := db.Exec(ctx, query, serverID)
+	return err
+}
@@ -2,16 +2,19 @@ package repository
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 
+	"github.com/yorukot/starker/internal/config"
 	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/pkg/encrypt"
 )
 
 // GetPrivateKeysByTeamID gets all private keys for a team
 func GetPrivateKeysByTeamID(ctx context.Context, db pgx.Tx, teamID string) ([]models.PrivateKey, error) {
 	query := `
-		SELECT id, team_id, name, description, private_key, fingerprint, created_at, updated_at
+		SELECT id, team_id, name, description, private_key, private_key_ciphertext, encrypted_data_key, passphrase_ciphertext, fingerprint, public_key, certificate, created_at, updated_at
 		FROM private_keys
 		WHERE team_id = $1
 		ORDER BY created_at DESC
@@ -25,19 +28,29 @@ func GetPrivateKeysByTeamID(ctx context.Context, db pgx.Tx, teamID string) ([]mo
 	var privateKeys []models.PrivateKey
 	for rows.Next() {
 		var privateKey models.PrivateKey
+		var encrypted encryptedPrivateKey
 		err := rows.Scan(
 			&privateKey.ID,
 			&privateKey.TeamID,
 			&privateKey.Name,
 			&privateKey.Description,
-			&privateKey.PrivateKey,
+			&encrypted.plaintext,
+			&encrypted.ciphertext,
+			&encrypted.encryptedDataKey,
+			&encrypted.passphraseCiphertext,
 			&privateKey.Fingerprint,
+			&privateKey.PublicKey,
+			&privateKey.Certificate,
 			&privateKey.CreatedAt,
 			&privateKey.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if privateKey.PrivateKey, privateKey.Passphrase, err = encrypted.decrypt(); err != nil {
+			return nil, err
+		}
+		privateKey.HasPassphrase = privateKey.Passphrase != nil
 		privateKeys = append(privateKeys, privateKey)
 	}
 
@@ -47,18 +60,24 @@ func GetPrivateKeysByTeamID(ctx context.Context, db pgx.Tx, teamID string) ([]mo
 // GetPrivateKeyByID gets a private key by ID and team ID
 func GetPrivateKeyByID(ctx context.Context, db pgx.Tx, privateKeyID, teamID string) (*models.PrivateKey, error) {
 	query := `
-		SELECT id, team_id, name, description, private_key, fingerprint, created_at, updated_at
+		SELECT id, team_id, name, description, private_key, private_key_ciphertext, encrypted_data_key, passphrase_ciphertext, fingerprint, public_key, certificate, created_at, updated_at
 		FROM private_keys
 		WHERE id = $1 AND team_id = $2
 	`
 	var privateKey models.PrivateKey
+	var encrypted encryptedPrivateKey
 	err := db.QueryRow(ctx, query, privateKeyID, teamID).Scan(
 		&privateKey.ID,
 		&privateKey.TeamID,
 		&privateKey.Name,
 		&privateKey.Description,
-		&privateKey.PrivateKey,
+		&encrypted.plaintext,
+		&encrypted.ciphertext,
+		&encrypted.encryptedDataKey,
+		&encrypted.passphraseCiphertext,
 		&privateKey.Fingerprint,
+		&privateKey.PublicKey,
+		&privateKey.Certificate,
 		&privateKey.CreatedAt,
 		&privateKey.UpdatedAt,
 	)
@@ -68,43 +87,66 @@ func GetPrivateKeyByID(ctx context.Context, db pgx.Tx, privateKeyID, teamID stri
 		}
 		return nil, err
 	}
+	if privateKey.PrivateKey, privateKey.Passphrase, err = encrypted.decrypt(); err != nil {
+		return nil, err
+	}
+	privateKey.HasPassphrase = privateKey.Passphrase != nil
 
 	return &privateKey, nil
 }
 
-// CreatePrivateKey creates a new private key
+// CreatePrivateKey creates a new private key, the key material is stored encrypted
 func CreatePrivateKey(ctx context.Context, db pgx.Tx, privateKey models.PrivateKey) error {
+	encrypted, err := encryptPrivateKey(privateKey.PrivateKey, privateKey.Passphrase)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO private_keys (id, team_id, name, description, private_key, fingerprint, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO private_keys (id, team_id, name, description, private_key_ciphertext, encrypted_data_key, passphrase_ciphertext, fingerprint, public_key, certificate, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	_, err := db.Exec(ctx, query,
+	_, err = db.Exec(ctx, query,
 		privateKey.ID,
 		privateKey.TeamID,
 		privateKey.Name,
 		privateKey.Description,
-		privateKey.PrivateKey,
+		encrypted.ciphertext,
+		encrypted.encryptedDataKey,
+		encrypted.passphraseCiphertext,
 		privateKey.Fingerprint,
+		privateKey.PublicKey,
+		privateKey.Certificate,
 		privateKey.CreatedAt,
 		privateKey.UpdatedAt,
 	)
 	return err
 }
 
-// UpdatePrivateKeyByID updates a private key by ID and team ID
+// UpdatePrivateKeyByID updates a private key by ID and team ID, the key material is encrypted with a new data key
 func UpdatePrivateKeyByID(ctx context.Context, db pgx.Tx, privateKey models.PrivateKey) error {
+	encrypted, err := encryptPrivateKey(privateKey.PrivateKey, privateKey.Passphrase)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE private_keys
-		SET name = $3, description = $4, private_key = $5, fingerprint = $6, updated_at = $7
+		SET name = $3, description = $4, private_key = NULL, private_key_ciphertext = $5, encrypted_data_key = $6, passphrase_ciphertext = $7,
+			fingerprint = $8, public_key = $9, certificate = $10, updated_at = $11
 		WHERE id = $1 AND team_id = $2
 	`
-	_, err := db.Exec(ctx, query,
+	_, err = db.Exec(ctx, query,
 		privateKey.ID,
 		privateKey.TeamID,
 		privateKey.Name,
 		privateKey.Description,
-		privateKey.PrivateKey,
+		encrypted.ciphertext,
+		encrypted.encryptedDataKey,
+		encrypted.passphraseCiphertext,
 		privateKey.Fingerprint,
+		privateKey.PublicKey,
+		privateKey.Certificate,
 		privateKey.UpdatedAt,
 	)
 	return err
@@ -119,3 +161,149 @@ func DeletePrivateKeyByID(ctx context.Context, db pgx.Tx, privateKeyID, teamID s
 	_, err := db.Exec(ctx, query, privateKeyID, teamID)
 	return err
 }
+
+// EncryptPlaintextPrivateKeys encrypts the private keys stored before encryption at rest was introduced
+func EncryptPlaintextPrivateKeys(ctx context.Context, db pgx.Tx) (int, error) {
+	rows, err := db.Query(ctx, `SELECT id, private_key FROM private_keys WHERE private_key IS NOT NULL FOR UPDATE`)
+	if err != nil {
+		return 0, err
+	}
+
+	plaintextKeys := map[string]string{}
+	for rows.Next() {
+		var id, privateKey string
+		if err := rows.Scan(&id, &privateKey); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plaintextKeys[id] = privateKey
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, privateKey := range plaintextKeys {
+		encrypted, err := encryptPrivateKey(privateKey, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		query := `
+			UPDATE private_keys
+			SET private_key = NULL, private_key_ciphertext = $2, encrypted_data_key = $3
+			WHERE id = $1
+		`
+		if _, err := db.Exec(ctx, query, id, encrypted.ciphertext, encrypted.encryptedDataKey); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(plaintextKeys), nil
+}
+
+// RotatePrivateKeyMasterKey re-encrypts the data keys of all private keys from the old to the new master key.
+// The key material itself is not touched, only the envelope around the data keys changes.
+func RotatePrivateKeyMasterKey(ctx context.Context, db pgx.Tx, oldMasterKey, newMasterKey []byte) (int, error) {
+	rows, err := db.Query(ctx, `SELECT id, encrypted_data_key FROM private_keys WHERE encrypted_data_key IS NOT NULL FOR UPDATE`)
+	if err != nil {
+		return 0, err
+	}
+
+	encryptedDataKeys := map[string][]byte{}
+	for rows.Next() {
+		var id string
+		var encryptedDataKey []byte
+		if err := rows.Scan(&id, &encryptedDataKey); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		encryptedDataKeys[id] = encryptedDataKey
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, encryptedDataKey := range encryptedDataKeys {
+		rewrapped, err := encrypt.RewrapDataKey(oldMasterKey, newMasterKey, encryptedDataKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt data key of private key %s: %w", id, err)
+		}
+
+		if _, err := db.Exec(ctx, `UPDATE private_keys SET encrypted_data_key = $2 WHERE id = $1`, id, rewrapped); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(encryptedDataKeys), nil
+}
+
+// encryptedPrivateKey holds the stored columns of the key material
+type encryptedPrivateKey struct {
+	plaintext            *string // Only set for keys stored before encryption at rest
+	ciphertext           []byte
+	encryptedDataKey     []byte
+	passphraseCiphertext []byte // Encrypted with the same data key as the private key
+}
+
+// decrypt returns the key material and the passphrase with the configured master key
+func (e encryptedPrivateKey) decrypt() (string, *string, error) {
+	if e.ciphertext == nil {
+		if e.plaintext == nil {
+			return "", nil, fmt.Errorf("private key has no key material")
+		}
+		return *e.plaintext, nil, nil
+	}
+
+	masterKey, err := encrypt.DecodeMasterKey(config.Env().PrivateKeyMasterKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dataKey, err := encrypt.DecryptDataKey(masterKey, e.encryptedDataKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	privateKey, err := encrypt.DecryptWithDataKey(dataKey, e.ciphertext)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	if e.passphraseCiphertext == nil {
+		return string(privateKey), nil, nil
+	}
+
+	passphrase, err := encrypt.DecryptWithDataKey(dataKey, e.passphraseCiphertext)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt private key passphrase: %w", err)
+	}
+	return string(privateKey), &[]string{string(passphrase)}[0], nil
+}
+
+// encryptPrivateKey envelope encrypts the key material and the passphrase with one data key
+func encryptPrivateKey(privateKey string, passphrase *string) (*encryptedPrivateKey, error) {
+	masterKey, err := encrypt.DecodeMasterKey(config.Env().PrivateKeyMasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, encryptedDataKey, err := encrypt.GenerateDataKey(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt private key: %w", err)
+	}
+
+	ciphertext, err := encrypt.EncryptWithDataKey(dataKey, []byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt private key: %w", err)
+	}
+
+	encrypted := &encryptedPrivateKey{ciphertext: ciphertext, encryptedDataKey: encryptedDataKey}
+	if passphrase != nil {
+		if encrypted.passphraseCiphertext, err = encrypt.EncryptWithDataKey(dataKey, []byte(*passphrase)); err != nil {
+			return nil, fmt.Errorf("failed to encrypt private key passphrase: %w", err)
+		}
+	}
+	return encrypted, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 
@@ -11,7 +12,8 @@ import (
 // GetServersByTeamID gets all servers for a team
 func GetServersByTeamID(ctx context.Context, db pgx.Tx, teamID string) ([]models.Server, error) {
 	query := `
-		SELECT id, team_id, name, description, ip, port, "user", private_key_id, created_at, updated_at
+		SELECT id, team_id, name, description, ip, port, "user", COALESCE(private_key_id, ''), host_key, jump_host_id, connection_type, tls_ca_certificate, tls_certificate, agent_token_hash,
+		       status, status_error, status_checked_at, last_seen_at, latency_ms, docker_version, os, arch, created_at, updated_at
 		FROM servers
 		WHERE team_id = $1
 		ORDER BY created_at DESC
@@ -34,6 +36,20 @@ func GetServersByTeamID(ctx context.Context, db pgx.Tx, teamID string) ([]models
 			&server.Port,
 			&server.User,
 			&server.PrivateKeyID,
+			&server.HostKey,
+			&server.JumpHostID,
+			&server.ConnectionType,
+			&server.TLSCACertificate,
+			&server.TLSCertificate,
+			&server.AgentTokenHash,
+			&server.Status,
+			&server.StatusError,
+			&server.StatusCheckedAt,
+			&server.LastSeenAt,
+			&server.LatencyMs,
+			&server.DockerVersion,
+			&server.OS,
+			&server.Arch,
 			&server.CreatedAt,
 			&server.UpdatedAt,
 		)
@@ -49,7 +65,8 @@ func GetServersByTeamID(ctx context.Context, db pgx.Tx, teamID string) ([]models
 // GetServerByID gets a server by ID and team ID
 func GetServerByID(ctx context.Context, db pgx.Tx, serverID, teamID string) (*models.Server, error) {
 	query := `
-		SELECT id, team_id, name, description, ip, port, "user", private_key_id, created_at, updated_at
+		SELECT id, team_id, name, description, ip, port, "user", COALESCE(private_key_id, ''), host_key, jump_host_id, connection_type, tls_ca_certificate, tls_certificate, agent_token_hash,
+		       status, status_error, status_checked_at, last_seen_at, latency_ms, docker_version, os, arch, created_at, updated_at
 		FROM servers
 		WHERE id = $1 AND team_id = $2
 	`
@@ -63,6 +80,65 @@ func GetServerByID(ctx context.Context, db pgx.Tx, serverID, teamID string) (*mo
 		&server.Port,
 		&server.User,
 		&server.PrivateKeyID,
+		&server.HostKey,
+		&server.JumpHostID,
+		&server.ConnectionType,
+		&server.TLSCACertificate,
+		&server.TLSCertificate,
+		&server.AgentTokenHash,
+		&server.Status,
+		&server.StatusError,
+		&server.StatusCheckedAt,
+		&server.LastSeenAt,
+		&server.LatencyMs,
+		&server.DockerVersion,
+		&server.OS,
+		&server.Arch,
+		&server.CreatedAt,
+		&server.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil // No server found
+		}
+		return nil, err
+	}
+
+	return &server, nil
+}
+
+// GetServerByAgentTokenHash gets the agent server that authenticates with the token of the hash
+func GetServerByAgentTokenHash(ctx context.Context, db pgx.Tx, tokenHash string) (*models.Server, error) {
+	query := `
+		SELECT id, team_id, name, description, ip, port, "user", COALESCE(private_key_id, ''), host_key, jump_host_id, connection_type, tls_ca_certificate, tls_certificate, agent_token_hash,
+		       status, status_error, status_checked_at, last_seen_at, latency_ms, docker_version, os, arch, created_at, updated_at
+		FROM servers
+		WHERE agent_token_hash = $1 AND connection_type = 'agent'
+	`
+	var server models.Server
+	err := db.QueryRow(ctx, query, tokenHash).Scan(
+		&server.ID,
+		&server.TeamID,
+		&server.Name,
+		&server.Description,
+		&server.IP,
+		&server.Port,
+		&server.User,
+		&server.PrivateKeyID,
+		&server.HostKey,
+		&server.JumpHostID,
+		&server.ConnectionType,
+		&server.TLSCACertificate,
+		&server.TLSCertificate,
+		&server.AgentTokenHash,
+		&server.Status,
+		&server.StatusError,
+		&server.StatusCheckedAt,
+		&server.LastSeenAt,
+		&server.LatencyMs,
+		&server.DockerVersion,
+		&server.OS,
+		&server.Arch,
 		&server.CreatedAt,
 		&server.UpdatedAt,
 	)
@@ -79,8 +155,8 @@ func GetServerByID(ctx context.Context, db pgx.Tx, serverID, teamID string) (*mo
 // CreateServer creates a new server
 func CreateServer(ctx context.Context, db pgx.Tx, server models.Server) error {
 	query := `
-		INSERT INTO servers (id, team_id, name, description, ip, port, "user", private_key_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO servers (id, team_id, name, description, ip, port, "user", private_key_id, host_key, jump_host_id, connection_type, tls_ca_certificate, tls_certificate, agent_token_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $12, $13, $14, $15, $16)
 	`
 	_, err := db.Exec(ctx, query,
 		server.ID,
@@ -91,6 +167,12 @@ func CreateServer(ctx context.Context, db pgx.Tx, server models.Server) error {
 		server.Port,
 		server.User,
 		server.PrivateKeyID,
+		server.HostKey,
+		server.JumpHostID,
+		server.ConnectionType,
+		server.TLSCACertificate,
+		server.TLSCertificate,
+		server.AgentTokenHash,
 		server.CreatedAt,
 		server.UpdatedAt,
 	)
@@ -101,7 +183,7 @@ func CreateServer(ctx context.Context, db pgx.Tx, server models.Server) error {
 func UpdateServerByID(ctx context.Context, db pgx.Tx, serverID, teamID string, server models.Server) error {
 	query := `
 		UPDATE servers
-		SET name = $3, description = $4, ip = $5, port = $6, "user" = $7, private_key_id = $8, updated_at = $9
+		SET name = $3, description = $4, ip = $5, port = $6, "user" = $7, private_key_id = NULLIF($8, ''), updated_at = $9
 		WHERE id = $1 AND team_id = $2
 	`
 	_, err := db.Exec(ctx, query,
@@ -122,8 +204,9 @@ func UpdateServerByID(ctx context.Context, db pgx.Tx, serverID, teamID string, s
 func UpdateServer(ctx context.Context, db pgx.Tx, teamID, serverID string, server models.Server) (*models.Server, error) {
 	query := `
 		UPDATE servers
-		SET name = $1, description = $2, ip = $3, port = $4, "user" = $5, private_key_id = $6, updated_at = $7
-		WHERE id = $8 AND team_id = $9
+		SET name = $1, description = $2, ip = $3, port = $4, "user" = $5, private_key_id = NULLIF($6, ''), host_key = $7, jump_host_id = $8,
+			connection_type = $9, tls_ca_certificate = $10, tls_certificate = $11, agent_token_hash = $12, updated_at = $13
+		WHERE id = $14 AND team_id = $15
 	`
 	_, err := db.Exec(ctx, query,
 		server.Name,
@@ -132,6 +215,12 @@ func UpdateServer(ctx context.Context, db pgx.Tx, teamID, serverID string, serve
 		server.Port,
 		server.User,
 		server.PrivateKeyID,
+		server.HostKey,
+		server.JumpHostID,
+		server.ConnectionType,
+		server.TLSCACertificate,
+		server.TLSCertificate,
+		server.AgentTokenHash,
 		server.UpdatedAt,
 		serverID,
 		teamID,
@@ -143,6 +232,76 @@ func UpdateServer(ctx context.Context, db pgx.Tx, teamID, serverID string, serve
 	return &server, nil
 }
 
+// PinServerHostKey stores the host key of a server that does not have one pinned yet
+func PinServerHostKey(ctx context.Context, db pgx.Tx, serverID, teamID, hostKey string) error {
+	query := `
+		UPDATE servers
+		SET host_key = $3
+		WHERE id = $1 AND team_id = $2 AND host_key IS NULL
+	`
+	_, err := db.Exec(ctx, query, serverID, teamID, hostKey)
+	return err
+}
+
+// UpdateServerHostKey replaces the pinned host key of a server
+func UpdateServerHostKey(ctx context.Context, db pgx.Tx, serverID, teamID, hostKey string) error {
+	query := `
+		UPDATE servers
+		SET host_key = $3, updated_at = $4
+		WHERE id = $1 AND team_id = $2
+	`
+	_, err := db.Exec(ctx, query, serverID, teamID, hostKey, time.Now())
+	return err
+}
+
+// UpdateServerStatus records the result of a status probe.
+// The last seen time and the daemon details are kept from the last successful probe when the server is offline.
+func UpdateServerStatus(ctx context.Context, db pgx.Tx, server models.Server) error {
+	query := `
+		UPDATE servers
+		SET status = $3, status_error = $4, status_checked_at = $5,
+		    last_seen_at = COALESCE($6, last_seen_at), latency_ms = COALESCE($7, latency_ms),
+		    docker_version = COALESCE($8, docker_version), os = COALESCE($9, os), arch = COALESCE($10, arch)
+		WHERE id = $1 AND team_id = $2
+	`
+	_, err := db.Exec(ctx, query,
+		server.ID,
+		server.TeamID,
+		server.Status,
+		server.StatusError,
+		server.StatusCheckedAt,
+		server.LastSeenAt,
+		server.LatencyMs,
+		server.DockerVersion,
+		server.OS,
+		server.Arch,
+	)
+	return err
+}
+
+// UpdateServerAgentTokenHash replaces the agent token hash of a server
+func UpdateServerAgentTokenHash(ctx context.Context, db pgx.Tx, serverID, teamID, tokenHash string) error {
+	query := `
+		UPDATE servers
+		SET agent_token_hash = $3, updated_at = $4
+		WHERE id = $1 AND team_id = $2
+	`
+	_, err := db.Exec(ctx, query, serverID, teamID, tokenHash, time.Now())
+	return err
+}
+
+// CountServersUsingJumpHost counts the servers that are reached through the given server
+func CountServersUsingJumpHost(ctx context.Context, db pgx.Tx, serverID, teamID string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM servers
+		WHERE jump_host_id = $1 AND team_id = $2
+	`
+	var count int
+	err := db.QueryRow(ctx, query, serverID, teamID).Scan(&count)
+	return count, err
+}
+
 // DeleteServerByID deletes a server by ID and team ID
 func DeleteServerByID(ctx context.Context, db pgx.Tx, serverID, teamID string) error {
 	query := `
@@ -152,3 +311,55 @@ func DeleteServerByID(ctx context.Context, db pgx.Tx, serverID, teamID string) e
 	_, err := db.Exec(ctx, query, serverID, teamID)
 	return err
 }
+
+// GetAllServers gets all servers across all teams
+func GetAllServers(ctx context.Context, db pgx.Tx) ([]models.Server, error) {
+	query := `
+		SELECT id, team_id, name, description, ip, port, "user", COALESCE(private_key_id, ''), host_key, jump_host_id, connection_type, tls_ca_certificate, tls_certificate, agent_token_hash,
+		       status, status_error, status_checked_at, last_seen_at, latency_ms, docker_version, os, arch, created_at, updated_at
+		FROM servers
+		ORDER BY created_at ASC
+	`
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var servers []models.Server
+	for rows.Next() {
+		var server models.Server
+		err := rows.Scan(
+			&server.ID,
+			&server.TeamID,
+			&server.Name,
+			&server.Description,
+			&server.IP,
+			&server.Port,
+			&server.User,
+			&server.PrivateKeyID,
+			&server.HostKey,
+			&server.JumpHostID,
+			&server.ConnectionType,
+			&server.TLSCACertificate,
+			&server.TLSCertificate,
+			&server.AgentTokenHash,
+			&server.Status,
+			&server.StatusError,
+			&server.StatusCheckedAt,
+			&server.LastSeenAt,
+			&server.LatencyMs,
+			&server.DockerVersion,
+			&server.OS,
+			&server.Arch,
+			&server.CreatedAt,
+			&server.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+
+	return servers, rows.Err()
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// CreateServerMetrics stores a host metrics sample
+func CreateServerMetrics(ctx context.Context, db pgx.Tx, metrics models.ServerMetrics) error {
+	query := `
+		INSERT INTO server_metrics (id, server_id, load1, load5, load15, cpu_count, memory_total, memory_available,
+		                            swap_total, swap_free, disks, docker_images_size, docker_containers_size,
+		                            docker_volumes_size, docker_build_cache_size, sampled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+	_, err := db.Exec(ctx, query,
+		metrics.ID,
+		metrics.ServerID,
+		metrics.Load1,
+		metrics.Load5,
+		metrics.Load15,
+		metrics.CPUCount,
+		metrics.MemoryTotal,
+		metrics.MemoryAvailable,
+		metrics.SwapTotal,
+		metrics.SwapFree,
+		metrics.Disks,
+		metrics.DockerImagesSize,
+		metrics.DockerContainersSize,
+		metrics.DockerVolumesSize,
+		metrics.DockerBuildCacheSize,
+		metrics.SampledAt,
+	)
+	return err
+}
+
+// GetServerMetrics gets the host metrics samples of a server between from and to, oldest first
+func GetServerMetrics(ctx context.Context, db pgx.Tx, serverID string, from, to time.Time) ([]models.ServerMetrics, error) {
+	query := `
+		SELECT id, server_id, load1, load5, load15, cpu_count, memory_total, memory_available,
+		       swap_total, swap_free, disks, docker_images_size, docker_containers_size,
+		       docker_volumes_size, docker_build_cache_size, sampled_at
+		FROM server_metrics
+		WHERE server_id = $1 AND sampled_at >= $2 AND sampled_at <= $3
+		ORDER BY sampled_at ASC
+	`
+	rows, err := db.Query(ctx, query, serverID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metricsList := []models.ServerMetrics{}
+	for rows.Next() {
+		var metrics models.ServerMetrics
+		err := rows.Scan(
+			&metrics.ID,
+			&metrics.ServerID,
+			&metrics.Load1,
+			&metrics.Load5,
+			&metrics.Load15,
+			&metrics.CPUCount,
+			&metrics.MemoryTotal,
+			&metrics.MemoryAvailable,
+			&metrics.SwapTotal,
+			&metrics.SwapFree,
+			&metrics.Disks,
+			&metrics.DockerImagesSize,
+			&metrics.DockerContainersSize,
+			&metrics.DockerVolumesSize,
+			&metrics.DockerBuildCacheSize,
+			&metrics.SampledAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		metricsList = append(metricsList, metrics)
+	}
+
+	return metricsList, rows.Err()
+}
+
+// DeleteServerMetricsBefore deletes all host metrics samples older than the given time
+func DeleteServerMetricsBefore(ctx context.Context, db pgx.Tx, before time.Time) error {
+	query := `DELETE FROM server_metrics WHERE sampled_at < $1`
+	_, err := db.Exec(ctx, query, before)
+	return err
+}
+
+// DeleteServerMetrics deletes all host metrics samples of a server
+func DeleteServerMetrics(ctx context.Context, db pgx.Tx, serverID string) error {
+	query := `DELETE FROM server_metrics WHERE server_id = $1`
+	_, err := db.Exec(ctx, query, serverID)
+	return err
+}
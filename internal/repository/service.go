@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 
@@ -13,7 +14,7 @@ import (
 func GetServices(ctx context.Context, db pgx.Tx, teamID, projectID string) ([]models.Service, error) {
 	query := `
 		SELECT id, team_id, server_id, project_id, name, description, type, state,
-		       container_id, last_deployed_at, created_at, updated_at
+		       container_id, last_deployed_at, auto_update, auto_update_window, created_at, updated_at
 		FROM services
 		WHERE team_id = $1 AND project_id = $2
 		ORDER BY created_at DESC
@@ -38,6 +39,8 @@ func GetServices(ctx context.Context, db pgx.Tx, teamID, projectID string) ([]mo
 			&service.State,
 			&service.ContainerID,
 			&service.LastDeployedAt,
+			&service.AutoUpdate,
+			&service.AutoUpdateWindow,
 			&service.CreatedAt,
 			&service.UpdatedAt,
 		)
@@ -50,11 +53,75 @@ func GetServices(ctx context.Context, db pgx.Tx, teamID, projectID string) ([]mo
 	return services, rows.Err()
 }
 
+// GetServicesByState gets all services in the given state across all teams
+func GetServicesByState(ctx context.Context, db pgx.Tx, state models.ServiceState) ([]models.Service, error) {
+	query := `
+		SELECT id, team_id, server_id, project_id, name, description, type, state,
+		       container_id, last_deployed_at, auto_update, auto_update_window, created_at, updated_at
+		FROM services
+		WHERE state = $1
+		ORDER BY server_id
+	`
+	rows, err := db.Query(ctx, query, state)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []models.Service
+	for rows.Next() {
+		var service models.Service
+		err := rows.Scan(
+			&service.ID,
+			&service.TeamID,
+			&service.ServerID,
+			&service.ProjectID,
+			&service.Name,
+			&service.Description,
+			&service.Type,
+			&service.State,
+			&service.ContainerID,
+			&service.LastDeployedAt,
+			&service.AutoUpdate,
+			&service.AutoUpdateWindow,
+			&service.CreatedAt,
+			&service.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+
+	return services, rows.Err()
+}
+
+// GetExistingServiceIDs returns which of the given service IDs still have a service
+func GetExistingServiceIDs(ctx context.Context, db pgx.Tx, serviceIDs []string) (map[string]bool, error) {
+	query := `SELECT id FROM services WHERE id = ANY($1)`
+	rows, err := db.Query(ctx, query, serviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool, len(serviceIDs))
+	for rows.Next() {
+		var serviceID string
+		if err := rows.Scan(&serviceID); err != nil {
+			return nil, err
+		}
+		existing[serviceID] = true
+	}
+
+	return existing, rows.Err()
+}
+
 // GetServiceByID gets a service by ID, team ID, and project ID
 func GetServiceByID(ctx context.Context, db pgx.Tx, serviceID, teamID, projectID string) (*models.Service, error) {
 	query := `
 		SELECT id, team_id, server_id, project_id, name, description, type, state,
-		       container_id, last_deployed_at, created_at, updated_at
+		       container_id, last_deployed_at, auto_update, auto_update_window, created_at, updated_at
 		FROM services
 		WHERE id = $1 AND team_id = $2 AND project_id = $3
 	`
@@ -70,6 +137,8 @@ func GetServiceByID(ctx context.Context, db pgx.Tx, serviceID, teamID, projectID
 		&service.State,
 		&service.ContainerID,
 		&service.LastDeployedAt,
+		&service.AutoUpdate,
+		&service.AutoUpdateWindow,
 		&service.CreatedAt,
 		&service.UpdatedAt,
 	)
@@ -109,7 +178,7 @@ func UpdateService(ctx context.Context, db pgx.Tx, service models.Service) error
 	query := `
 		UPDATE services
 		SET name = $2, description = $3, type = $4, state = $5,
-		    container_id = $6, last_deployed_at = $7, updated_at = $8
+		    container_id = $6, last_deployed_at = $7, auto_update = $8, auto_update_window = $9, updated_at = $10
 		WHERE id = $1
 	`
 	_, err := db.Exec(ctx, query,
@@ -120,11 +189,61 @@ func UpdateService(ctx context.Context, db pgx.Tx, service models.Service) error
 		service.State,
 		service.ContainerID,
 		service.LastDeployedAt,
+		service.AutoUpdate,
+		service.AutoUpdateWindow,
 		service.UpdatedAt,
 	)
 	return err
 }
 
+// GetServiceForUpdate gets a service by ID and locks it until the transaction ends.
+// It returns nil when the service does not exist or another transaction holds the lock, like a deploy started by a user.
+func GetServiceForUpdate(ctx context.Context, db pgx.Tx, serviceID string) (*models.Service, error) {
+	query := `
+		SELECT id, team_id, server_id, project_id, name, description, type, state,
+		       container_id, last_deployed_at, auto_update, auto_update_window, created_at, updated_at
+		FROM services
+		WHERE id = $1
+		FOR UPDATE SKIP LOCKED
+	`
+	var service models.Service
+	err := db.QueryRow(ctx, query, serviceID).Scan(
+		&service.ID,
+		&service.TeamID,
+		&service.ServerID,
+		&service.ProjectID,
+		&service.Name,
+		&service.Description,
+		&service.Type,
+		&service.State,
+		&service.ContainerID,
+		&service.LastDeployedAt,
+		&service.AutoUpdate,
+		&service.AutoUpdateWindow,
+		&service.CreatedAt,
+		&service.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &service, nil
+}
+
+// UpdateServiceState updates only the state of a service, and the last deploy time when it is given
+func UpdateServiceState(ctx context.Context, db pgx.Tx, serviceID string, state models.ServiceState, lastDeployedAt *time.Time) error {
+	query := `
+		UPDATE services
+		SET state = $2, last_deployed_at = COALESCE($3, last_deployed_at), updated_at = $4
+		WHERE id = $1
+	`
+	_, err := db.Exec(ctx, query, serviceID, state, lastDeployedAt, time.Now())
+	return err
+}
+
 // DeleteService deletes a service
 func DeleteService(ctx context.Context, db pgx.Tx, serviceID, teamID, projectID string) error {
 	query := `DELETE FROM services WHERE id = $1 AND team_id = $2 AND project_id = $3`
@@ -144,7 +263,7 @@ func DeleteService(ctx context.Context, db pgx.Tx, serviceID, teamID, projectID
 // GetServiceComposeConfig gets the compose config for a service
 func GetServiceComposeConfig(ctx context.Context, db pgx.Tx, serviceID string) (*models.ServiceComposeConfig, error) {
 	query := `
-		SELECT id, service_id, compose_file, created_at, updated_at
+		SELECT id, service_id, compose_file, profiles, created_at, updated_at
 		FROM service_compose_configs
 		WHERE service_id = $1
 	`
@@ -153,6 +272,7 @@ func GetServiceComposeConfig(ctx context.Context, db pgx.Tx, serviceID string) (
 		&config.ID,
 		&config.ServiceID,
 		&config.ComposeFile,
+		&config.Profiles,
 		&config.CreatedAt,
 		&config.UpdatedAt,
 	)
@@ -163,19 +283,26 @@ func GetServiceComposeConfig(ctx context.Context, db pgx.Tx, serviceID string) (
 		return nil, err
 	}
 
+	// The additional files are part of the config, a deploy needs all of them
+	config.Files, err = GetServiceComposeFiles(ctx, db, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
 // CreateServiceComposeConfig creates a new compose config
 func CreateServiceComposeConfig(ctx context.Context, db pgx.Tx, config models.ServiceComposeConfig) error {
 	query := `
-		INSERT INTO service_compose_configs (id, service_id, compose_file, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO service_compose_configs (id, service_id, compose_file, profiles, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 	_, err := db.Exec(ctx, query,
 		config.ID,
 		config.ServiceID,
 		config.ComposeFile,
+		profilesOrEmpty(config.Profiles),
 		config.CreatedAt,
 		config.UpdatedAt,
 	)
@@ -186,12 +313,13 @@ func CreateServiceComposeConfig(ctx context.Context, db pgx.Tx, config models.Se
 func UpdateServiceComposeConfig(ctx context.Context, db pgx.Tx, config models.ServiceComposeConfig) error {
 	query := `
 		UPDATE service_compose_configs
-		SET compose_file = $2, updated_at = $3
+		SET compose_file = $2, profiles = $3, updated_at = $4
 		WHERE id = $1
 	`
 	_, err := db.Exec(ctx, query,
 		config.ID,
 		config.ComposeFile,
+		profilesOrEmpty(config.Profiles),
 		config.UpdatedAt,
 	)
 	return err
@@ -204,6 +332,90 @@ func DeleteServiceComposeConfig(ctx context.Context, db pgx.Tx, serviceID string
 	return err
 }
 
+// profilesOrEmpty returns the profiles as an empty array instead of NULL when none are set
+func profilesOrEmpty(profiles []string) []string {
+	if profiles == nil {
+		return []string{}
+	}
+	return profiles
+}
+
+// +----------------------------------------------+
+// | Service Compose File Functions               |
+// +----------------------------------------------+
+
+// GetServiceComposeFiles gets the additional compose files of a service in load order
+func GetServiceComposeFiles(ctx context.Context, db pgx.Tx, serviceID string) ([]models.ServiceComposeFile, error) {
+	query := `
+		SELECT id, service_id, filename, content, override, position, created_at, updated_at
+		FROM service_compose_files
+		WHERE service_id = $1
+		ORDER BY position ASC
+	`
+	rows, err := db.Query(ctx, query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []models.ServiceComposeFile{}
+	for rows.Next() {
+		var file models.ServiceComposeFile
+		err := rows.Scan(
+			&file.ID,
+			&file.ServiceID,
+			&file.Filename,
+			&file.Content,
+			&file.Override,
+			&file.Position,
+			&file.CreatedAt,
+			&file.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// ReplaceServiceComposeFiles replaces the additional compose files of a service
+func ReplaceServiceComposeFiles(ctx context.Context, db pgx.Tx, serviceID string, files []models.ServiceComposeFile) error {
+	if err := DeleteServiceComposeFiles(ctx, db, serviceID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO service_compose_files (id, service_id, filename, content, override, position, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	for _, file := range files {
+		_, err := db.Exec(ctx, query,
+			file.ID,
+			file.ServiceID,
+			file.Filename,
+			file.Content,
+			file.Override,
+			file.Position,
+			file.CreatedAt,
+			file.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteServiceComposeFiles deletes the additional compose files of a service
+func DeleteServiceComposeFiles(ctx context.Context, db pgx.Tx, serviceID string) error {
+	query := `DELETE FROM service_compose_files WHERE service_id = $1`
+	_, err := db.Exec(ctx, query, serviceID)
+	return err
+}
+
 // +----------------------------------------------+
 // | Service Container Functions                  |
 // +----------------------------------------------+
@@ -346,7 +558,7 @@ func UpdateServiceContainer(ctx context.Context, db pgx.Tx, container models.Ser
 // GetServiceImages gets all images for a service
 func GetServiceImages(ctx context.Context, db pgx.Tx, serviceID string) ([]models.ServiceImage, error) {
 	query := `
-		SELECT id, service_id, image_id, image_name, created_at, updated_at
+		SELECT id, service_id, image_id, image_name, repo_digest, service_name, deployment_id, created_at, updated_at
 		FROM service_images
 		WHERE service_id = $1
 		ORDER BY created_at DESC
@@ -357,6 +569,30 @@ func GetServiceImages(ctx context.Context, db pgx.Tx, serviceID string) ([]model
 	}
 	defer rows.Close()
 
+	return scanServiceImages(rows)
+}
+
+// GetServerServiceImages gets the images of all services on a server, newest first
+func GetServerServiceImages(ctx context.Context, db pgx.Tx, serverID string) ([]models.ServiceImage, error) {
+	query := `
+		SELECT si.id, si.service_id, si.image_id, si.image_name, si.repo_digest, si.service_name, si.deployment_id,
+		       si.created_at, si.updated_at
+		FROM service_images si
+		JOIN services s ON s.id = si.service_id
+		WHERE s.server_id = $1
+		ORDER BY si.created_at DESC
+	`
+	rows, err := db.Query(ctx, query, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanServiceImages(rows)
+}
+
+// scanServiceImages scans the rows of a service_images query
+func scanServiceImages(rows pgx.Rows) ([]models.ServiceImage, error) {
 	var images []models.ServiceImage
 	for rows.Next() {
 		var image models.ServiceImage
@@ -365,6 +601,9 @@ func GetServiceImages(ctx context.Context, db pgx.Tx, serviceID string) ([]model
 			&image.ServiceID,
 			&image.ImageID,
 			&image.ImageName,
+			&image.RepoDigest,
+			&image.ServiceName,
+			&image.DeploymentID,
 			&image.CreatedAt,
 			&image.UpdatedAt,
 		)
@@ -380,14 +619,18 @@ func GetServiceImages(ctx context.Context, db pgx.Tx, serviceID string) ([]model
 // CreateServiceImage creates a new service image
 func CreateServiceImage(ctx context.Context, db pgx.Tx, image models.ServiceImage) error {
 	query := `
-		INSERT INTO service_images (id, service_id, image_id, image_name, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO service_images (id, service_id, image_id, image_name, repo_digest, service_name, deployment_id,
+		                            created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 	_, err := db.Exec(ctx, query,
 		image.ID,
 		image.ServiceID,
 		image.ImageID,
 		image.ImageName,
+		image.RepoDigest,
+		image.ServiceName,
+		image.DeploymentID,
 		image.CreatedAt,
 		image.UpdatedAt,
 	)
@@ -401,6 +644,13 @@ func DeleteServiceImages(ctx context.Context, db pgx.Tx, serviceID string) error
 	return err
 }
 
+// DeleteServiceImagesByIDs deletes the given service image records
+func DeleteServiceImagesByIDs(ctx context.Context, db pgx.Tx, ids []string) error {
+	query := `DELETE FROM service_images WHERE id = ANY($1)`
+	_, err := db.Exec(ctx, query, ids)
+	return err
+}
+
 // +----------------------------------------------+
 // | Service Network Functions                    |
 // +----------------------------------------------+
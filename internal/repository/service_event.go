@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// CreateServiceEvent creates a new service event
+func CreateServiceEvent(ctx context.Context, db pgx.Tx, event models.ServiceEvent) error {
+	query := `
+		INSERT INTO service_events (id, team_id, service_id, type, message, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := db.Exec(ctx, query,
+		event.ID,
+		event.TeamID,
+		event.ServiceID,
+		event.Type,
+		event.Message,
+		event.Metadata,
+		event.CreatedAt,
+	)
+	return err
+}
+
+// GetServiceEvents gets the latest events of a service, newest first
+func GetServiceEvents(ctx context.Context, db pgx.Tx, serviceID string, limit int) ([]models.ServiceEvent, error) {
+	query := `
+		SELECT id, team_id, service_id, type, message, metadata, created_at
+		FROM service_events
+		WHERE service_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := db.Query(ctx, query, serviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.ServiceEvent{}
+	for rows.Next() {
+		var event models.ServiceEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.TeamID,
+			&event.ServiceID,
+			&event.Type,
+			&event.Message,
+			&event.Metadata,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// DeleteServiceEvents deletes all events of a service
+func DeleteServiceEvents(ctx context.Context, db pgx.Tx, serviceID string) error {
+	query := `DELETE FROM service_events WHERE service_id = $1`
+	_, err := db.Exec(ctx, query, serviceID)
+	return err
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// GetServiceImageUpdates gets the available image updates of a service
+func GetServiceImageUpdates(ctx context.Context, db pgx.Tx, serviceID string) ([]models.ServiceImageUpdate, error) {
+	query := `
+		SELECT id, service_id, service_name, image_name, current_digest, available_digest, detected_at, checked_at
+		FROM service_image_updates
+		WHERE service_id = $1
+		ORDER BY service_name ASC
+	`
+	rows, err := db.Query(ctx, query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updates := []models.ServiceImageUpdate{}
+	for rows.Next() {
+		var update models.ServiceImageUpdate
+		err := rows.Scan(
+			&update.ID,
+			&update.ServiceID,
+			&update.ServiceName,
+			&update.ImageName,
+			&update.CurrentDigest,
+			&update.AvailableDigest,
+			&update.DetectedAt,
+			&update.CheckedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+
+	return updates, rows.Err()
+}
+
+// GetServiceImageUpdate gets the available image update of a compose service
+func GetServiceImageUpdate(ctx context.Context, db pgx.Tx, serviceID, serviceName string) (*models.ServiceImageUpdate, error) {
+	query := `
+		SELECT id, service_id, service_name, image_name, current_digest, available_digest, detected_at, checked_at
+		FROM service_image_updates
+		WHERE service_id = $1 AND service_name = $2
+	`
+	var update models.ServiceImageUpdate
+	err := db.QueryRow(ctx, query, serviceID, serviceName).Scan(
+		&update.ID,
+		&update.ServiceID,
+		&update.ServiceName,
+		&update.ImageName,
+		&update.CurrentDigest,
+		&update.AvailableDigest,
+		&update.DetectedAt,
+		&update.CheckedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &update, nil
+}
+
+// UpsertServiceImageUpdate creates or replaces the available image update of a compose service
+func UpsertServiceImageUpdate(ctx context.Context, db pgx.Tx, update models.ServiceImageUpdate) error {
+	query := `
+		INSERT INTO service_image_updates (id, service_id, service_name, image_name, current_digest, available_digest,
+		                                   detected_at, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (service_id, service_name)
+		DO UPDATE SET image_name = EXCLUDED.image_name, current_digest = EXCLUDED.current_digest,
+		              available_digest = EXCLUDED.available_digest, detected_at = EXCLUDED.detected_at,
+		              checked_at = EXCLUDED.checked_at
+	`
+	_, err := db.Exec(ctx, query,
+		update.ID,
+		update.ServiceID,
+		update.ServiceName,
+		update.ImageName,
+		update.CurrentDigest,
+		update.AvailableDigest,
+		update.DetectedAt,
+		update.CheckedAt,
+	)
+	return err
+}
+
+// DeleteServiceImageUpdate deletes the image update of a compose service once it is up to date
+func DeleteServiceImageUpdate(ctx context.Context, db pgx.Tx, serviceID, serviceName string) error {
+	query := `DELETE FROM service_image_updates WHERE service_id = $1 AND service_name = $2`
+	_, err := db.Exec(ctx, query, serviceID, serviceName)
+	return err
+}
+
+// DeleteServiceImageUpdates deletes all image updates of a service
+func DeleteServiceImageUpdates(ctx context.Context, db pgx.Tx, serviceID string) error {
+	query := `DELETE FROM service_image_updates WHERE service_id = $1`
+	_, err := db.Exec(ctx, query, serviceID)
+	return err
+}
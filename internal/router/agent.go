@@ -0,0 +1,18 @@
+package router
+
+import (
+	"github.com/go-chi/chi/v5"
+
+	"github.com/yorukot/starker/internal/handler"
+	"github.com/yorukot/starker/internal/handler/agent"
+)
+
+// AgentRouter sets up the route starker-agent connects to, agents authenticate with their own token
+func AgentRouter(r chi.Router, app *handler.App) {
+
+	agentHandler := agent.AgentHandler{
+		DB: app.DB,
+	}
+
+	r.Get("/agent/connect", agentHandler.ConnectAgent)
+}
@@ -19,6 +19,7 @@ func PrivateKeyRouter(r chi.Router, app *handler.App) {
 		r.Use(middleware.AuthRequiredMiddleware)
 
 		r.Post("/", privateKeyHandler.CreatePrivateKey)
+		r.Post("/generate", privateKeyHandler.GeneratePrivateKey)
 		r.Get("/", privateKeyHandler.GetPrivateKeys)
 		r.Get("/{privateKeyID}", privateKeyHandler.GetPrivateKey)
 		r.Delete("/{privateKeyID}", privateKeyHandler.DeletePrivateKey)
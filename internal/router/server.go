@@ -5,6 +5,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/yorukot/starker/internal/core/hostkeys"
+	"github.com/yorukot/starker/internal/core/wsticket"
 	"github.com/yorukot/starker/internal/handler"
 	"github.com/yorukot/starker/internal/handler/server"
 	"github.com/yorukot/starker/internal/middleware"
@@ -15,6 +17,7 @@ import (
 func ServerRouter(r chi.Router, app *handler.App) {
 
 	dockerPool := connection.NewConnectionPool(20*time.Minute, 1*time.Hour)
+	dockerPool.SetHostKeyStore(hostkeys.NewStore(app.DB))
 
 	serverHandler := server.ServerHandler{
 		DB:         app.DB,
@@ -22,12 +25,43 @@ func ServerRouter(r chi.Router, app *handler.App) {
 	}
 
 	r.Route("/teams/{teamID}/servers", func(r chi.Router) {
-		r.Use(middleware.AuthRequiredMiddleware)
+		// Browsers can not send the Authorization header on a WebSocket handshake, they use a ticket instead
+		r.With(middleware.WebSocketAuthMiddleware(app.DB, wsticket.ResourceServerTerminal, "serverID")).
+			Get("/{serverID}/terminal", serverHandler.OpenTerminal)
 
-		r.Post("/", serverHandler.CreateServer)
-		r.Get("/", serverHandler.GetServers)
-		r.Get("/{serverID}", serverHandler.GetServer)
-		r.Patch("/{serverID}", serverHandler.UpdateServer)
-		r.Delete("/{serverID}", serverHandler.DeleteServer)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthRequiredMiddleware)
+
+			r.Post("/", serverHandler.CreateServer)
+			r.Get("/", serverHandler.GetServers)
+			r.Get("/{serverID}", serverHandler.GetServer)
+			r.Patch("/{serverID}", serverHandler.UpdateServer)
+			r.Delete("/{serverID}", serverHandler.DeleteServer)
+			r.Get("/{serverID}/metrics", serverHandler.GetServerMetrics)
+			r.Post("/{serverID}/bootstrap", serverHandler.BootstrapServer)
+			r.Get("/{serverID}/host-key", serverHandler.GetHostKey)
+			r.Put("/{serverID}/host-key", serverHandler.AcceptHostKey)
+			r.Post("/{serverID}/agent-token", serverHandler.RotateAgentToken)
+
+			r.Route("/{serverID}/docker", func(r chi.Router) {
+				r.Get("/containers", serverHandler.GetDockerContainers)
+				r.Get("/images", serverHandler.GetDockerImages)
+				r.Get("/volumes", serverHandler.GetDockerVolumes)
+				r.Get("/networks", serverHandler.GetDockerNetworks)
+				r.Post("/prune", serverHandler.PruneDocker)
+			})
+
+			r.Route("/{serverID}/orphans", func(r chi.Router) {
+				r.Get("/", serverHandler.GetOrphanedResources)
+				r.Post("/{orphanID}/approve", serverHandler.ApproveOrphanedResource)
+			})
+
+			r.Get("/{serverID}/compose-projects", serverHandler.GetComposeProjects)
+
+			// Not a sub router, it would take over the terminal WebSocket route above
+			r.Post("/{serverID}/terminal/ticket", serverHandler.CreateTerminalTicket)
+			r.Get("/{serverID}/terminal/sessions", serverHandler.GetTerminalSessions)
+			r.Get("/{serverID}/terminal/sessions/{sessionID}/recording", serverHandler.DownloadTerminalRecording)
+		})
 	})
 }
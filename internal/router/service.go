@@ -5,6 +5,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/yorukot/starker/internal/core/hostkeys"
+	"github.com/yorukot/starker/internal/core/wsticket"
 	"github.com/yorukot/starker/internal/handler"
 	"github.com/yorukot/starker/internal/handler/service"
 	"github.com/yorukot/starker/internal/middleware"
@@ -13,6 +15,7 @@ import (
 
 func ServiceRouter(r chi.Router, app *handler.App) {
 	dockerPool := connection.NewConnectionPool(20*time.Minute, 1*time.Hour)
+	dockerPool.SetHostKeyStore(hostkeys.NewStore(app.DB))
 
 	serviceHandler := service.ServiceHandler{
 		DB:             app.DB,
@@ -21,31 +24,59 @@ func ServiceRouter(r chi.Router, app *handler.App) {
 	}
 
 	r.Route("/teams/{teamID}/projects/{projectID}/services", func(r chi.Router) {
-		r.Use(middleware.AuthRequiredMiddleware)
+		// Browsers can not send the Authorization header on a WebSocket handshake, they use a ticket instead
+		r.With(middleware.WebSocketAuthMiddleware(app.DB, wsticket.ResourceContainerExec, "containerID")).
+			Get("/{serviceID}/containers/{containerID}/exec", serviceHandler.ExecContainer)
 
-		r.Get("/", serviceHandler.GetServices)
-		r.Get("/{serviceID}", serviceHandler.GetService)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthRequiredMiddleware)
 
-		r.Post("/compose", serviceHandler.CreateServiceCompose)
-		// r.Post("/git", serviceHandler.CreateServiceGit)
+			r.Get("/", serviceHandler.GetServices)
+			r.Get("/{serviceID}", serviceHandler.GetService)
 
-		r.Patch("/{serviceID}/", serviceHandler.UpdateService)
-		r.Delete("/{serviceID}/", serviceHandler.DeleteService)
-		r.Patch("/{serviceID}/state", serviceHandler.UpdateServiceState)
+			r.Post("/compose", serviceHandler.CreateServiceCompose)
+			r.Post("/compose/validate", serviceHandler.ValidateCompose)
+			r.Post("/adopt", serviceHandler.AdoptComposeProject)
+			// r.Post("/git", serviceHandler.CreateServiceGit)
 
-		r.Route("/{serviceID}/compose", func(r chi.Router) {
-			r.Get("/", serviceHandler.GetServiceCompose)
-			r.Patch("/", serviceHandler.UpdateServiceCompose)
-		})
+			r.Patch("/{serviceID}/", serviceHandler.UpdateService)
+			r.Delete("/{serviceID}/", serviceHandler.DeleteService)
+			r.Patch("/{serviceID}/state", serviceHandler.UpdateServiceState)
 
-		r.Route("/{serviceID}/env", func(r chi.Router) {
-			r.Get("/", serviceHandler.GetServiceEnvironments)
-			r.Patch("/", serviceHandler.UpdateServiceEnvironments)
-		})
+			r.Route("/{serviceID}/compose", func(r chi.Router) {
+				r.Get("/", serviceHandler.GetServiceCompose)
+				r.Patch("/", serviceHandler.UpdateServiceCompose)
+			})
+
+			r.Route("/{serviceID}/env", func(r chi.Router) {
+				r.Get("/", serviceHandler.GetServiceEnvironments)
+				r.Patch("/", serviceHandler.UpdateServiceEnvironments)
+				r.Get("/dotenv", serviceHandler.ExportServiceEnvironments)
+				r.Put("/dotenv", serviceHandler.ImportServiceEnvironments)
+			})
+
+			r.Route("/{serviceID}/env-files", func(r chi.Router) {
+				r.Get("/", serviceHandler.GetServiceEnvFiles)
+				r.Put("/", serviceHandler.UpdateServiceEnvFiles)
+			})
+
+			r.Route("/{serviceID}/containers", func(r chi.Router) {
+				r.Get("/", serviceHandler.GetContainers)
+				r.Get("/{containerID}/logs", serviceHandler.GetContainerLogs)
+				r.Post("/{containerID}/exec/ticket", serviceHandler.CreateExecTicket)
+			})
+
+			r.Get("/{serviceID}/volumes/compatibility", serviceHandler.GetVolumeCompatibility)
+			r.Post("/{serviceID}/plan", serviceHandler.PlanService)
+
+			r.Get("/{serviceID}/image-updates", serviceHandler.GetServiceImageUpdates)
+			r.Get("/{serviceID}/events", serviceHandler.GetServiceEvents)
+			r.Get("/{serviceID}/export", serviceHandler.ExportService)
 
-		r.Route("/{serviceID}/containers", func(r chi.Router) {
-			r.Get("/", serviceHandler.GetContainers)
-			r.Get("/{containerID}/logs", serviceHandler.GetContainerLogs)
+			r.Route("/{serviceID}/stats", func(r chi.Router) {
+				r.Get("/", serviceHandler.GetServiceStats)
+				r.Get("/history", serviceHandler.GetServiceStatsHistory)
+			})
 		})
 	})
 }
@@ -0,0 +1,54 @@
+package privatekeysvc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/yorukot/starker/internal/repository"
+)
+
+// EncryptPlaintextPrivateKeys encrypts the private keys that were stored before encryption at rest
+func EncryptPlaintextPrivateKeys(ctx context.Context, db *pgxpool.Pool) error {
+	tx, err := repository.StartTransaction(db, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	count, err := repository.EncryptPlaintextPrivateKeys(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt private keys: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if count > 0 {
+		zap.L().Info("Encrypted plaintext private keys", zap.Int("count", count))
+	}
+	return nil
+}
+
+// RotateMasterKey re-encrypts the data keys of all private keys with the new master key in one transaction
+func RotateMasterKey(ctx context.Context, db *pgxpool.Pool, oldMasterKey, newMasterKey []byte) (int, error) {
+	tx, err := repository.StartTransaction(db, ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer repository.DeferRollback(tx, ctx)
+
+	count, err := repository.RotatePrivateKeyMasterKey(ctx, tx, oldMasterKey, newMasterKey)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,126 @@
+package privatekeysvc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/segmentio/ksuid"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/yorukot/starker/internal/models"
+)
+
+// KeyType is the algorithm of a generated key pair
+type KeyType string
+
+const (
+	KeyTypeEd25519 KeyType = "ed25519"
+	KeyTypeRSA     KeyType = "rsa"
+)
+
+// defaultRSABits is used when no RSA key size is requested
+const defaultRSABits = 4096
+
+type GenerateKeyPairRequest struct {
+	Name        string  `json:"name" validate:"required,min=3,max=255"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
+	Type        KeyType `json:"type" validate:"required,oneof=ed25519 rsa" example:"ed25519"`            // Key algorithm
+	Bits        int     `json:"bits,omitempty" validate:"omitempty,oneof=2048 3072 4096" example:"4096"` // RSA key size, defaults to 4096
+}
+
+// GenerateKeyPairValidate validates the generate key pair request
+func GenerateKeyPairValidate(generateKeyPairRequest GenerateKeyPairRequest) error {
+	if err := validator.New().Struct(generateKeyPairRequest); err != nil {
+		return err
+	}
+	if generateKeyPairRequest.Type != KeyTypeRSA && generateKeyPairRequest.Bits != 0 {
+		return fmt.Errorf("bits can only be set for RSA keys")
+	}
+	return nil
+}
+
+// GenerateKeyPair generates a key pair and returns the PEM encoded private key and the public key in authorized_keys format
+func GenerateKeyPair(keyType KeyType, bits int, comment string) (privateKeyPEM string, publicKey string, err error) {
+	var block *pem.Block
+	var sshPublicKey ssh.PublicKey
+
+	switch keyType {
+	case KeyTypeEd25519:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate ed25519 key: %w", err)
+		}
+		if block, err = ssh.MarshalPrivateKey(private, comment); err != nil {
+			return "", "", fmt.Errorf("failed to encode ed25519 key: %w", err)
+		}
+		if sshPublicKey, err = ssh.NewPublicKey(public); err != nil {
+			return "", "", fmt.Errorf("failed to encode ed25519 public key: %w", err)
+		}
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+		private, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}
+		if sshPublicKey, err = ssh.NewPublicKey(&private.PublicKey); err != nil {
+			return "", "", fmt.Errorf("failed to encode RSA public key: %w", err)
+		}
+	default:
+		return "", "", fmt.Errorf("unsupported key type: %s", keyType)
+	}
+
+	publicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey)))
+	if comment != "" {
+		publicKey += " " + comment
+	}
+
+	return string(pem.EncodeToMemory(block)), publicKey, nil
+}
+
+// DerivePublicKey returns the public key of a PEM encoded private key in authorized_keys format,
+// or nil when the key can not be parsed
+func DerivePublicKey(privateKey string, passphrase *string) *string {
+	var signer ssh.Signer
+	var err error
+	if passphrase != nil {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(*passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(privateKey))
+	}
+	if err != nil {
+		return nil
+	}
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	return &publicKey
+}
+
+// GenerateKeyPairPrivateKey generates a key pair and the private key model that stores it
+func GenerateKeyPairPrivateKey(generateKeyPairRequest GenerateKeyPairRequest, teamID string) (models.PrivateKey, error) {
+	privateKeyPEM, publicKey, err := GenerateKeyPair(generateKeyPairRequest.Type, generateKeyPairRequest.Bits, "starker-"+teamID)
+	if err != nil {
+		return models.PrivateKey{}, err
+	}
+
+	now := time.Now()
+	return models.PrivateKey{
+		ID:          ksuid.New().String(),
+		TeamID:      teamID,
+		Name:        generateKeyPairRequest.Name,
+		Description: generateKeyPairRequest.Description,
+		PrivateKey:  privateKeyPEM,
+		Fingerprint: GenerateFingerprint(privateKeyPEM),
+		PublicKey:   &publicKey,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
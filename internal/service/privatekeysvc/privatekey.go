@@ -9,18 +9,23 @@ import (
 	"github.com/segmentio/ksuid"
 
 	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/pkg/connection"
 )
 
 type CreatePrivateKeyRequest struct {
 	Name        string  `json:"name" validate:"required,min=3,max=255"`
 	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
 	PrivateKey  string  `json:"private_key" validate:"required"`
+	Passphrase  *string `json:"passphrase,omitempty" validate:"omitempty,max=1024"`   // Passphrase of an encrypted private key
+	Certificate *string `json:"certificate,omitempty" validate:"omitempty,max=16384"` // OpenSSH user certificate signed for the private key
 }
 
 type UpdatePrivateKeyRequest struct {
 	Name        *string `json:"name,omitempty" validate:"omitempty,min=3,max=255"`
 	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
 	PrivateKey  *string `json:"private_key,omitempty" validate:"omitempty"`
+	Passphrase  *string `json:"passphrase,omitempty" validate:"omitempty,max=1024"`   // Passphrase of an encrypted private key, an empty string removes it
+	Certificate *string `json:"certificate,omitempty" validate:"omitempty,max=16384"` // OpenSSH user certificate signed for the private key, an empty string removes it
 }
 
 // PrivateKeyValidate validates the create private key request
@@ -45,14 +50,18 @@ func GeneratePrivateKey(createPrivateKeyRequest CreatePrivateKeyRequest, teamID
 	fingerprint := GenerateFingerprint(createPrivateKeyRequest.PrivateKey)
 
 	return models.PrivateKey{
-		ID:          ksuid.New().String(),
-		TeamID:      teamID,
-		Name:        createPrivateKeyRequest.Name,
-		Description: createPrivateKeyRequest.Description,
-		PrivateKey:  createPrivateKeyRequest.PrivateKey,
-		Fingerprint: fingerprint,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:            ksuid.New().String(),
+		TeamID:        teamID,
+		Name:          createPrivateKeyRequest.Name,
+		Description:   createPrivateKeyRequest.Description,
+		PrivateKey:    createPrivateKeyRequest.PrivateKey,
+		Fingerprint:   fingerprint,
+		PublicKey:     DerivePublicKey(createPrivateKeyRequest.PrivateKey, nonEmpty(createPrivateKeyRequest.Passphrase)),
+		Passphrase:    nonEmpty(createPrivateKeyRequest.Passphrase),
+		HasPassphrase: nonEmpty(createPrivateKeyRequest.Passphrase) != nil,
+		Certificate:   nonEmpty(createPrivateKeyRequest.Certificate),
+		CreatedAt:     now,
+		UpdatedAt:     now,
 	}
 }
 
@@ -73,5 +82,49 @@ func UpdatePrivateKeyFields(privateKey *models.PrivateKey, updateRequest UpdateP
 		privateKey.Fingerprint = GenerateFingerprint(*updateRequest.PrivateKey)
 	}
 
+	if updateRequest.Passphrase != nil {
+		privateKey.Passphrase = nonEmpty(updateRequest.Passphrase)
+		privateKey.HasPassphrase = privateKey.Passphrase != nil
+	}
+
+	if updateRequest.Certificate != nil {
+		privateKey.Certificate = nonEmpty(updateRequest.Certificate)
+	}
+
+	if updateRequest.PrivateKey != nil || updateRequest.Passphrase != nil {
+		privateKey.PublicKey = DerivePublicKey(privateKey.PrivateKey, privateKey.Passphrase)
+	}
+
 	privateKey.UpdatedAt = now
 }
+
+// UpdatesCredentials reports whether the update request changes the key material used for authentication
+func (r UpdatePrivateKeyRequest) UpdatesCredentials() bool {
+	return r.PrivateKey != nil || r.Passphrase != nil || r.Certificate != nil
+}
+
+// ValidateCredentials checks that the private key can be parsed with its passphrase and matches its certificate
+func ValidateCredentials(privateKey models.PrivateKey) error {
+	_, err := connection.NewSigner(Credentials(privateKey))
+	return err
+}
+
+// Credentials returns the credentials the connection pool authenticates with
+func Credentials(privateKey models.PrivateKey) connection.Credentials {
+	credentials := connection.Credentials{PrivateKey: []byte(privateKey.PrivateKey)}
+	if privateKey.Passphrase != nil {
+		credentials.Passphrase = []byte(*privateKey.Passphrase)
+	}
+	if privateKey.Certificate != nil {
+		credentials.Certificate = []byte(*privateKey.Certificate)
+	}
+	return credentials
+}
+
+// nonEmpty returns nil for a missing or empty optional value
+func nonEmpty(value *string) *string {
+	if value == nil || *value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,5 @@
+DELETE FROM "public"."servers" WHERE "private_key_id" IS NULL;
+ALTER TABLE "public"."servers" ALTER COLUMN "private_key_id" SET NOT NULL;
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "tls_certificate";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "tls_ca_certificate";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "connection_type";
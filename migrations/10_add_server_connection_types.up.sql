@@ -0,0 +1,6 @@
+-- Servers can be reached over SSH, the local Docker socket or a TLS protected TCP daemon
+ALTER TABLE "public"."servers" ADD COLUMN "connection_type" text NOT NULL DEFAULT 'ssh';
+ALTER TABLE "public"."servers" ADD COLUMN "tls_ca_certificate" text;
+ALTER TABLE "public"."servers" ADD COLUMN "tls_certificate" text;
+-- Local servers do not need a private key
+ALTER TABLE "public"."servers" ALTER COLUMN "private_key_id" DROP NOT NULL;
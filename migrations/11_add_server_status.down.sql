@@ -0,0 +1,8 @@
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "arch";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "os";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "docker_version";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "latency_ms";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "last_seen_at";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "status_checked_at";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "status_error";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "status";
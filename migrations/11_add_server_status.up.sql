@@ -0,0 +1,9 @@
+-- Connectivity of the servers as recorded by the periodic status probe
+ALTER TABLE "public"."servers" ADD COLUMN "status" text NOT NULL DEFAULT 'unknown';
+ALTER TABLE "public"."servers" ADD COLUMN "status_error" text;
+ALTER TABLE "public"."servers" ADD COLUMN "status_checked_at" timestamp with time zone;
+ALTER TABLE "public"."servers" ADD COLUMN "last_seen_at" timestamp with time zone;
+ALTER TABLE "public"."servers" ADD COLUMN "latency_ms" integer;
+ALTER TABLE "public"."servers" ADD COLUMN "docker_version" text;
+ALTER TABLE "public"."servers" ADD COLUMN "os" text;
+ALTER TABLE "public"."servers" ADD COLUMN "arch" text;
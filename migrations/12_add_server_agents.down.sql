@@ -0,0 +1,3 @@
+DELETE FROM "public"."servers" WHERE "connection_type" = 'agent';
+DROP INDEX IF EXISTS "idx_servers_agent_token_hash";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "agent_token_hash";
@@ -0,0 +1,3 @@
+-- Agents authenticate their tunnel with a token, only its SHA-256 hash is stored
+ALTER TABLE "public"."servers" ADD COLUMN "agent_token_hash" text;
+CREATE UNIQUE INDEX "idx_servers_agent_token_hash" ON "public"."servers" ("agent_token_hash");
@@ -0,0 +1,8 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."orphaned_resources" DROP CONSTRAINT IF EXISTS "fk_orphaned_resources_server_id_servers_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "orphaned_resources_idx_orphaned_resources_server_id_resource";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."orphaned_resources";
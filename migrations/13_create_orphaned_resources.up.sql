@@ -0,0 +1,17 @@
+CREATE TABLE "public"."orphaned_resources" (
+    "id" character varying(27) NOT NULL,
+    "server_id" character varying(27) NOT NULL,
+    "service_id" character varying(27) NOT NULL,
+    "resource_type" text NOT NULL,
+    "resource_id" text NOT NULL,
+    "name" text NOT NULL,
+    "approved_at" timestamp,
+    "removal_error" text,
+    "first_seen_at" timestamp NOT NULL,
+    "last_seen_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE UNIQUE INDEX "orphaned_resources_idx_orphaned_resources_server_id_resource" ON "public"."orphaned_resources" ("server_id", "resource_type", "resource_id");
+
+ALTER TABLE "public"."orphaned_resources" ADD CONSTRAINT "fk_orphaned_resources_server_id_servers_id" FOREIGN KEY("server_id") REFERENCES "public"."servers"("id");
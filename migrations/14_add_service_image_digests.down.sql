@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "service_images_idx_service_images_service_id_created_at";
+ALTER TABLE "public"."service_images" DROP COLUMN IF EXISTS "repo_digest";
+ALTER TABLE "public"."service_images" DROP COLUMN IF EXISTS "service_name";
+ALTER TABLE "public"."service_images" DROP COLUMN IF EXISTS "deployment_id";
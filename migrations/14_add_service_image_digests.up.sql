@@ -0,0 +1,5 @@
+-- Every pull records the resolved image of each compose service, grouped by the deployment it belongs to
+ALTER TABLE "public"."service_images" ADD COLUMN "deployment_id" character varying(27) NOT NULL DEFAULT '';
+ALTER TABLE "public"."service_images" ADD COLUMN "service_name" text NOT NULL DEFAULT '';
+ALTER TABLE "public"."service_images" ADD COLUMN "repo_digest" text;
+CREATE INDEX "service_images_idx_service_images_service_id_created_at" ON "public"."service_images" ("service_id", "created_at");
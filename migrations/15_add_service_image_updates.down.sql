@@ -0,0 +1,16 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."service_events" DROP CONSTRAINT IF EXISTS "fk_service_events_team_id_teams_id";
+ALTER TABLE "public"."service_events" DROP CONSTRAINT IF EXISTS "fk_service_events_service_id_services_id";
+ALTER TABLE "public"."service_image_updates" DROP CONSTRAINT IF EXISTS "fk_service_image_updates_service_id_services_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "service_events_idx_service_events_team_id_created_at";
+DROP INDEX IF EXISTS "service_events_idx_service_events_service_id_created_at";
+DROP INDEX IF EXISTS "service_image_updates_idx_service_image_updates_service_id_service_name";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."service_events";
+DROP TABLE IF EXISTS "public"."service_image_updates";
+
+ALTER TABLE "public"."services" DROP COLUMN IF EXISTS "auto_update_window";
+ALTER TABLE "public"."services" DROP COLUMN IF EXISTS "auto_update";
@@ -0,0 +1,34 @@
+-- Services can opt in to be redeployed when the tags of their images point to a new digest
+ALTER TABLE "public"."services" ADD COLUMN "auto_update" boolean NOT NULL DEFAULT false;
+ALTER TABLE "public"."services" ADD COLUMN "auto_update_window" text;
+
+CREATE TABLE "public"."service_image_updates" (
+    "id" character varying(27) NOT NULL,
+    "service_id" character varying(27) NOT NULL,
+    "service_name" text NOT NULL,
+    "image_name" text NOT NULL,
+    "current_digest" text NOT NULL,
+    "available_digest" text NOT NULL,
+    "detected_at" timestamp NOT NULL,
+    "checked_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE "public"."service_events" (
+    "id" character varying(27) NOT NULL,
+    "team_id" character varying(27) NOT NULL,
+    "service_id" character varying(27) NOT NULL,
+    "type" text NOT NULL,
+    "message" text NOT NULL,
+    "metadata" jsonb,
+    "created_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE UNIQUE INDEX "service_image_updates_idx_service_image_updates_service_id_service_name" ON "public"."service_image_updates" ("service_id", "service_name");
+CREATE INDEX "service_events_idx_service_events_service_id_created_at" ON "public"."service_events" ("service_id", "created_at");
+CREATE INDEX "service_events_idx_service_events_team_id_created_at" ON "public"."service_events" ("team_id", "created_at");
+
+ALTER TABLE "public"."service_image_updates" ADD CONSTRAINT "fk_service_image_updates_service_id_services_id" FOREIGN KEY("service_id") REFERENCES "public"."services"("id");
+ALTER TABLE "public"."service_events" ADD CONSTRAINT "fk_service_events_service_id_services_id" FOREIGN KEY("service_id") REFERENCES "public"."services"("id");
+ALTER TABLE "public"."service_events" ADD CONSTRAINT "fk_service_events_team_id_teams_id" FOREIGN KEY("team_id") REFERENCES "public"."teams"("id");
@@ -0,0 +1,10 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."service_compose_files" DROP CONSTRAINT IF EXISTS "fk_service_compose_files_service_id_services_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "service_compose_files_idx_service_compose_files_service_id_filename";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."service_compose_files";
+
+ALTER TABLE "public"."service_compose_configs" DROP COLUMN IF EXISTS "profiles";
@@ -0,0 +1,18 @@
+-- Services can load overrides on top of the main compose file and activate compose profiles
+ALTER TABLE "public"."service_compose_configs" ADD COLUMN "profiles" text[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE "public"."service_compose_files" (
+    "id" character varying(27) NOT NULL,
+    "service_id" character varying(27) NOT NULL,
+    "filename" text NOT NULL,
+    "content" text NOT NULL,
+    "override" boolean NOT NULL DEFAULT true,
+    "position" integer NOT NULL,
+    "created_at" timestamp NOT NULL,
+    "updated_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE UNIQUE INDEX "service_compose_files_idx_service_compose_files_service_id_filename" ON "public"."service_compose_files" ("service_id", "filename");
+
+ALTER TABLE "public"."service_compose_files" ADD CONSTRAINT "fk_service_compose_files_service_id_services_id" FOREIGN KEY("service_id") REFERENCES "public"."services"("id");
@@ -0,0 +1,10 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."service_env_files" DROP CONSTRAINT IF EXISTS "fk_service_env_files_service_id_services_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "service_env_files_idx_service_env_files_service_id_name";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."service_env_files";
+
+ALTER TABLE "public"."service_environments" DROP COLUMN IF EXISTS "comment";
@@ -0,0 +1,16 @@
+-- Environment variables keep the comment written above them in an imported dotenv file
+ALTER TABLE "public"."service_environments" ADD COLUMN "comment" text;
+
+CREATE TABLE "public"."service_env_files" (
+    "id" character varying(27) NOT NULL,
+    "service_id" character varying(27) NOT NULL,
+    "name" text NOT NULL,
+    "content" text NOT NULL,
+    "created_at" timestamp NOT NULL,
+    "updated_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE UNIQUE INDEX "service_env_files_idx_service_env_files_service_id_name" ON "public"."service_env_files" ("service_id", "name");
+
+ALTER TABLE "public"."service_env_files" ADD CONSTRAINT "fk_service_env_files_service_id_services_id" FOREIGN KEY("service_id") REFERENCES "public"."services"("id");
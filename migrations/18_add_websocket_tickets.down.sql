@@ -0,0 +1,9 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."websocket_tickets" DROP CONSTRAINT IF EXISTS "fk_websocket_tickets_user_id_users_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "websocket_tickets_idx_websocket_tickets_expires_at";
+DROP INDEX IF EXISTS "websocket_tickets_idx_websocket_tickets_ticket_hash";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."websocket_tickets";
@@ -0,0 +1,16 @@
+-- Browsers can not send the Authorization header on a WebSocket handshake, they authenticate with a single use ticket
+CREATE TABLE "public"."websocket_tickets" (
+    "id" character varying(27) NOT NULL,
+    "ticket_hash" text NOT NULL,
+    "user_id" character varying(27) NOT NULL,
+    "resource_type" text NOT NULL,
+    "resource_id" character varying(27) NOT NULL,
+    "expires_at" timestamp NOT NULL,
+    "created_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE UNIQUE INDEX "websocket_tickets_idx_websocket_tickets_ticket_hash" ON "public"."websocket_tickets" ("ticket_hash");
+CREATE INDEX "websocket_tickets_idx_websocket_tickets_expires_at" ON "public"."websocket_tickets" ("expires_at");
+
+ALTER TABLE "public"."websocket_tickets" ADD CONSTRAINT "fk_websocket_tickets_user_id_users_id" FOREIGN KEY("user_id") REFERENCES "public"."users"("id");
@@ -0,0 +1,11 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."audit_logs" DROP CONSTRAINT IF EXISTS "fk_audit_logs_user_id_users_id";
+ALTER TABLE "public"."audit_logs" DROP CONSTRAINT IF EXISTS "fk_audit_logs_team_id_teams_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "audit_logs_idx_audit_logs_created_at";
+DROP INDEX IF EXISTS "audit_logs_idx_audit_logs_resource_id";
+DROP INDEX IF EXISTS "audit_logs_idx_audit_logs_team_id";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."audit_logs";
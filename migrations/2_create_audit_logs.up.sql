@@ -0,0 +1,20 @@
+CREATE TABLE "public"."audit_logs" (
+    "id" character varying(27) NOT NULL,
+    "team_id" character varying(27) NOT NULL,
+    "user_id" character varying(27) NOT NULL,
+    "action" text NOT NULL,
+    "resource_type" text NOT NULL,
+    "resource_id" character varying(27) NOT NULL,
+    "metadata" jsonb,
+    "ip" text,
+    "ended_at" timestamp,
+    "created_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE INDEX "audit_logs_idx_audit_logs_team_id" ON "public"."audit_logs" ("team_id");
+CREATE INDEX "audit_logs_idx_audit_logs_resource_id" ON "public"."audit_logs" ("resource_id");
+CREATE INDEX "audit_logs_idx_audit_logs_created_at" ON "public"."audit_logs" ("created_at");
+
+ALTER TABLE "public"."audit_logs" ADD CONSTRAINT "fk_audit_logs_team_id_teams_id" FOREIGN KEY("team_id") REFERENCES "public"."teams"("id");
+ALTER TABLE "public"."audit_logs" ADD CONSTRAINT "fk_audit_logs_user_id_users_id" FOREIGN KEY("user_id") REFERENCES "public"."users"("id");
@@ -0,0 +1,9 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."container_stats" DROP CONSTRAINT IF EXISTS "fk_container_stats_service_id_services_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "container_stats_idx_container_stats_resolution_sampled_at";
+DROP INDEX IF EXISTS "container_stats_service_id_container_name_resolution_sampled_at_key";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."container_stats";
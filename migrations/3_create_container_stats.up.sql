@@ -0,0 +1,21 @@
+CREATE TABLE "public"."container_stats" (
+    "id" character varying(27) NOT NULL,
+    "service_id" character varying(27) NOT NULL,
+    "container_name" text NOT NULL,
+    "resolution" text NOT NULL,
+    "cpu_percent" double precision NOT NULL,
+    "memory_usage" bigint NOT NULL,
+    "memory_limit" bigint NOT NULL,
+    "memory_percent" double precision NOT NULL,
+    "network_rx" bigint NOT NULL,
+    "network_tx" bigint NOT NULL,
+    "block_read" bigint NOT NULL,
+    "block_write" bigint NOT NULL,
+    "sampled_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE UNIQUE INDEX "container_stats_service_id_container_name_resolution_sampled_at_key" ON "public"."container_stats" ("service_id", "container_name", "resolution", "sampled_at");
+CREATE INDEX "container_stats_idx_container_stats_resolution_sampled_at" ON "public"."container_stats" ("resolution", "sampled_at");
+
+ALTER TABLE "public"."container_stats" ADD CONSTRAINT "fk_container_stats_service_id_services_id" FOREIGN KEY("service_id") REFERENCES "public"."services"("id");
@@ -0,0 +1,9 @@
+-- Drop foreign key constraints first
+ALTER TABLE "public"."server_metrics" DROP CONSTRAINT IF EXISTS "fk_server_metrics_server_id_servers_id";
+
+-- Drop indexes
+DROP INDEX IF EXISTS "server_metrics_idx_server_metrics_sampled_at";
+DROP INDEX IF EXISTS "server_metrics_idx_server_metrics_server_id_sampled_at";
+
+-- Drop tables
+DROP TABLE IF EXISTS "public"."server_metrics";
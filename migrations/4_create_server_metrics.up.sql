@@ -0,0 +1,24 @@
+CREATE TABLE "public"."server_metrics" (
+    "id" character varying(27) NOT NULL,
+    "server_id" character varying(27) NOT NULL,
+    "load1" double precision NOT NULL,
+    "load5" double precision NOT NULL,
+    "load15" double precision NOT NULL,
+    "cpu_count" integer NOT NULL,
+    "memory_total" bigint NOT NULL,
+    "memory_available" bigint NOT NULL,
+    "swap_total" bigint NOT NULL,
+    "swap_free" bigint NOT NULL,
+    "disks" jsonb NOT NULL,
+    "docker_images_size" bigint NOT NULL,
+    "docker_containers_size" bigint NOT NULL,
+    "docker_volumes_size" bigint NOT NULL,
+    "docker_build_cache_size" bigint NOT NULL,
+    "sampled_at" timestamp NOT NULL,
+    PRIMARY KEY ("id")
+);
+-- Indexes
+CREATE INDEX "server_metrics_idx_server_metrics_server_id_sampled_at" ON "public"."server_metrics" ("server_id", "sampled_at");
+CREATE INDEX "server_metrics_idx_server_metrics_sampled_at" ON "public"."server_metrics" ("sampled_at");
+
+ALTER TABLE "public"."server_metrics" ADD CONSTRAINT "fk_server_metrics_server_id_servers_id" FOREIGN KEY("server_id") REFERENCES "public"."servers"("id");
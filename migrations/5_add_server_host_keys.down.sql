@@ -0,0 +1 @@
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "host_key";
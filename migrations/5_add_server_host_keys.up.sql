@@ -0,0 +1 @@
+ALTER TABLE "public"."servers" ADD COLUMN "host_key" text;
@@ -0,0 +1,12 @@
+-- Encrypted keys can not be decrypted in SQL, refuse to roll back instead of losing them
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM "public"."private_keys" WHERE "private_key_ciphertext" IS NOT NULL) THEN
+        RAISE EXCEPTION 'private_keys contains encrypted keys that can not be decrypted in SQL, delete them or store them as plaintext before rolling back migration 6';
+    END IF;
+END $$;
+
+ALTER TABLE "public"."private_keys" ALTER COLUMN "private_key" SET NOT NULL;
+
+ALTER TABLE "public"."private_keys" DROP COLUMN IF EXISTS "encrypted_data_key";
+ALTER TABLE "public"."private_keys" DROP COLUMN IF EXISTS "private_key_ciphertext";
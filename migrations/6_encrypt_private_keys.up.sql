@@ -0,0 +1,4 @@
+-- Key material is envelope encrypted, plaintext keys are encrypted on startup and cleared
+ALTER TABLE "public"."private_keys" ADD COLUMN "private_key_ciphertext" bytea;
+ALTER TABLE "public"."private_keys" ADD COLUMN "encrypted_data_key" bytea;
+ALTER TABLE "public"."private_keys" ALTER COLUMN "private_key" DROP NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE "public"."private_keys" DROP COLUMN IF EXISTS "public_key";
@@ -0,0 +1 @@
+ALTER TABLE "public"."private_keys" ADD COLUMN "public_key" text;
@@ -0,0 +1,2 @@
+ALTER TABLE "public"."private_keys" DROP COLUMN IF EXISTS "certificate";
+ALTER TABLE "public"."private_keys" DROP COLUMN IF EXISTS "passphrase_ciphertext";
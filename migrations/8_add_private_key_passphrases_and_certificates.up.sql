@@ -0,0 +1,3 @@
+-- Passphrases are encrypted with the data key of the private key, certificates are public
+ALTER TABLE "public"."private_keys" ADD COLUMN "passphrase_ciphertext" bytea;
+ALTER TABLE "public"."private_keys" ADD COLUMN "certificate" text;
@@ -0,0 +1,2 @@
+ALTER TABLE "public"."servers" DROP CONSTRAINT IF EXISTS "fk_servers_jump_host_id_servers_id";
+ALTER TABLE "public"."servers" DROP COLUMN IF EXISTS "jump_host_id";
@@ -0,0 +1,3 @@
+-- Servers that are only reachable through a bastion reference it as their jump host
+ALTER TABLE "public"."servers" ADD COLUMN "jump_host_id" character varying(27);
+ALTER TABLE "public"."servers" ADD CONSTRAINT "fk_servers_jump_host_id_servers_id" FOREIGN KEY("jump_host_id") REFERENCES "public"."servers"("id");
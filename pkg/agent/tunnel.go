@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The tunnel between an agent and Starker is an SSH connection carried over the WebSocket the agent dials.
+// The agent is the SSH server and Starker the client, so every Docker API request is an SSH channel
+// and the tunnel reuses the multiplexing and flow control of the SSH transport.
+// Both sides are authenticated before the tunnel starts: Starker by the TLS certificate of the API
+// and the agent by its token in the WebSocket handshake.
+
+const (
+	// DockerSocketChannel is the socket path requested in direct-streamlocal channels,
+	// the agent always connects it to the Docker socket it was configured with
+	DockerSocketChannel = "/var/run/docker.sock"
+	// keepaliveInterval is how often the agent checks that Starker is still reachable
+	keepaliveInterval = 30 * time.Second
+	// handshakeTimeout limits the SSH handshake over a freshly opened WebSocket
+	handshakeTimeout = 15 * time.Second
+)
+
+// streamLocalChannelData is the payload of a direct-streamlocal@openssh.com channel request
+type streamLocalChannelData struct {
+	SocketPath string
+	Reserved0  string
+	Reserved1  uint32
+}
+
+// NewClient starts the Starker side of the tunnel on an accepted agent connection
+func NewClient(conn net.Conn, agentID string) (*ssh.Client, error) {
+	sshConfig := &ssh.ClientConfig{
+		User: agentID,
+		// The agent was authenticated by its token, its host key is generated on every start
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         handshakeTimeout,
+	}
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, agentID, sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start agent tunnel: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+// Serve runs the agent side of the tunnel on a connection to Starker until the connection closes.
+// Only channels to the Docker socket are accepted, they are proxied to dockerSocket.
+func Serve(conn net.Conn, dockerSocket string) error {
+	hostKey, err := newHostKey()
+	if err != nil {
+		return err
+	}
+
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(hostKey)
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return fmt.Errorf("failed to start agent tunnel: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	defer serverConn.Close()
+
+	go ssh.DiscardRequests(reqs)
+	go keepalive(serverConn)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-streamlocal@openssh.com" {
+			newChannel.Reject(ssh.UnknownChannelType, "only Docker socket channels are supported")
+			continue
+		}
+
+		var data streamLocalChannelData
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &data); err != nil || data.SocketPath != DockerSocketChannel {
+			newChannel.Reject(ssh.Prohibited, "only the Docker socket can be opened")
+			continue
+		}
+
+		go proxyDockerSocket(newChannel, dockerSocket)
+	}
+
+	return serverConn.Wait()
+}
+
+// proxyDockerSocket copies a channel to a new connection to the Docker socket
+func proxyDockerSocket(newChannel ssh.NewChannel, dockerSocket string) {
+	dockerConn, err := net.Dial("unix", dockerSocket)
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	defer dockerConn.Close()
+
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	go ssh.DiscardRequests(reqs)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(dockerConn, channel)
+		// Tell Docker the request is complete
+		if unixConn, ok := dockerConn.(*net.UnixConn); ok {
+			unixConn.CloseWrite()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(channel, dockerConn)
+		channel.CloseWrite()
+	}()
+	wg.Wait()
+}
+
+// keepalive closes the tunnel when Starker stops answering, so the agent dials again
+func keepalive(serverConn *ssh.ServerConn) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, _, err := serverConn.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+			serverConn.Close()
+			return
+		}
+	}
+}
+
+// newHostKey generates the throwaway host key of the agent side of the tunnel
+func newHostKey() (ssh.Signer, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tunnel host key: %w", err)
+	}
+	return ssh.NewSignerFromKey(privateKey)
+}
@@ -0,0 +1,118 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/client"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/yorukot/starker/pkg/agent"
+)
+
+// ErrAgentNotConnected is returned when the agent of a server has no open tunnel to Starker
+var ErrAgentNotConnected = errors.New("agent is not connected")
+
+// agentTunnels holds the SSH clients of the connected agents by agent ID.
+// The tunnels are accepted by the API and shared by every connection pool of the process.
+var agentTunnels = struct {
+	sync.RWMutex
+	clients map[string]*ssh.Client
+}{clients: make(map[string]*ssh.Client)}
+
+// AgentHost returns the host the connection pool reaches the Docker daemon of an agent with
+func AgentHost(agentID string) string {
+	return "agent://" + agentID
+}
+
+// RegisterAgentTunnel makes the tunnel of a connected agent available to the connection pools.
+// A tunnel of the same agent that is still registered is closed. The returned function
+// unregisters the tunnel again unless it was replaced in the meantime.
+func RegisterAgentTunnel(agentID string, sshClient *ssh.Client) (unregister func()) {
+	agentTunnels.Lock()
+	if previous, exists := agentTunnels.clients[agentID]; exists {
+		previous.Close()
+	}
+	agentTunnels.clients[agentID] = sshClient
+	agentTunnels.Unlock()
+
+	return func() {
+		agentTunnels.Lock()
+		defer agentTunnels.Unlock()
+		if agentTunnels.clients[agentID] == sshClient {
+			delete(agentTunnels.clients, agentID)
+		}
+	}
+}
+
+// IsAgentConnected reports whether the agent has an open tunnel
+func IsAgentConnected(agentID string) bool {
+	agentTunnels.RLock()
+	defer agentTunnels.RUnlock()
+	_, exists := agentTunnels.clients[agentID]
+	return exists
+}
+
+// getAgentTunnel returns the tunnel of the agent of an agent:// host
+func getAgentTunnel(host string) (*ssh.Client, error) {
+	agentID := strings.TrimPrefix(host, "agent://")
+
+	agentTunnels.RLock()
+	defer agentTunnels.RUnlock()
+	sshClient, exists := agentTunnels.clients[agentID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotConnected, agentID)
+	}
+	return sshClient, nil
+}
+
+// createAgentDockerClient creates a Docker client that reaches the daemon through the tunnel of an agent.
+// The tunnel is looked up on every dial, so a pooled client keeps working after the agent reconnects.
+func createAgentDockerClient(host string, opts ...client.Opt) (*client.Client, error) {
+	// Fail early when the agent is offline
+	if _, err := getAgentTunnel(host); err != nil {
+		return nil, err
+	}
+
+	dialer := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		sshClient, err := getAgentTunnel(host)
+		if err != nil {
+			return nil, err
+		}
+		return sshClient.DialContext(ctx, "unix", agent.DockerSocketChannel)
+	}
+
+	clientOpts := []client.Opt{
+		client.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{
+				DialContext: dialer,
+			},
+		}),
+		client.WithAPIVersionNegotiation(),
+	}
+
+	// Append any additional options provided
+	clientOpts = append(clientOpts, opts...)
+
+	dockerClient, err := client.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+
+	return dockerClient, nil
+}
+
+// DisconnectAgent closes the tunnel of an agent, used when its server is deleted or its token is replaced
+func DisconnectAgent(agentID string) {
+	agentTunnels.Lock()
+	defer agentTunnels.Unlock()
+	if sshClient, exists := agentTunnels.clients[agentID]; exists {
+		delete(agentTunnels.clients, agentID)
+		sshClient.Close()
+	}
+}
@@ -2,8 +2,6 @@ package connection
 
 import (
 	"context"
-	"crypto/x509"
-	"encoding/pem"
 	"fmt"
 	"io"
 	"net"
@@ -74,19 +72,27 @@ type ConnectionInfo struct {
 	dockerClient *client.Client // Docker API client (via SSH)
 	sshClient    *ssh.Client    // Raw SSH client
 	connType     ConnectionType // Type of primary connection
+	jumpHostID   string         // Connection ID of the bastion the connection is tunnelled through, if any
+	fingerprint  string         // Hash of the host and credentials the connection was opened with
 	lastUsed     time.Time
 	createdAt    time.Time
 }
 
 // ConnectionPool manages SSH-based connections for both Docker API access and direct SSH commands
 type ConnectionPool struct {
-	connections map[string]*ConnectionInfo
-	mutex       sync.RWMutex
-	maxIdle     time.Duration
-	maxLifetime time.Duration
-	ctx         context.Context
-	cancel      context.CancelFunc
-	closed      bool
+	connections  map[string]*ConnectionInfo
+	mutex        sync.RWMutex
+	maxIdle      time.Duration
+	maxLifetime  time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	closed       bool
+	hostKeyStore HostKeyStore // Pinned host keys, kept in memory unless SetHostKeyStore is called
+
+	// Bastion connections are pooled separately so targets behind the same bastion share them
+	jumpHosts       map[string]*jumpHostConnection
+	jumpMutex       sync.Mutex
+	jumpHostsClosed bool
 }
 
 // NewConnectionPool creates a new connection pool that supports SSH key-based authentication
@@ -94,11 +100,13 @@ func NewConnectionPool(maxIdle, maxLifetime time.Duration) *ConnectionPool {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	pool := &ConnectionPool{
-		connections: make(map[string]*ConnectionInfo),
-		maxIdle:     maxIdle,
-		maxLifetime: maxLifetime,
-		ctx:         ctx,
-		cancel:      cancel,
+		connections:  make(map[string]*ConnectionInfo),
+		maxIdle:      maxIdle,
+		maxLifetime:  maxLifetime,
+		ctx:          ctx,
+		cancel:       cancel,
+		hostKeyStore: newMemoryHostKeyStore(),
+		jumpHosts:    make(map[string]*jumpHostConnection),
 	}
 
 	// Start cleanup goroutine
@@ -113,9 +121,11 @@ func NewDockerConnectionPool(maxIdle, maxLifetime time.Duration) *ConnectionPool
 	return NewConnectionPool(maxIdle, maxLifetime)
 }
 
-// GetDockerConnection gets a Docker client from the pool using connectionID and privateKeyContent
+// GetDockerConnection gets a Docker client from the pool using connectionID and credentials
 // If the connection is not found, it will create a new SSH key-based connection using the provided private key
-func (p *ConnectionPool) GetDockerConnection(connectionID, host string, privateKeyContent []byte, opts ...client.Opt) (*client.Client, error) {
+func (p *ConnectionPool) GetDockerConnection(connectionID, host string, credentials Credentials, opts ...client.Opt) (*client.Client, error) {
+	p.evictChangedConnection(connectionID, credentials.fingerprint(host))
+
 	p.mutex.RLock()
 	if connInfo, exists := p.connections[connectionID]; exists {
 		// Check if connection needs reconnection due to lifetime expiration
@@ -130,7 +140,7 @@ func (p *ConnectionPool) GetDockerConnection(connectionID, host string, privateK
 			if connInfo, stillExists := p.connections[connectionID]; stillExists {
 				if p.maxLifetime > 0 && now.Sub(connInfo.createdAt) > p.maxLifetime {
 					// Reconnect the connection
-					err := p.reconnectConnection(connectionID, connInfo, host, privateKeyContent, opts...)
+					err := p.reconnectConnection(connectionID, connInfo, host, credentials, opts...)
 					if err != nil {
 						return nil, fmt.Errorf("failed to reconnect connection: %w", err)
 					}
@@ -143,7 +153,8 @@ func (p *ConnectionPool) GetDockerConnection(connectionID, host string, privateK
 		}
 
 		// Check if connection is still valid (not lifetime-expired)
-		if p.isConnectionValid(connInfo) {
+		// SSH only connections opened by ExecuteSSHCommand have no Docker client and are replaced below
+		if connInfo.dockerClient != nil && p.isConnectionValid(connInfo) {
 			connInfo.lastUsed = time.Now()
 			p.mutex.RUnlock()
 			return connInfo.dockerClient, nil
@@ -161,7 +172,7 @@ func (p *ConnectionPool) GetDockerConnection(connectionID, host string, privateK
 
 	// Double-check pattern
 	if connInfo, exists := p.connections[connectionID]; exists {
-		if p.isConnectionValid(connInfo) {
+		if connInfo.dockerClient != nil && p.isConnectionValid(connInfo) {
 			connInfo.lastUsed = time.Now()
 			return connInfo.dockerClient, nil
 		} else {
@@ -171,7 +182,7 @@ func (p *ConnectionPool) GetDockerConnection(connectionID, host string, privateK
 	}
 
 	// Create new connection with private key
-	dockerClient, sshConn, err := p.createDockerClient(host, privateKeyContent, opts...)
+	dockerClient, sshConn, err := p.dialDockerClient(connectionID, host, credentials, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker connection: %w", err)
 	}
@@ -181,6 +192,8 @@ func (p *ConnectionPool) GetDockerConnection(connectionID, host string, privateK
 		dockerClient: dockerClient,
 		sshClient:    sshConn,
 		connType:     DockerConnection,
+		jumpHostID:   credentials.jumpHostID(),
+		fingerprint:  credentials.fingerprint(host),
 		lastUsed:     now,
 		createdAt:    now,
 	}
@@ -189,15 +202,22 @@ func (p *ConnectionPool) GetDockerConnection(connectionID, host string, privateK
 }
 
 // GetConnection is deprecated, use GetDockerConnection instead
-// GetConnection gets an SSH connection from the pool using connectionID and privateKeyContent
+// GetConnection gets an SSH connection from the pool using connectionID and credentials
 // If the connection is not found, it will create a new SSH key-based connection using the provided private key
-func (p *ConnectionPool) GetConnection(connectionID, host string, privateKeyContent []byte, opts ...client.Opt) (*client.Client, error) {
-	return p.GetDockerConnection(connectionID, host, privateKeyContent, opts...)
+func (p *ConnectionPool) GetConnection(connectionID, host string, credentials Credentials, opts ...client.Opt) (*client.Client, error) {
+	return p.GetDockerConnection(connectionID, host, credentials, opts...)
 }
 
-// GetSSHConnection gets a raw SSH client from the pool using connectionID and privateKeyContent
+// GetSSHConnection gets a raw SSH client from the pool using connectionID and credentials
 // If the connection is not found, it will create a new SSH connection using the provided private key
-func (p *ConnectionPool) GetSSHConnection(connectionID, host string, privateKeyContent []byte) (*ssh.Client, error) {
+func (p *ConnectionPool) GetSSHConnection(connectionID, host string, credentials Credentials) (*ssh.Client, error) {
+	// Pooled connections to direct Docker endpoints have no SSH client
+	if IsDirectDockerHost(host) {
+		return nil, ErrSSHUnavailable
+	}
+
+	p.evictChangedConnection(connectionID, credentials.fingerprint(host))
+
 	p.mutex.RLock()
 	if connInfo, exists := p.connections[connectionID]; exists {
 		// Check if connection needs reconnection due to lifetime expiration
@@ -212,7 +232,7 @@ func (p *ConnectionPool) GetSSHConnection(connectionID, host string, privateKeyC
 			if connInfo, stillExists := p.connections[connectionID]; stillExists {
 				if p.maxLifetime > 0 && now.Sub(connInfo.createdAt) > p.maxLifetime {
 					// Reconnect the SSH connection
-					err := p.reconnectSSHConnection(connectionID, connInfo, host, privateKeyContent)
+					err := p.reconnectSSHConnection(connectionID, connInfo, host, credentials)
 					if err != nil {
 						return nil, fmt.Errorf("failed to reconnect SSH connection: %w", err)
 					}
@@ -253,7 +273,7 @@ func (p *ConnectionPool) GetSSHConnection(connectionID, host string, privateKeyC
 	}
 
 	// Create new SSH connection
-	sshConn, err := p.createRawSSHConnection(host, privateKeyContent)
+	sshConn, err := p.dialSSH(connectionID, host, credentials)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSH connection: %w", err)
 	}
@@ -263,6 +283,8 @@ func (p *ConnectionPool) GetSSHConnection(connectionID, host string, privateKeyC
 		dockerClient: nil, // No Docker client for raw SSH connections
 		sshClient:    sshConn,
 		connType:     SSHConnection,
+		jumpHostID:   credentials.jumpHostID(),
+		fingerprint:  credentials.fingerprint(host),
 		lastUsed:     now,
 		createdAt:    now,
 	}
@@ -334,15 +356,39 @@ func (p *ConnectionPool) removeConnection(connectionID string, connInfo *Connect
 	}
 }
 
-// RemoveConnection removes a connection from the pool by connectionID
+// evictChangedConnection closes the pooled connection when it was opened with a different host or credentials,
+// so an updated server or private key takes effect on the next use instead of when the connection expires
+func (p *ConnectionPool) evictChangedConnection(connectionID, fingerprint string) {
+	p.mutex.RLock()
+	connInfo, exists := p.connections[connectionID]
+	changed := exists && connInfo.fingerprint != fingerprint
+	p.mutex.RUnlock()
+	if !changed {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if connInfo, exists := p.connections[connectionID]; exists && connInfo.fingerprint != fingerprint {
+		p.removeConnection(connectionID, connInfo)
+	}
+}
+
+// RemoveConnection removes a connection from the pool by connectionID.
+// A pooled bastion connection with the same ID is closed as well.
 func (p *ConnectionPool) RemoveConnection(connectionID string) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	removedJumpHost := p.removeJumpHost(connectionID)
 	if connInfo, exists := p.connections[connectionID]; exists {
 		p.removeConnection(connectionID, connInfo)
 		return nil
 	}
+	if removedJumpHost {
+		return nil
+	}
 	return fmt.Errorf("connection with ID %s not found", connectionID)
 }
 
@@ -388,15 +434,21 @@ func (p *ConnectionPool) cleanup() {
 			}
 		}
 	}
+
+	// Close bastions that no remaining connection is tunnelled through
+	p.cleanupJumpHosts()
 }
 
 // TestConnection creates a new Docker connection using the provided private key, tests it, and immediately closes it
-// This function bypasses the connection pool and accepts private key content directly
-func (p *ConnectionPool) TestConnection(host string, privateKeyContent []byte, opts ...client.Opt) error {
+// This function bypasses the connection pool and accepts private key content directly.
+// The host key is verified against knownHostKey, an empty knownHostKey accepts any key.
+// It returns the host key presented by the server so it can be pinned.
+func (p *ConnectionPool) TestConnection(host string, credentials Credentials, knownHostKey string, opts ...client.Opt) (string, error) {
 	// Create a new Docker client for testing with the provided key
-	dockerClient, sshConn, err := p.createDockerClient(host, privateKeyContent, opts...)
+	verifier := &hostKeyVerifier{known: knownHostKey}
+	dockerClient, sshConn, err := p.createDockerClient(host, credentials, verifier.callback, opts...)
 	if err != nil {
-		return fmt.Errorf("failed to create Docker connection: %w", err)
+		return "", fmt.Errorf("failed to create Docker connection: %w", err)
 	}
 	defer dockerClient.Close()
 	if sshConn != nil {
@@ -409,85 +461,81 @@ func (p *ConnectionPool) TestConnection(host string, privateKeyContent []byte, o
 
 	_, err = dockerClient.Ping(ctx)
 	if err != nil {
-		return fmt.Errorf("Docker connection test failed: %w", err)
+		return "", fmt.Errorf("Docker connection test failed: %w", err)
 	}
 
-	return nil
+	return verifier.presented, nil
 }
 
-// createDockerClient creates a new Docker client with SSH key-based authentication using provided key content
-func (p *ConnectionPool) createDockerClient(host string, privateKeyContent []byte, opts ...client.Opt) (*client.Client, *ssh.Client, error) {
-	// Normalize host format - if it doesn't have ssh:// scheme, add it
-	if !strings.HasPrefix(host, "ssh://") {
-		// Assume user@host:port or host:port format and prepend ssh://
-		host = "ssh://" + host
+// TestSSHConnection opens a raw SSH connection with the provided private key and immediately closes it.
+// Unlike TestConnection it does not need Docker on the server, so it can be used before bootstrapping.
+// Like TestConnection it verifies and returns the host key.
+func (p *ConnectionPool) TestSSHConnection(host string, credentials Credentials, knownHostKey string) (string, error) {
+	verifier := &hostKeyVerifier{known: knownHostKey}
+	sshConn, err := p.createRawSSHConnection(host, credentials, verifier.callback)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH connection: %w", err)
 	}
+	sshConn.Close()
 
-	// Validate SSH key authentication
-	if err := p.validateSSHKeyAuth(host); err != nil {
-		return nil, nil, fmt.Errorf("SSH key validation failed: %w", err)
-	}
+	return verifier.presented, nil
+}
 
-	// Parse the SSH URL
-	parsedURL, err := url.Parse(host)
+// dialDockerClient creates a Docker client for a pooled connection and pins the host key on first use
+func (p *ConnectionPool) dialDockerClient(connectionID, host string, credentials Credentials, opts ...client.Opt) (*client.Client, *ssh.Client, error) {
+	verifier, err := p.newHostKeyVerifier(connectionID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid SSH URL format: %w", err)
+		return nil, nil, err
 	}
 
-	// Parse the private key
-	block, _ := pem.Decode(privateKeyContent)
-	if block == nil {
-		return nil, nil, fmt.Errorf("failed to parse private key PEM block")
+	dockerClient, sshConn, err := p.createDockerClient(host, credentials, verifier.callback, opts...)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var privateKey any
-	var parseErr error
-
-	switch block.Type {
-	case "RSA PRIVATE KEY":
-		privateKey, parseErr = x509.ParsePKCS1PrivateKey(block.Bytes)
-	case "PRIVATE KEY":
-		privateKey, parseErr = x509.ParsePKCS8PrivateKey(block.Bytes)
-	case "EC PRIVATE KEY":
-		privateKey, parseErr = x509.ParseECPrivateKey(block.Bytes)
-	case "OPENSSH PRIVATE KEY":
-		privateKey, parseErr = ssh.ParseRawPrivateKey(privateKeyContent)
-	default:
-		return nil, nil, fmt.Errorf("unsupported private key type: %s", block.Type)
+	if err := p.pinHostKey(connectionID, verifier); err != nil {
+		dockerClient.Close()
+		if sshConn != nil {
+			sshConn.Close()
+		}
+		return nil, nil, err
 	}
 
-	if parseErr != nil {
-		return nil, nil, fmt.Errorf("failed to parse private key: %w", parseErr)
+	return dockerClient, sshConn, nil
+}
+
+// dialSSH creates a raw SSH client for a pooled connection and pins the host key on first use
+func (p *ConnectionPool) dialSSH(connectionID, host string, credentials Credentials) (*ssh.Client, error) {
+	verifier, err := p.newHostKeyVerifier(connectionID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create SSH signer
-	signer, err := ssh.NewSignerFromKey(privateKey)
+	sshConn, err := p.createRawSSHConnection(host, credentials, verifier.callback)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create SSH signer: %w", err)
+		return nil, err
 	}
 
-	// Extract username from URL, default to "root" if not provided
-	username := "root"
-	if parsedURL.User != nil {
-		if user := parsedURL.User.Username(); user != "" {
-			username = user
-		}
+	if err := p.pinHostKey(connectionID, verifier); err != nil {
+		sshConn.Close()
+		return nil, err
 	}
 
-	// Create SSH client config
-	sshConfig := &ssh.ClientConfig{
-		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // In production, use proper host key validation
-		Timeout:         10 * time.Second,
+	return sshConn, nil
+}
+
+// createDockerClient creates a new Docker client with SSH key-based authentication using provided key content
+func (p *ConnectionPool) createDockerClient(host string, credentials Credentials, hostKeyCallback ssh.HostKeyCallback, opts ...client.Opt) (*client.Client, *ssh.Client, error) {
+	// Local sockets, TCP daemons and agents are used directly, without an SSH client
+	if IsDirectDockerHost(host) {
+		dockerClient, err := p.createDirectDockerClient(host, credentials, opts...)
+		return dockerClient, nil, err
 	}
 
 	// Establish persistent SSH connection
-	sshConn, err := ssh.Dial("tcp", parsedURL.Host, sshConfig)
+	sshConn, err := p.createRawSSHConnection(host, credentials, hostKeyCallback)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to connect via SSH: %w", err)
+		return nil, nil, err
 	}
 
 	// Create custom dialer that uses the established SSH connection
@@ -553,7 +601,7 @@ func (p *ConnectionPool) createDockerClient(host string, privateKeyContent []byt
 }
 
 // reconnectConnection recreates a connection with the same parameters, preserving lastUsed time
-func (p *ConnectionPool) reconnectConnection(connectionID string, oldConnInfo *ConnectionInfo, host string, privateKeyContent []byte, opts ...client.Opt) error {
+func (p *ConnectionPool) reconnectConnection(connectionID string, oldConnInfo *ConnectionInfo, host string, credentials Credentials, opts ...client.Opt) error {
 	// Preserve the last used time
 	lastUsed := oldConnInfo.lastUsed
 
@@ -566,7 +614,7 @@ func (p *ConnectionPool) reconnectConnection(connectionID string, oldConnInfo *C
 	}
 
 	// Create new connection
-	dockerClient, sshConn, err := p.createDockerClient(host, privateKeyContent, opts...)
+	dockerClient, sshConn, err := p.dialDockerClient(connectionID, host, credentials, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to create new Docker connection: %w", err)
 	}
@@ -576,6 +624,8 @@ func (p *ConnectionPool) reconnectConnection(connectionID string, oldConnInfo *C
 		dockerClient: dockerClient,
 		sshClient:    sshConn,
 		connType:     DockerConnection,
+		jumpHostID:   credentials.jumpHostID(),
+		fingerprint:  credentials.fingerprint(host),
 		lastUsed:     lastUsed,   // Preserve the last used time
 		createdAt:    time.Now(), // Reset creation time
 	}
@@ -584,7 +634,11 @@ func (p *ConnectionPool) reconnectConnection(connectionID string, oldConnInfo *C
 }
 
 // createRawSSHConnection creates a raw SSH client connection using the provided private key
-func (p *ConnectionPool) createRawSSHConnection(host string, privateKeyContent []byte) (*ssh.Client, error) {
+func (p *ConnectionPool) createRawSSHConnection(host string, credentials Credentials, hostKeyCallback ssh.HostKeyCallback) (*ssh.Client, error) {
+	if IsDirectDockerHost(host) {
+		return nil, ErrSSHUnavailable
+	}
+
 	// Normalize host format - if it doesn't have ssh:// scheme, add it
 	if !strings.HasPrefix(host, "ssh://") {
 		// Assume user@host:port or host:port format and prepend ssh://
@@ -602,36 +656,10 @@ func (p *ConnectionPool) createRawSSHConnection(host string, privateKeyContent [
 		return nil, fmt.Errorf("invalid SSH URL format: %w", err)
 	}
 
-	// Parse the private key
-	block, _ := pem.Decode(privateKeyContent)
-	if block == nil {
-		return nil, fmt.Errorf("failed to parse private key PEM block")
-	}
-
-	var privateKey any
-	var parseErr error
-
-	switch block.Type {
-	case "RSA PRIVATE KEY":
-		privateKey, parseErr = x509.ParsePKCS1PrivateKey(block.Bytes)
-	case "PRIVATE KEY":
-		privateKey, parseErr = x509.ParsePKCS8PrivateKey(block.Bytes)
-	case "EC PRIVATE KEY":
-		privateKey, parseErr = x509.ParseECPrivateKey(block.Bytes)
-	case "OPENSSH PRIVATE KEY":
-		privateKey, parseErr = ssh.ParseRawPrivateKey(privateKeyContent)
-	default:
-		return nil, fmt.Errorf("unsupported private key type: %s", block.Type)
-	}
-
-	if parseErr != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", parseErr)
-	}
-
-	// Create SSH signer
-	signer, err := ssh.NewSignerFromKey(privateKey)
+	// Create SSH signer, presenting the certificate if one is set
+	signer, err := NewSigner(credentials)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create SSH signer: %w", err)
+		return nil, err
 	}
 
 	// Extract username from URL, default to "root" if not provided
@@ -648,12 +676,12 @@ func (p *ConnectionPool) createRawSSHConnection(host string, privateKeyContent [
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // In production, use proper host key validation
+		HostKeyCallback: hostKeyCallback,
 		Timeout:         10 * time.Second,
 	}
 
-	// Establish SSH connection
-	sshConn, err := ssh.Dial("tcp", parsedURL.Host, sshConfig)
+	// Establish SSH connection, through the jump host if one is set
+	sshConn, err := p.dialSSHClient(parsedURL.Host, sshConfig, credentials.JumpHost)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect via SSH: %w", err)
 	}
@@ -662,7 +690,7 @@ func (p *ConnectionPool) createRawSSHConnection(host string, privateKeyContent [
 }
 
 // reconnectSSHConnection recreates an SSH connection with the same parameters, preserving lastUsed time
-func (p *ConnectionPool) reconnectSSHConnection(connectionID string, oldConnInfo *ConnectionInfo, host string, privateKeyContent []byte) error {
+func (p *ConnectionPool) reconnectSSHConnection(connectionID string, oldConnInfo *ConnectionInfo, host string, credentials Credentials) error {
 	// Preserve the last used time
 	lastUsed := oldConnInfo.lastUsed
 
@@ -672,7 +700,7 @@ func (p *ConnectionPool) reconnectSSHConnection(connectionID string, oldConnInfo
 	}
 
 	// Create new SSH connection
-	sshConn, err := p.createRawSSHConnection(host, privateKeyContent)
+	sshConn, err := p.dialSSH(connectionID, host, credentials)
 	if err != nil {
 		return fmt.Errorf("failed to create new SSH connection: %w", err)
 	}
@@ -682,8 +710,10 @@ func (p *ConnectionPool) reconnectSSHConnection(connectionID string, oldConnInfo
 		dockerClient: oldConnInfo.dockerClient, // Keep existing Docker client if any
 		sshClient:    sshConn,
 		connType:     SSHConnection, // This is primarily an SSH connection
-		lastUsed:     lastUsed,      // Preserve the last used time
-		createdAt:    time.Now(),    // Reset creation time
+		jumpHostID:   credentials.jumpHostID(),
+		fingerprint:  credentials.fingerprint(host),
+		lastUsed:     lastUsed,   // Preserve the last used time
+		createdAt:    time.Now(), // Reset creation time
 	}
 
 	return nil
@@ -705,4 +735,7 @@ func (p *ConnectionPool) Close() {
 	for connectionID, connInfo := range p.connections {
 		p.removeConnection(connectionID, connInfo)
 	}
+
+	// Close the bastions after the connections tunnelled through them
+	p.closeJumpHosts()
 }
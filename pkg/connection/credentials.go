@@ -0,0 +1,142 @@
+package connection
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Credentials is the key material used to authenticate SSH connections and TLS Docker endpoints
+type Credentials struct {
+	PrivateKey  []byte    // PEM encoded private key
+	Passphrase  []byte    // Passphrase of an encrypted private key, optional
+	Certificate []byte    // OpenSSH certificate for the private key in authorized_keys format, optional
+	JumpHost    *JumpHost // Bastion the connection is tunnelled through, optional
+
+	TLS *TLSCredentials // Client certificates for tcp:// Docker endpoints, unused over SSH
+}
+
+// NewSigner parses the private key of the credentials and wraps it with the certificate if one is set
+func NewSigner(credentials Credentials) (ssh.Signer, error) {
+	privateKey, err := parsePrivateKey(credentials.PrivateKey, credentials.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create SSH signer
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH signer: %w", err)
+	}
+
+	if len(credentials.Certificate) == 0 {
+		return signer, nil
+	}
+
+	// Present the certificate instead of the bare public key
+	certificate, err := ParseCertificate(credentials.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	if now := uint64(time.Now().Unix()); certificate.ValidBefore != ssh.CertTimeInfinity && now >= certificate.ValidBefore {
+		return nil, fmt.Errorf("SSH certificate expired at %s", time.Unix(int64(certificate.ValidBefore), 0).UTC().Format(time.RFC3339))
+	}
+	certSigner, err := ssh.NewCertSigner(certificate, signer)
+	if err != nil {
+		return nil, fmt.Errorf("SSH certificate does not belong to the private key: %w", err)
+	}
+
+	return certSigner, nil
+}
+
+// ParseCertificate parses an OpenSSH certificate in authorized_keys format
+func ParseCertificate(certificateContent []byte) (*ssh.Certificate, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(certificateContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH certificate: %w", err)
+	}
+	certificate, ok := publicKey.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("key is not an SSH certificate")
+	}
+	if certificate.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("SSH certificate is not a user certificate")
+	}
+	return certificate, nil
+}
+
+// parsePrivateKey parses a PEM encoded private key, decrypting it with the passphrase when one is given
+func parsePrivateKey(privateKeyContent, passphrase []byte) (any, error) {
+	if len(passphrase) > 0 {
+		privateKey, err := ssh.ParseRawPrivateKeyWithPassphrase(privateKeyContent, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key with passphrase: %w", err)
+		}
+		return privateKey, nil
+	}
+
+	// Parse the private key
+	block, _ := pem.Decode(privateKeyContent)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse private key PEM block")
+	}
+
+	var privateKey any
+	var parseErr error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, parseErr = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, parseErr = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, parseErr = x509.ParseECPrivateKey(block.Bytes)
+	case "OPENSSH PRIVATE KEY":
+		privateKey, parseErr = ssh.ParseRawPrivateKey(privateKeyContent)
+	default:
+		return nil, fmt.Errorf("unsupported private key type: %s", block.Type)
+	}
+
+	if parseErr != nil {
+		var passphraseMissing *ssh.PassphraseMissingError
+		if errors.As(parseErr, &passphraseMissing) || x509.IsEncryptedPEMBlock(block) {
+			return nil, fmt.Errorf("private key is encrypted, a passphrase is required")
+		}
+		return nil, fmt.Errorf("failed to parse private key: %w", parseErr)
+	}
+
+	return privateKey, nil
+}
+
+// fingerprint hashes the host and all key material of the credentials, including the jump host chain.
+// Pooled connections remember it so a changed server or key replaces the stale connection.
+func (c Credentials) fingerprint(host string) string {
+	hash := sha256.New()
+	c.writeFingerprint(hash, host)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// writeFingerprint writes the length prefixed fields of the credentials to the hash
+func (c Credentials) writeFingerprint(hash hash.Hash, host string) {
+	fields := [][]byte{[]byte(host), c.PrivateKey, c.Passphrase, c.Certificate}
+	if c.TLS != nil {
+		fields = append(fields, c.TLS.CACertificate, c.TLS.Certificate, c.TLS.PrivateKey)
+	}
+	for _, field := range fields {
+		binary.Write(hash, binary.BigEndian, uint64(len(field)))
+		hash.Write(field)
+	}
+
+	if c.JumpHost != nil {
+		hash.Write([]byte(c.JumpHost.ConnectionID))
+		c.JumpHost.Credentials.writeFingerprint(hash, c.JumpHost.Host)
+	}
+}
@@ -0,0 +1,90 @@
+package connection
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// ErrSSHUnavailable is returned for SSH operations on servers that are reached without SSH
+var ErrSSHUnavailable = errors.New("server is not connected over SSH")
+
+// TLSCredentials are the client certificates for a Docker daemon listening on TCP
+type TLSCredentials struct {
+	CACertificate []byte // PEM encoded CA that signed the daemon certificate
+	Certificate   []byte // PEM encoded client certificate
+	PrivateKey    []byte // PEM encoded key of the client certificate
+}
+
+// IsDirectDockerHost reports whether the host is a Docker endpoint that is used without SSH,
+// a local unix:// socket, a tcp:// daemon or the agent:// tunnel of an agent
+func IsDirectDockerHost(host string) bool {
+	return strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "tcp://") || strings.HasPrefix(host, "agent://")
+}
+
+// createDirectDockerClient creates a Docker client for a local socket, a TLS protected TCP daemon or an agent
+func (p *ConnectionPool) createDirectDockerClient(host string, credentials Credentials, opts ...client.Opt) (*client.Client, error) {
+	if strings.HasPrefix(host, "agent://") {
+		return createAgentDockerClient(host, opts...)
+	}
+
+	var clientOpts []client.Opt
+
+	if strings.HasPrefix(host, "tcp://") {
+		// The Docker API gives root on the server, so it is never used without client certificates
+		if credentials.TLS == nil {
+			return nil, fmt.Errorf("TLS client certificates are required for TCP Docker endpoints")
+		}
+		tlsConfig, err := newTLSConfig(*credentials.TLS)
+		if err != nil {
+			return nil, err
+		}
+
+		clientOpts = append(clientOpts, client.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:     tlsConfig,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+		}))
+	}
+
+	clientOpts = append(clientOpts,
+		client.WithHost(host),
+		client.WithAPIVersionNegotiation(),
+	)
+
+	// Append any additional options provided
+	clientOpts = append(clientOpts, opts...)
+
+	dockerClient, err := client.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+
+	return dockerClient, nil
+}
+
+// newTLSConfig builds the client TLS config that verifies the daemon against the CA
+func newTLSConfig(credentials TLSCredentials) (*tls.Config, error) {
+	certificate, err := tls.X509KeyPair(credentials.Certificate, credentials.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(credentials.CACertificate) {
+		return nil, fmt.Errorf("failed to parse TLS CA certificate")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      caPool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrHostKeyMismatch is returned when a server presents a different host key than the pinned one
+var ErrHostKeyMismatch = errors.New("host key mismatch")
+
+// errHostKeyScanned aborts the handshake once the host key was read by ScanHostKey
+var errHostKeyScanned = errors.New("host key scanned")
+
+// HostKeyMismatchError describes the pinned and the presented host key of a failed verification
+type HostKeyMismatchError struct {
+	Host     string // Address of the server
+	Expected string // Fingerprint of the pinned host key
+	Actual   string // Fingerprint of the host key presented by the server
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key mismatch for %s: expected %s, got %s", e.Host, e.Expected, e.Actual)
+}
+
+// Is makes errors.Is(err, ErrHostKeyMismatch) match
+func (e *HostKeyMismatchError) Is(target error) bool {
+	return target == ErrHostKeyMismatch
+}
+
+// HostKeyStore loads and pins the known host key of a pooled connection
+type HostKeyStore interface {
+	// GetHostKey returns the pinned host key in authorized_keys format, or "" when none is known yet
+	GetHostKey(connectionID string) (string, error)
+	// SaveHostKey pins the host key seen on the first successful connection
+	SaveHostKey(connectionID, hostKey string) error
+}
+
+// memoryHostKeyStore pins host keys in memory, it is used when no store is configured
+type memoryHostKeyStore struct {
+	hostKeys map[string]string
+	mutex    sync.Mutex
+}
+
+func newMemoryHostKeyStore() *memoryHostKeyStore {
+	return &memoryHostKeyStore{hostKeys: make(map[string]string)}
+}
+
+func (s *memoryHostKeyStore) GetHostKey(connectionID string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.hostKeys[connectionID], nil
+}
+
+func (s *memoryHostKeyStore) SaveHostKey(connectionID, hostKey string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.hostKeys[connectionID]; !exists {
+		s.hostKeys[connectionID] = hostKey
+	}
+	return nil
+}
+
+// SetHostKeyStore sets the store used to verify and pin the host keys of pooled connections
+func (p *ConnectionPool) SetHostKeyStore(store HostKeyStore) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.hostKeyStore = store
+}
+
+// hostKeyVerifier checks the host key presented during the handshake against the pinned key.
+// Without a pinned key every host key is accepted (trust on first use) and remembered in presented.
+type hostKeyVerifier struct {
+	known     string
+	presented string
+}
+
+func (v *hostKeyVerifier) callback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	v.presented = MarshalHostKey(key)
+	if v.known == "" {
+		return nil
+	}
+
+	knownKey, err := ParseHostKey(v.known)
+	if err != nil {
+		return fmt.Errorf("invalid pinned host key: %w", err)
+	}
+	if string(knownKey.Marshal()) != string(key.Marshal()) {
+		return &HostKeyMismatchError{
+			Host:     hostname,
+			Expected: ssh.FingerprintSHA256(knownKey),
+			Actual:   ssh.FingerprintSHA256(key),
+		}
+	}
+
+	return nil
+}
+
+// newHostKeyVerifier loads the pinned host key of the connection from the store
+func (p *ConnectionPool) newHostKeyVerifier(connectionID string) (*hostKeyVerifier, error) {
+	known, err := p.hostKeyStore.GetHostKey(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned host key: %w", err)
+	}
+	return &hostKeyVerifier{known: known}, nil
+}
+
+// pinHostKey stores the presented host key after the first successful connection
+func (p *ConnectionPool) pinHostKey(connectionID string, verifier *hostKeyVerifier) error {
+	if verifier.known != "" || verifier.presented == "" {
+		return nil
+	}
+	if err := p.hostKeyStore.SaveHostKey(connectionID, verifier.presented); err != nil {
+		return fmt.Errorf("failed to pin host key: %w", err)
+	}
+	return nil
+}
+
+// ScanHostKey reads the host key a server presents without authenticating.
+// It is used to show the new key to the user before a rotated key is accepted.
+// Servers behind a bastion are scanned through the jump host, jumpHost is nil for direct connections.
+func (p *ConnectionPool) ScanHostKey(host string, jumpHost *JumpHost) (string, error) {
+	// Normalize host format - if it doesn't have ssh:// scheme, add it
+	if !strings.HasPrefix(host, "ssh://") {
+		host = "ssh://" + host
+	}
+
+	parsedURL, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid SSH URL format: %w", err)
+	}
+
+	verifier := &hostKeyVerifier{}
+	sshConfig := &ssh.ClientConfig{
+		User: parsedURL.User.Username(),
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			verifier.callback(hostname, remote, key)
+			return errHostKeyScanned
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	_, err = p.dialSSHClient(parsedURL.Host, sshConfig, jumpHost)
+	if verifier.presented == "" {
+		return "", fmt.Errorf("failed to read host key: %w", err)
+	}
+
+	return verifier.presented, nil
+}
+
+// MarshalHostKey formats a host key in authorized_keys format
+func MarshalHostKey(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+// ParseHostKey parses a host key in authorized_keys or known_hosts line format
+func ParseHostKey(hostKey string) (ssh.PublicKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err == nil {
+		return key, nil
+	}
+
+	// Accept a known_hosts line as printed by ssh-keyscan
+	if _, _, key, _, _, knownHostsErr := ssh.ParseKnownHosts([]byte(hostKey)); knownHostsErr == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("failed to parse host key: %w", err)
+}
+
+// HostKeyFingerprint returns the SHA256 fingerprint of a host key in authorized_keys format
+func HostKeyFingerprint(hostKey string) (string, error) {
+	key, err := ParseHostKey(hostKey)
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(key), nil
+}
@@ -0,0 +1,173 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// JumpHost is a bastion server that connections are tunnelled through
+type JumpHost struct {
+	ConnectionID string      // Pool ID of the bastion connection, shared by all targets behind it
+	Host         string      // SSH host in user@ip:port format
+	Credentials  Credentials // Credentials for the bastion, may reference another jump host
+}
+
+// jumpHostConnection is a pooled SSH client to a bastion
+type jumpHostConnection struct {
+	sshClient   *ssh.Client
+	jumpHostID  string // Connection ID of the bastion this bastion is reached through, if any
+	fingerprint string // Hash of the host and credentials of the bastion
+	lastUsed    time.Time
+	createdAt   time.Time
+}
+
+// jumpHostID returns the connection ID of the jump host of the credentials or "" for direct connections
+func (c Credentials) jumpHostID() string {
+	if c.JumpHost == nil {
+		return ""
+	}
+	return c.JumpHost.ConnectionID
+}
+
+// dialSSHClient opens an SSH client to addr, directly or through the jump host
+func (p *ConnectionPool) dialSSHClient(addr string, sshConfig *ssh.ClientConfig, jumpHost *JumpHost) (*ssh.Client, error) {
+	if jumpHost == nil {
+		return ssh.Dial("tcp", addr, sshConfig)
+	}
+
+	bastion, err := p.getJumpHostClient(jumpHost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to jump host %s: %w", jumpHost.Host, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sshConfig.Timeout)
+	defer cancel()
+
+	// Open a direct-tcpip channel from the bastion to the target
+	conn, err := bastion.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s through jump host %s: %w", addr, jumpHost.Host, err)
+	}
+
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+// getJumpHostClient returns the pooled SSH client of a bastion, dialing it when there is no live one
+func (p *ConnectionPool) getJumpHostClient(jumpHost *JumpHost) (*ssh.Client, error) {
+	fingerprint := jumpHost.Credentials.fingerprint(jumpHost.Host)
+
+	p.jumpMutex.Lock()
+	if jumpConn, exists := p.jumpHosts[jumpHost.ConnectionID]; exists {
+		// A bastion with changed settings is dialed again
+		if jumpConn.fingerprint == fingerprint && isSSHClientAlive(jumpConn.sshClient) {
+			jumpConn.lastUsed = time.Now()
+			p.jumpMutex.Unlock()
+			return jumpConn.sshClient, nil
+		}
+		delete(p.jumpHosts, jumpHost.ConnectionID)
+		jumpConn.sshClient.Close()
+	}
+	closed := p.jumpHostsClosed
+	p.jumpMutex.Unlock()
+
+	if closed {
+		return nil, fmt.Errorf("connection pool is closed")
+	}
+
+	// Dial without holding the lock, a bastion can itself be behind another jump host
+	sshClient, err := p.dialSSH(jumpHost.ConnectionID, jumpHost.Host, jumpHost.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	p.jumpMutex.Lock()
+	defer p.jumpMutex.Unlock()
+
+	// Another target may have dialed the same bastion in the meantime
+	if jumpConn, exists := p.jumpHosts[jumpHost.ConnectionID]; exists {
+		if jumpConn.fingerprint == fingerprint {
+			sshClient.Close()
+			jumpConn.lastUsed = time.Now()
+			return jumpConn.sshClient, nil
+		}
+		jumpConn.sshClient.Close()
+	}
+
+	now := time.Now()
+	p.jumpHosts[jumpHost.ConnectionID] = &jumpHostConnection{
+		sshClient:   sshClient,
+		jumpHostID:  jumpHost.Credentials.jumpHostID(),
+		fingerprint: fingerprint,
+		lastUsed:    now,
+		createdAt:   now,
+	}
+
+	return sshClient, nil
+}
+
+// removeJumpHost closes the pooled connection to a bastion, it returns false if there was none
+func (p *ConnectionPool) removeJumpHost(connectionID string) bool {
+	p.jumpMutex.Lock()
+	defer p.jumpMutex.Unlock()
+
+	jumpConn, exists := p.jumpHosts[connectionID]
+	if !exists {
+		return false
+	}
+	delete(p.jumpHosts, connectionID)
+	jumpConn.sshClient.Close()
+	return true
+}
+
+// cleanupJumpHosts closes idle bastion connections that no pooled connection is tunnelled through.
+// The caller must hold p.mutex.
+func (p *ConnectionPool) cleanupJumpHosts() {
+	p.jumpMutex.Lock()
+	defer p.jumpMutex.Unlock()
+
+	inUse := map[string]bool{}
+	for _, connInfo := range p.connections {
+		inUse[connInfo.jumpHostID] = true
+	}
+	for _, jumpConn := range p.jumpHosts {
+		inUse[jumpConn.jumpHostID] = true
+	}
+
+	now := time.Now()
+	for connectionID, jumpConn := range p.jumpHosts {
+		if inUse[connectionID] {
+			continue
+		}
+		if p.maxIdle > 0 && now.Sub(jumpConn.lastUsed) > p.maxIdle {
+			delete(p.jumpHosts, connectionID)
+			jumpConn.sshClient.Close()
+		}
+	}
+}
+
+// closeJumpHosts closes all bastion connections when the pool is closed
+func (p *ConnectionPool) closeJumpHosts() {
+	p.jumpMutex.Lock()
+	defer p.jumpMutex.Unlock()
+
+	p.jumpHostsClosed = true
+	for connectionID, jumpConn := range p.jumpHosts {
+		delete(p.jumpHosts, connectionID)
+		jumpConn.sshClient.Close()
+	}
+}
+
+// isSSHClientAlive sends a keepalive request to check that the SSH connection is still open
+func isSSHClientAlive(sshClient *ssh.Client) bool {
+	_, _, err := sshClient.SendRequest("keepalive@openssh.com", true, nil)
+	return err == nil
+}
@@ -42,13 +42,13 @@ func (sr *SSHCommandResult) GetExitCode() int {
 }
 
 // ExecuteSSHCommand executes a command via SSH with real-time streaming output using existing connection infrastructure
-func (p *ConnectionPool) ExecuteSSHCommand(ctx context.Context, connectionID, host string, privateKeyContent []byte, command string, timeout time.Duration) (*SSHCommandResult, error) {
+func (p *ConnectionPool) ExecuteSSHCommand(ctx context.Context, connectionID, host string, credentials Credentials, command string, timeout time.Duration) (*SSHCommandResult, error) {
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
 	// Try to reuse existing SSH connection from Docker connection first
-	sshClient, err := p.getOrCreateSSHConnection(connectionID, host, privateKeyContent)
+	sshClient, err := p.getOrCreateSSHConnection(connectionID, host, credentials)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get SSH connection: %w", err)
 	}
@@ -191,13 +191,13 @@ func (p *ConnectionPool) ExecuteSSHCommand(ctx context.Context, connectionID, ho
 }
 
 // ExecuteInteractiveSSHCommand executes a command that may require interactive input
-func (p *ConnectionPool) ExecuteInteractiveSSHCommand(ctx context.Context, connectionID, host string, privateKeyContent []byte, command string, input string, timeout time.Duration) (*SSHCommandResult, error) {
+func (p *ConnectionPool) ExecuteInteractiveSSHCommand(ctx context.Context, connectionID, host string, credentials Credentials, command string, input string, timeout time.Duration) (*SSHCommandResult, error) {
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
 	// Try to reuse existing SSH connection from Docker connection first
-	sshClient, err := p.getOrCreateSSHConnection(connectionID, host, privateKeyContent)
+	sshClient, err := p.getOrCreateSSHConnection(connectionID, host, credentials)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get SSH connection: %w", err)
 	}
@@ -360,7 +360,7 @@ func (p *ConnectionPool) ExecuteInteractiveSSHCommand(ctx context.Context, conne
 
 // getOrCreateSSHConnection tries to reuse an existing SSH connection from a Docker connection
 // or creates a new SSH connection if needed. This optimizes resource usage.
-func (p *ConnectionPool) getOrCreateSSHConnection(connectionID, host string, privateKeyContent []byte) (*ssh.Client, error) {
+func (p *ConnectionPool) getOrCreateSSHConnection(connectionID, host string, credentials Credentials) (*ssh.Client, error) {
 	p.mutex.RLock()
 
 	// Check if we already have a connection with this ID
@@ -386,5 +386,5 @@ func (p *ConnectionPool) getOrCreateSSHConnection(connectionID, host string, pri
 	p.mutex.RUnlock()
 
 	// No reusable connection found, create a new SSH connection
-	return p.GetSSHConnection(connectionID, host, privateKeyContent)
+	return p.GetSSHConnection(connectionID, host, credentials)
 }
@@ -0,0 +1,77 @@
+package connection
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PTYSession is an interactive login shell running in a PTY over SSH
+type PTYSession struct {
+	Stdin   io.WriteCloser // Input of the shell
+	Stdout  io.Reader      // Combined stdout and stderr of the shell
+	session *ssh.Session
+}
+
+// OpenPTYSession starts a login shell with a PTY of the given size on the pooled SSH client.
+// The SSH client stays in the pool, only the session is closed by PTYSession.Close.
+func (p *ConnectionPool) OpenPTYSession(connectionID, host string, credentials Credentials, term string, cols, rows int) (*PTYSession, error) {
+	sshClient, err := p.getOrCreateSSHConnection(connectionID, host, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSH connection: %w", err)
+	}
+
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty(term, rows, cols, modes); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to request PTY: %w", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	// The PTY merges stderr into stdout on the server, but keep both on the same reader anyway
+	stdoutReader, stdoutWriter := io.Pipe()
+	session.Stdout = stdoutWriter
+	session.Stderr = stdoutWriter
+
+	if err := session.Shell(); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+
+	// Close the output once the shell exits so readers get EOF
+	go func() {
+		session.Wait()
+		stdoutWriter.Close()
+	}()
+
+	return &PTYSession{
+		Stdin:   stdin,
+		Stdout:  stdoutReader,
+		session: session,
+	}, nil
+}
+
+// Resize changes the window size of the PTY
+func (s *PTYSession) Resize(cols, rows int) error {
+	return s.session.WindowChange(rows, cols)
+}
+
+// Close terminates the shell session
+func (s *PTYSession) Close() error {
+	return s.session.Close()
+}
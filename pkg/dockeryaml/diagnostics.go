@@ -0,0 +1,225 @@
+package dockeryaml
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity tells whether a diagnostic prevents a deployment
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // The compose files can not be deployed
+	SeverityWarning Severity = "warning" // The compose files can be deployed but likely not as intended
+)
+
+// Diagnostic codes of problems found while loading compose files
+const (
+	DiagnosticYAMLSyntax    = "yaml_syntax"   // A file is not valid YAML
+	DiagnosticSchema        = "schema"        // A file does not match the compose specification
+	DiagnosticInterpolation = "interpolation" // A variable could not be interpolated
+	DiagnosticCompose       = "compose"       // The files could not be loaded into a project
+	DiagnosticValidation    = "validation"    // The project can not be deployed by Starker
+)
+
+// Diagnostic is a problem in compose files, located by file, line and column when possible
+type Diagnostic struct {
+	Severity Severity `json:"severity" example:"warning"`                           // error or warning
+	Code     string   `json:"code" example:"unpinned_image"`                        // Kind of problem
+	Message  string   `json:"message" example:"image nginx is not pinned to a tag"` // Human readable description
+	File     string   `json:"file,omitempty" example:"docker-compose.yml"`          // Compose file the problem is in
+	Line     int      `json:"line,omitempty" example:"3"`                           // Line in the file, starting at 1
+	Column   int      `json:"column,omitempty" example:"5"`                         // Column in the line, starting at 1
+	Path     string   `json:"path,omitempty" example:"services.web.image"`          // Dotted path of the attribute
+}
+
+var (
+	// yamlErrorPattern matches the errors of the YAML parser, like "yaml: line 3: did not find expected key"
+	yamlErrorPattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+	// schemaErrorPattern matches schema errors of compose-go, like "validating docker-compose.yml: services.web additional properties 'foo' not allowed"
+	schemaErrorPattern = regexp.MustCompile(`validating (\S+): (\S+) (.*)$`)
+	// additionalPropertyPattern finds the attribute of a schema error about an unknown attribute
+	additionalPropertyPattern = regexp.MustCompile(`^additional properties? '([^']+)' not allowed`)
+	// interpolationErrorPattern matches interpolation errors of compose-go, like "error while interpolating services.web.image: required variable FOO is missing a value"
+	interpolationErrorPattern = regexp.MustCompile(`error while interpolating (\S+): (.*)$`)
+	// serviceErrorPattern finds the service an error of compose-go is about
+	serviceErrorPattern = regexp.MustCompile(`service "([^"]+)"`)
+)
+
+// Diagnose loads compose files like ParseComposeFiles and reports every problem found with its location.
+// The project is nil when the files could not be loaded.
+func Diagnose(files []ComposeFile, envFiles []EnvFile, profiles []string, projectName string) (*types.Project, []Diagnostic) {
+	diagnostics := []Diagnostic{}
+	if err := ValidateFileNames(files, envFiles); err != nil {
+		return nil, append(diagnostics, Diagnostic{Severity: SeverityError, Code: DiagnosticCompose, Message: err.Error()})
+	}
+
+	// The YAML parser of compose-go does not tell which file a syntax error is in, so every file is parsed here first
+	locator := newLocator()
+	for _, file := range files {
+		var document yaml.Node
+		if err := yaml.Unmarshal([]byte(file.Content), &document); err != nil {
+			diagnostics = append(diagnostics, yamlDiagnostic(file.Filename, err))
+			continue
+		}
+		locator.add(file.Filename, &document)
+	}
+	if len(diagnostics) > 0 {
+		return nil, diagnostics
+	}
+
+	project, err := ParseComposeFiles(files, envFiles, profiles, projectName)
+	var referenceErr *FileReferenceError
+	if errors.As(err, &referenceErr) {
+		diagnostic := Diagnostic{Severity: SeverityError, Code: DiagnosticCompose, Message: referenceErr.Message, File: referenceErr.File}
+		return nil, append(diagnostics, locator.locate(diagnostic, referenceErr.Path))
+	}
+	if err != nil {
+		return nil, append(diagnostics, locator.loadDiagnostic(err))
+	}
+
+	if err := Validate(project); err != nil {
+		diagnostic := Diagnostic{Severity: SeverityError, Code: DiagnosticValidation, Message: err.Error()}
+		if match := serviceErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+			diagnostic = locator.locate(diagnostic, "services."+match[1])
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return project, diagnostics
+}
+
+// Locate sets the file, line and column of diagnostics with a path but no position
+func Locate(files []ComposeFile, diagnostics []Diagnostic) []Diagnostic {
+	locator := newLocator()
+	for _, file := range files {
+		var document yaml.Node
+		if err := yaml.Unmarshal([]byte(file.Content), &document); err == nil {
+			locator.add(file.Filename, &document)
+		}
+	}
+
+	for i, diagnostic := range diagnostics {
+		if diagnostic.Path != "" && diagnostic.Line == 0 {
+			diagnostics[i] = locator.locate(diagnostic, diagnostic.Path)
+		}
+	}
+	return diagnostics
+}
+
+// yamlDiagnostic converts an error of the YAML parser
+func yamlDiagnostic(filename string, err error) Diagnostic {
+	diagnostic := Diagnostic{Severity: SeverityError, Code: DiagnosticYAMLSyntax, Message: err.Error(), File: filename}
+	if match := yamlErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+		diagnostic.Line, _ = strconv.Atoi(match[1])
+		diagnostic.Message = match[2]
+	}
+	return diagnostic
+}
+
+// locator finds the position of dotted paths in the compose files
+type locator struct {
+	filenames []string // In load order
+	documents map[string]*yaml.Node
+}
+
+func newLocator() *locator {
+	return &locator{documents: make(map[string]*yaml.Node)}
+}
+
+func (l *locator) add(filename string, document *yaml.Node) {
+	l.filenames = append(l.filenames, filename)
+	l.documents[filename] = document
+}
+
+// loadDiagnostic converts an error of the compose-go loader
+func (l *locator) loadDiagnostic(err error) Diagnostic {
+	message := strings.TrimPrefix(err.Error(), "failed to parse compose file: ")
+	diagnostic := Diagnostic{Severity: SeverityError, Code: DiagnosticCompose, Message: message}
+
+	if match := schemaErrorPattern.FindStringSubmatch(message); match != nil {
+		diagnostic.Code = DiagnosticSchema
+		diagnostic.Message = match[2] + " " + match[3]
+		diagnostic.File = match[1]
+		path := match[2]
+		// Point at the attribute that is not allowed instead of its parent
+		if property := additionalPropertyPattern.FindStringSubmatch(match[3]); property != nil {
+			path += "." + property[1]
+		}
+		return l.locate(diagnostic, path)
+	}
+	if match := interpolationErrorPattern.FindStringSubmatch(message); match != nil {
+		diagnostic.Code = DiagnosticInterpolation
+		diagnostic.Message = match[2]
+		return l.locate(diagnostic, match[1])
+	}
+	if match := serviceErrorPattern.FindStringSubmatch(message); match != nil {
+		return l.locate(diagnostic, "services."+match[1])
+	}
+	return diagnostic
+}
+
+// locate sets the path of a diagnostic and the position of the deepest part of it found.
+// A file already set is searched alone, otherwise the last file defining the path wins like in a merge.
+func (l *locator) locate(diagnostic Diagnostic, path string) Diagnostic {
+	diagnostic.Path = path
+	segments := strings.Split(path, ".")
+
+	filenames := l.filenames
+	if diagnostic.File != "" {
+		filenames = []string{diagnostic.File}
+	}
+
+	bestDepth := 0
+	for i := len(filenames) - 1; i >= 0; i-- {
+		document, ok := l.documents[filenames[i]]
+		if !ok {
+			continue
+		}
+		node, depth := findPath(document, segments)
+		if node != nil && depth > bestDepth {
+			bestDepth = depth
+			diagnostic.File = filenames[i]
+			diagnostic.Line = node.Line
+			diagnostic.Column = node.Column
+		}
+	}
+
+	return diagnostic
+}
+
+// findPath walks the path in a YAML document and returns the deepest node found with the number of segments matched.
+// For mapping entries the key node is returned, it is where editors point at.
+func findPath(document *yaml.Node, segments []string) (*yaml.Node, int) {
+	if len(document.Content) == 0 {
+		return nil, 0
+	}
+	current := document.Content[0]
+	var found *yaml.Node
+	for depth, segment := range segments {
+		var next, position *yaml.Node
+		switch current.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(current.Content); i += 2 {
+				if current.Content[i].Value == segment {
+					position, next = current.Content[i], current.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(segment); err == nil && index >= 0 && index < len(current.Content) {
+				position, next = current.Content[index], current.Content[index]
+			}
+		}
+		if next == nil {
+			return found, depth
+		}
+		found, current = position, next
+	}
+	return found, len(segments)
+}
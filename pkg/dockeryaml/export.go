@@ -0,0 +1,133 @@
+package dockeryaml
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yorukot/starker/pkg/generator"
+)
+
+// ResolveNames rewrites a compose file with the project, container, network and volume names Starker deploys it with,
+// so docker compose creates the same resources. Networks are named by ExportNetworkName so they stay apart.
+// Everything else, including comments and variables, is kept as written.
+func ResolveNames(content string, project *types.Project, namingGenerator *generator.NamingGenerator) (string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		return "", fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return "", fmt.Errorf("compose file is not a mapping")
+	}
+	root := document.Content[0]
+
+	// The project name comes first, like docker compose writes it
+	if mappingValue(root, "name") != nil {
+		setScalar(root, "name", namingGenerator.ProjectName())
+	} else {
+		nameKey := scalarNode("name")
+		if len(root.Content) > 0 {
+			// Keep the comment at the top of the file above the name
+			nameKey.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+		}
+		root.Content = append([]*yaml.Node{nameKey, scalarNode(namingGenerator.ProjectName())}, root.Content...)
+	}
+
+	services := ensureMapping(root, "services")
+	for _, name := range sortedKeys(project.Services) {
+		setScalar(ensureMapping(services, name), "container_name", namingGenerator.ContainerName(name))
+	}
+
+	// Networks and volumes docker compose adds implicitly are written out to carry their names
+	if len(project.Networks) > 0 {
+		networks := ensureMapping(root, "networks")
+		for _, key := range sortedKeys(project.Networks) {
+			if project.Networks[key].External {
+				continue
+			}
+			setScalar(ensureMapping(networks, key), "name", ExportNetworkName(project, key, namingGenerator))
+		}
+	}
+
+	if len(project.Volumes) > 0 {
+		volumes := ensureMapping(root, "volumes")
+		for _, key := range sortedKeys(project.Volumes) {
+			if project.Volumes[key].External {
+				continue
+			}
+			setScalar(ensureMapping(volumes, key), "name", namingGenerator.VolumeName(project.Volumes[key].Name))
+		}
+	}
+
+	var output bytes.Buffer
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return "", fmt.Errorf("failed to write compose file: %w", err)
+	}
+
+	return output.String(), nil
+}
+
+// ExportNetworkName returns the name of a network in an exported compose file.
+// Networks docker compose names after the project are named by their key, the name the containers are attached by,
+// instead of the single default network NetworkName maps them to for deployments.
+func ExportNetworkName(project *types.Project, key string, namingGenerator *generator.NamingGenerator) string {
+	if project.Networks[key].Name == fmt.Sprintf("%s_%s", project.Name, key) {
+		return namingGenerator.ResolveNetworkName(key, "")
+	}
+	return namingGenerator.NetworkName(project.Networks[key].Name)
+}
+
+// mappingValue returns the value of a key in a mapping node, or nil when the key is missing
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// ensureMapping returns the mapping under a key, an empty or missing value is replaced by an empty mapping
+func ensureMapping(mapping *yaml.Node, key string) *yaml.Node {
+	value := mappingValue(mapping, key)
+	if value != nil && value.Kind == yaml.MappingNode {
+		// Keys are added to it, flow style like {} would put them on one line
+		value.Style &^= yaml.FlowStyle
+		return value
+	}
+
+	emptyMapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if value != nil {
+		*value = *emptyMapping
+		return value
+	}
+	mapping.Content = append(mapping.Content, scalarNode(key), emptyMapping)
+	return emptyMapping
+}
+
+// setScalar sets a key of a mapping node to a string
+func setScalar(mapping *yaml.Node, key, value string) {
+	if existing := mappingValue(mapping, key); existing != nil {
+		*existing = *scalarNode(value)
+		return
+	}
+	mapping.Content = append(mapping.Content, scalarNode(key), scalarNode(value))
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
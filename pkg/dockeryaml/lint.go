@@ -0,0 +1,107 @@
+package dockeryaml
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// Lint codes of compose files Starker can deploy but likely not as intended
+const (
+	LintUnpinnedImage        = "unpinned_image"               // The image has no tag or the latest tag
+	LintMissingRestart       = "missing_restart_policy"       // The containers are not started again after a crash or reboot
+	LintPrivileged           = "privileged"                   // The containers have full access to the server
+	LintHostNetwork          = "host_network"                 // The containers share the network of the server
+	LintBindMountOutsideData = "bind_mount_outside_data_path" // A directory of the server outside the service data path is mounted
+	LintPortInUse            = "port_in_use"                  // A published port is already used on the server
+)
+
+// Lint checks a loaded compose project against the practices of Starker deployments.
+// Bind mounts are expected inside dataPath, the directory of the service on the server.
+func Lint(project *types.Project, dataPath string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	names := make([]string, 0, len(project.Services))
+	for name := range project.Services {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		service := project.Services[name]
+		servicePath := "services." + name
+
+		if service.Image != "" && !isPinnedImage(service.Image) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     LintUnpinnedImage,
+				Message:  fmt.Sprintf("image %s of service %s is not pinned to a version, a redeploy can start a different version", service.Image, name),
+				Path:     servicePath + ".image",
+			})
+		}
+
+		if service.Restart == "" && (service.Deploy == nil || service.Deploy.RestartPolicy == nil) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     LintMissingRestart,
+				Message:  fmt.Sprintf("service %s has no restart policy, its container stays stopped after a crash or a reboot of the server", name),
+				Path:     servicePath,
+			})
+		}
+
+		if service.Privileged {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     LintPrivileged,
+				Message:  fmt.Sprintf("service %s runs privileged and has full access to the server", name),
+				Path:     servicePath + ".privileged",
+			})
+		}
+
+		if service.NetworkMode == "host" {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     LintHostNetwork,
+				Message:  fmt.Sprintf("service %s uses the host network, its ports are not isolated and port mappings are ignored", name),
+				Path:     servicePath + ".network_mode",
+			})
+		}
+
+		for i, volume := range service.Volumes {
+			if volume.Type != types.VolumeTypeBind || isInside(volume.Source, dataPath) {
+				continue
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     LintBindMountOutsideData,
+				Message:  fmt.Sprintf("service %s mounts %s from the server, it is outside the service data path %s and is not removed with the service", name, volume.Source, dataPath),
+				Path:     fmt.Sprintf("%s.volumes.%d", servicePath, i),
+			})
+		}
+	}
+
+	return diagnostics
+}
+
+// isPinnedImage reports whether an image reference has a digest or a tag other than latest
+func isPinnedImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+	// The tag follows the last colon after the last slash, a colon before it belongs to the registry port
+	lastSegment := image[strings.LastIndex(image, "/")+1:]
+	_, tag, ok := strings.Cut(lastSegment, ":")
+	return ok && tag != "latest"
+}
+
+// isInside reports whether a path is the directory or inside it
+func isInside(source, directory string) bool {
+	if !path.IsAbs(source) {
+		return false
+	}
+	source, directory = path.Clean(source), path.Clean(directory)
+	return source == directory || strings.HasPrefix(source, directory+"/")
+}
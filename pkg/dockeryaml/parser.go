@@ -3,37 +3,170 @@ package dockeryaml
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/compose-spec/compose-go/v2/loader"
 	"github.com/compose-spec/compose-go/v2/types"
 )
 
+// MainComposeFileName is the name of the main compose file, the files of a service are loaded on top of it
+const MainComposeFileName = "docker-compose.yml"
+
+// fileNameSegmentPattern matches a directory or file name of the relative paths files are stored under
+var fileNameSegmentPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ComposeFile is a compose file of a service
+type ComposeFile struct {
+	Filename string // Relative path other files reference it by in extends and include
+	Content  string // YAML content
+	Override bool   // Whether it is merged on top of the main file, otherwise it is only referenced by other files
+}
+
+// EnvFile is a named env file of a service, env_file entries of the compose files resolve against its name
+type EnvFile struct {
+	Name    string // Relative path env_file references it by, like .env or config/app.env
+	Content string // Dotenv content
+}
+
 // ParseComposeContent parses Docker Compose YAML content and returns a ComposeFile
 func ParseComposeContent(yamlContent string, projectName string) (*types.Project, error) {
-	if yamlContent == "" {
+	return ParseComposeFiles([]ComposeFile{{Filename: MainComposeFileName, Content: yamlContent, Override: true}}, nil, nil, projectName)
+}
+
+// ParseComposeFiles loads compose files the way docker compose loads them with -f for every override and --profile for every profile.
+// The first file is the main file. extends and include can reference every file by its filename, env_file every env file by its name.
+// Nothing outside these files can be referenced, the host filesystem is never read.
+func ParseComposeFiles(files []ComposeFile, envFiles []EnvFile, profiles []string, projectName string) (*types.Project, error) {
+	if len(files) == 0 || files[0].Content == "" {
 		return nil, fmt.Errorf("compose content cannot be empty")
 	}
+	if err := ValidateFileNames(files, envFiles); err != nil {
+		return nil, err
+	}
+	if err := checkFileReferences(files); err != nil {
+		return nil, err
+	}
 
-	configFiles := []types.ConfigFile{
-		{
-			Filename: "docker-compose.yml",
-			Content:  []byte(yamlContent),
-		},
+	// extends and include are read from disk, so the files are written to a directory only this load uses
+	workingDir, err := os.MkdirTemp("", "starker-compose-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compose directory: %w", err)
+	}
+	defer os.RemoveAll(workingDir)
+
+	for _, envFile := range envFiles {
+		if _, err := writeFile(workingDir, envFile.Name, envFile.Content); err != nil {
+			return nil, fmt.Errorf("failed to write env file %s: %w", envFile.Name, err)
+		}
+	}
+
+	var configFiles []types.ConfigFile
+	for i, file := range files {
+		path, err := writeFile(workingDir, file.Filename, file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write compose file %s: %w", file.Filename, err)
+		}
+
+		if i == 0 || file.Override {
+			configFiles = append(configFiles, types.ConfigFile{
+				Filename: path,
+				Content:  []byte(file.Content),
+			})
+		}
 	}
 
 	project, err := loader.LoadWithContext(context.Background(), types.ConfigDetails{
 		ConfigFiles: configFiles,
-		WorkingDir:  ".",
+		WorkingDir:  workingDir,
 	}, func(options *loader.Options) {
 		options.SetProjectName(projectName, true)
+		options.Profiles = profiles
+		options.ResourceLoaders = []loader.ResourceLoader{sandboxLoader{workingDir: workingDir}}
+		// The env files are read below, once their paths are checked
+		options.SkipResolveEnvironment = true
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+		return nil, fmt.Errorf("failed to parse compose file: %s", strings.ReplaceAll(err.Error(), workingDir+string(filepath.Separator), ""))
+	}
+
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+		return path
+	}
+	if err := checkEnvFiles(project.Services, envFiles, relative); err != nil {
+		return nil, err
+	}
+	project, err = project.WithServicesEnvironmentResolved(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %s", strings.ReplaceAll(err.Error(), workingDir+string(filepath.Separator), ""))
+	}
+
+	// Relative paths were resolved against the temporary directory, they stay relative like in a single compose file
+	project.WorkingDir = "."
+	project.ComposeFiles = nil
+	for name, service := range project.Services {
+		for i, volume := range service.Volumes {
+			if volume.Type == types.VolumeTypeBind {
+				service.Volumes[i].Source = relative(volume.Source)
+			}
+		}
+		// The env files were read into the environment of the service already
+		for i, envFile := range service.EnvFiles {
+			service.EnvFiles[i].Path = relative(envFile.Path)
+		}
+		project.Services[name] = service
 	}
 
 	return project, nil
 }
 
+// writeFile writes a file under the directory at its relative path and returns the full path
+func writeFile(directory, name, content string) (string, error) {
+	path := filepath.Join(directory, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(content), 0o600)
+}
+
+// ValidateFileNames checks that the compose filenames and env file names are relative paths inside the service and unique
+func ValidateFileNames(files []ComposeFile, envFiles []EnvFile) error {
+	names := make([]string, 0, len(files)+len(envFiles))
+	for _, file := range files {
+		names = append(names, file.Filename)
+	}
+	for _, envFile := range envFiles {
+		names = append(names, envFile.Name)
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !isRelativeFileName(name) {
+			return fmt.Errorf("invalid filename %q, use a relative path like overrides/prod.yml", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("filename %q is used twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// isRelativeFileName reports whether a name is a relative path that stays inside its directory
+func isRelativeFileName(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "." || segment == ".." || !fileNameSegmentPattern.MatchString(segment) {
+			return false
+		}
+	}
+	return true
+}
+
 func Validate(project *types.Project) error {
 	if project == nil {
 		return fmt.Errorf("project is nil")
@@ -0,0 +1,164 @@
+package dockeryaml
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"gopkg.in/yaml.v3"
+)
+
+// fileReferenceMessage tells where the files compose files reference have to be
+const fileReferenceMessage = "must be a relative path to a file of the service"
+
+// FileReferenceError is a path in a compose file that points outside the files of the service
+type FileReferenceError struct {
+	File    string // Compose file with the reference, empty when it is only known after the files were merged
+	Path    string // Dotted path of the reference, like include.0.path
+	Message string // What is wrong with the reference
+}
+
+func (e *FileReferenceError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("%s %s", e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s %s", e.File, e.Path, e.Message)
+}
+
+// checkFileReferences checks the paths compose-go reads while loading before anything is read.
+// They have to be relative paths without .. so only the files written for the load can be reached.
+// include paths and extends files are checked again after interpolation by sandboxLoader, env files by checkEnvFiles,
+// the other paths are read without another check, so they can not use variables.
+func checkFileReferences(files []ComposeFile) error {
+	for _, file := range files {
+		var model map[string]any
+		if err := yaml.Unmarshal([]byte(file.Content), &model); err != nil {
+			// The loader reports the syntax error
+			continue
+		}
+		checker := referenceChecker{file: file.Filename}
+
+		includes, _ := model["include"].([]any)
+		for i, include := range includes {
+			path := fmt.Sprintf("include.%d", i)
+			switch include := include.(type) {
+			case string:
+				if err := checker.check(path, include, true); err != nil {
+					return err
+				}
+			case map[string]any:
+				if err := checker.check(path+".path", include["path"], true); err != nil {
+					return err
+				}
+				if err := checker.check(path+".project_directory", include["project_directory"], false); err != nil {
+					return err
+				}
+				if err := checker.check(path+".env_file", include["env_file"], false); err != nil {
+					return err
+				}
+			}
+		}
+
+		services, _ := model["services"].(map[string]any)
+		names := make([]string, 0, len(services))
+		for name := range services {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			service, _ := services[name].(map[string]any)
+			path := "services." + name
+			if extends, ok := service["extends"].(map[string]any); ok {
+				if err := checker.check(path+".extends.file", extends["file"], true); err != nil {
+					return err
+				}
+			}
+			if err := checker.check(path+".env_file", service["env_file"], true); err != nil {
+				return err
+			}
+			if err := checker.check(path+".label_file", service["label_file"], false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkEnvFiles checks that every env_file of the services, resolved after interpolation, is one of the env files by its name.
+// The env files are only read after this check.
+func checkEnvFiles(services types.Services, envFiles []EnvFile, relative func(string) string) error {
+	names := make(map[string]bool, len(envFiles))
+	for _, envFile := range envFiles {
+		names[envFile.Name] = true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(services)) {
+		for i, envFile := range services[name].EnvFiles {
+			if path := relative(envFile.Path); !names[path] {
+				return &FileReferenceError{
+					Path:    fmt.Sprintf("services.%s.env_file.%d", name, i),
+					Message: fmt.Sprintf("%q is not an env file of the service", path),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// referenceChecker checks the file references of one compose file
+type referenceChecker struct {
+	file string
+}
+
+// check checks a path, a list of paths or the long syntax with a path attribute
+func (c referenceChecker) check(path string, value any, variables bool) error {
+	switch value := value.(type) {
+	case string:
+		if !isSandboxedPath(value) || (!variables && strings.Contains(value, "$")) {
+			return &FileReferenceError{File: c.file, Path: path, Message: fmt.Sprintf("%q %s", value, fileReferenceMessage)}
+		}
+	case []any:
+		for i, item := range value {
+			if err := c.check(fmt.Sprintf("%s.%d", path, i), item, variables); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		return c.check(path+".path", value["path"], variables)
+	}
+	return nil
+}
+
+// isSandboxedPath reports whether a slash separated path stays inside the directory it is resolved against
+func isSandboxedPath(name string) bool {
+	if name == "" || strings.HasPrefix(name, "~") || filepath.IsAbs(name) {
+		return false
+	}
+	return !slices.Contains(strings.Split(name, "/"), "..")
+}
+
+// sandboxLoader refuses include paths and extends files that leave the directory the files of a load are written to.
+// compose-go asks it before its own loader of local files, which would read any file of the host.
+type sandboxLoader struct {
+	workingDir string
+}
+
+func (l sandboxLoader) Accept(path string) bool {
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(l.workingDir, path)
+		return err != nil || !isSandboxedPath(filepath.ToSlash(rel))
+	}
+	return !isSandboxedPath(path)
+}
+
+func (l sandboxLoader) Load(_ context.Context, path string) (string, error) {
+	return "", fmt.Errorf("%q %s", path, fileReferenceMessage)
+}
+
+func (l sandboxLoader) Dir(path string) string {
+	return filepath.Dir(path)
+}
@@ -0,0 +1,24 @@
+package dotenv
+
+import (
+	"regexp"
+	"strings"
+)
+
+// plainValue matches values that need no quoting
+var plainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// Format returns a KEY=value line that docker compose reads back as the same value.
+// Values are single quoted when possible, so they are never interpolated. A backslash could escape
+// the closing single quote, so values with one are double quoted with escapes instead.
+func Format(key, value string) string {
+	switch {
+	case plainValue.MatchString(value):
+		return key + "=" + value
+	case !strings.ContainsAny(value, "'\\\n\r"):
+		return key + "='" + value + "'"
+	default:
+		replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+		return key + `="` + replacer.Replace(value) + `"`
+	}
+}
@@ -0,0 +1,166 @@
+package dotenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyPattern matches the variable names docker compose accepts in env files
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// doubleQuotedEscapes are the escape sequences docker compose expands in double quoted values
+var doubleQuotedEscapes = map[byte]string{
+	'a': "\a", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v",
+	'$': "$", '"': `"`, '\\': `\`,
+}
+
+// Entry is a variable of a dotenv file with the comment written above it
+type Entry struct {
+	Key     string
+	Value   string
+	Comment string // Comment lines above the variable without the leading #, joined by newlines
+}
+
+// ParseError is a line of a dotenv file that can not be read
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Parse reads a dotenv file with the quoting rules of docker compose. Multiline quoted values are supported.
+// Values are kept as written, variables in them are not expanded. Comment lines directly above a variable
+// become its comment, a blank line ends a comment. A variable defined twice keeps its first position and last value.
+func Parse(content string) ([]Entry, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var entries []Entry
+	index := make(map[string]int)
+	var comment []string
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		trimmed := strings.TrimSpace(lines[i])
+
+		switch {
+		case trimmed == "":
+			comment = nil
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			text := strings.TrimPrefix(trimmed, "#")
+			comment = append(comment, strings.TrimPrefix(text, " "))
+			continue
+		}
+
+		trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "export "))
+		key, rest, ok := strings.Cut(trimmed, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, &ParseError{Line: lineNumber, Message: "expected KEY=value"}
+		}
+		if !keyPattern.MatchString(key) {
+			return nil, &ParseError{Line: lineNumber, Message: fmt.Sprintf("invalid variable name %q", key)}
+		}
+
+		rest = strings.TrimLeft(rest, " \t")
+		var value string
+		if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+			// Quoted values continue on the next lines until the closing quote
+			quote := rest[0]
+			body := rest[1:]
+			end := closingQuote(body, quote)
+			for end < 0 {
+				if i+1 >= len(lines) {
+					return nil, &ParseError{Line: lineNumber, Message: "unterminated quoted value"}
+				}
+				i++
+				body += "\n" + lines[i]
+				end = closingQuote(body, quote)
+			}
+
+			if remainder := strings.TrimSpace(body[end+1:]); remainder != "" && !strings.HasPrefix(remainder, "#") {
+				return nil, &ParseError{Line: i + 1, Message: "unexpected characters after the closing quote"}
+			}
+			value = unquote(body[:end], quote)
+		} else {
+			// An inline comment starts with # after whitespace
+			if at := strings.Index(rest, " #"); at >= 0 {
+				rest = rest[:at]
+			}
+			if at := strings.Index(rest, "\t#"); at >= 0 {
+				rest = rest[:at]
+			}
+			value = strings.TrimSpace(rest)
+		}
+
+		entry := Entry{Key: key, Value: value, Comment: strings.Join(comment, "\n")}
+		comment = nil
+		if existing, ok := index[key]; ok {
+			entries[existing] = entry
+			continue
+		}
+		index[key] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// closingQuote returns the index of the quote ending a quoted value, or -1 when it is not closed yet
+func closingQuote(body string, quote byte) int {
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote returns the value of a quoted string, double quoted values expand escape sequences
+func unquote(body string, quote byte) string {
+	var value strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' || i+1 >= len(body) {
+			value.WriteByte(body[i])
+			continue
+		}
+
+		next := body[i+1]
+		switch {
+		case quote == '"' && doubleQuotedEscapes[next] != "":
+			value.WriteString(doubleQuotedEscapes[next])
+			i++
+		case next == quote:
+			value.WriteByte(quote)
+			i++
+		default:
+			value.WriteByte('\\')
+		}
+	}
+	return value.String()
+}
+
+// Marshal writes entries as a dotenv file that Parse and docker compose read back as the same values.
+// Comments are written above their variables.
+func Marshal(entries []Entry) string {
+	var content strings.Builder
+	for i, entry := range entries {
+		if entry.Comment != "" {
+			// Variables with a comment are set apart by a blank line
+			if i > 0 {
+				content.WriteString("\n")
+			}
+			for _, line := range strings.Split(entry.Comment, "\n") {
+				content.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+			}
+		}
+		content.WriteString(Format(entry.Key, entry.Value) + "\n")
+	}
+	return content.String()
+}
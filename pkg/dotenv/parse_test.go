@@ -0,0 +1,49 @@
+package dotenv
+
+import (
+	"testing"
+
+	composedotenv "github.com/compose-spec/compose-go/v2/dotenv"
+)
+
+func TestMarshalParseRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Key: "PLAIN", Value: "postgres://db:5432/app"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "SPACES", Value: "hello world"},
+		{Key: "VARIABLE", Value: "${HOME} and $USER"},
+		{Key: "SINGLE_QUOTE", Value: "it's"},
+		{Key: "DOUBLE_QUOTE", Value: `say "hi"`},
+		{Key: "MULTILINE", Value: "first\nsecond\r\nthird"},
+		{Key: "WINDOWS_PATH", Value: `C:\path\`},
+		{Key: "BACKSLASH_QUOTE", Value: `a\'b`},
+		{Key: "ESCAPES", Value: `\n is not a newline \$`},
+		{Key: "HASH", Value: "value #not a comment", Comment: "Comment above\n\nwith a blank line"},
+	}
+
+	content := Marshal(entries)
+
+	parsed, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse(Marshal()) failed: %v\n%s", err, content)
+	}
+	if len(parsed) != len(entries) {
+		t.Fatalf("got %d entries, want %d\n%s", len(parsed), len(entries), content)
+	}
+	for i, entry := range entries {
+		if parsed[i] != entry {
+			t.Errorf("entry %d: got %+v, want %+v", i, parsed[i], entry)
+		}
+	}
+
+	// docker compose has to read the same values from the env file
+	values, err := composedotenv.UnmarshalWithLookup(content, func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatalf("docker compose can not read Marshal(): %v\n%s", err, content)
+	}
+	for _, entry := range entries {
+		if values[entry.Key] != entry.Value {
+			t.Errorf("docker compose reads %s as %q, want %q", entry.Key, values[entry.Key], entry.Value)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// MasterKeySize is the size of the AES-256 master and data keys
+const MasterKeySize = 32
+
+// ErrDecryptionFailed is returned when a ciphertext can not be decrypted, usually because of a wrong master key
+var ErrDecryptionFailed = errors.New("decryption failed")
+
+// DecodeMasterKey decodes a base64 encoded 32 byte master key
+func DecodeMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	if len(key) != MasterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", MasterKeySize, len(key))
+	}
+	return key, nil
+}
+
+// EnvelopeEncrypt encrypts the plaintext with a new random data key and encrypts the data key with the master key.
+// Both results are needed to decrypt, only the encrypted data key has to be updated when the master key rotates.
+func EnvelopeEncrypt(masterKey, plaintext []byte) (ciphertext, encryptedDataKey []byte, err error) {
+	dataKey, encryptedDataKey, err := GenerateDataKey(masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err = EncryptWithDataKey(dataKey, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, encryptedDataKey, nil
+}
+
+// EnvelopeDecrypt decrypts the data key with the master key and the ciphertext with the data key
+func EnvelopeDecrypt(masterKey, ciphertext, encryptedDataKey []byte) ([]byte, error) {
+	dataKey, err := DecryptDataKey(masterKey, encryptedDataKey)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptWithDataKey(dataKey, ciphertext)
+}
+
+// GenerateDataKey creates a random data key and returns it together with its encryption under the master key.
+// Use it to encrypt several values of the same row with one data key.
+func GenerateDataKey(masterKey []byte) (dataKey, encryptedDataKey []byte, err error) {
+	dataKey = make([]byte, MasterKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	encryptedDataKey, err = sealAESGCM(masterKey, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dataKey, encryptedDataKey, nil
+}
+
+// DecryptDataKey decrypts a data key created by GenerateDataKey
+func DecryptDataKey(masterKey, encryptedDataKey []byte) ([]byte, error) {
+	return openAESGCM(masterKey, encryptedDataKey)
+}
+
+// EncryptWithDataKey encrypts a value with a data key
+func EncryptWithDataKey(dataKey, plaintext []byte) ([]byte, error) {
+	return sealAESGCM(dataKey, plaintext)
+}
+
+// DecryptWithDataKey decrypts a value encrypted by EncryptWithDataKey
+func DecryptWithDataKey(dataKey, ciphertext []byte) ([]byte, error) {
+	return openAESGCM(dataKey, ciphertext)
+}
+
+// RewrapDataKey re-encrypts a data key from the old master key to the new master key
+func RewrapDataKey(oldMasterKey, newMasterKey, encryptedDataKey []byte) ([]byte, error) {
+	dataKey, err := openAESGCM(oldMasterKey, encryptedDataKey)
+	if err != nil {
+		return nil, err
+	}
+	return sealAESGCM(newMasterKey, dataKey)
+}
+
+// sealAESGCM encrypts with AES-GCM and prefixes the random nonce
+func sealAESGCM(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openAESGCM decrypts a ciphertext created by sealAESGCM
+func openAESGCM(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// newGCM creates an AES-GCM cipher for the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -2,7 +2,9 @@ package encrypt
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	mathRand "math/rand"
@@ -36,6 +38,36 @@ func GenerateSecureRefreshToken() (string, error) {
 	return ksuid.New().String() + "_" + base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(bytes), nil
 }
 
+// GenerateAgentToken generate the secret an agent authenticates its tunnel with
+func GenerateAgentToken() (string, error) {
+	bytes := make([]byte, 32) // 256-bit
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return "starker_agent_" + base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(bytes), nil
+}
+
+// HashAgentToken returns the hex encoded SHA-256 hash of an agent token, the form it is stored in
+func HashAgentToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateWebSocketTicket generate the single use ticket a browser opens a WebSocket with
+func GenerateWebSocketTicket() (string, error) {
+	bytes := make([]byte, 32) // 256-bit
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate ticket: %w", err)
+	}
+	return "starker_ws_" + base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(bytes), nil
+}
+
+// HashWebSocketTicket returns the hex encoded SHA-256 hash of a WebSocket ticket, the form it is stored in
+func HashWebSocketTicket(ticket string) string {
+	hash := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(hash[:])
+}
+
 // GenerateRandomUserDisplayName generate a random user display name
 func GenerateRandomUserDisplayName() string {
 	names := []string{
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/internal/service/privatekeysvc"
+	"github.com/yorukot/starker/pkg/connection"
+)
+
+// MaxJumpHostDepth limits how many bastions a server can be reached through
+const MaxJumpHostDepth = 3
+
+var (
+	// ErrJumpHostNotFound is returned when the jump host of a server does not exist in its team
+	ErrJumpHostNotFound = errors.New("jump host not found")
+	// ErrInvalidJumpHost is returned when the jump host chain loops, is too long or contains a server without SSH
+	ErrInvalidJumpHost = errors.New("invalid jump host")
+)
+
+// ServerJumpHost returns the jump host chain of a server, or nil when the server is reached directly
+func ServerJumpHost(ctx context.Context, db pgx.Tx, server models.Server) (*connection.JumpHost, error) {
+	return resolveJumpHost(ctx, db, server, map[string]bool{server.ID: true})
+}
+
+// resolveJumpHost builds the jump host of a server and the jump hosts of its bastion recursively
+func resolveJumpHost(ctx context.Context, db pgx.Tx, server models.Server, visited map[string]bool) (*connection.JumpHost, error) {
+	if server.JumpHostID == nil {
+		return nil, nil
+	}
+	if visited[*server.JumpHostID] {
+		return nil, fmt.Errorf("%w: server %s is reached through itself", ErrInvalidJumpHost, *server.JumpHostID)
+	}
+	if len(visited) > MaxJumpHostDepth {
+		return nil, fmt.Errorf("%w: servers can be reached through at most %d jump hosts", ErrInvalidJumpHost, MaxJumpHostDepth)
+	}
+	visited[*server.JumpHostID] = true
+
+	jumpServer, err := repository.GetServerByID(ctx, db, *server.JumpHostID, server.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jump host: %w", err)
+	}
+	if jumpServer == nil {
+		return nil, fmt.Errorf("%w: %s", ErrJumpHostNotFound, *server.JumpHostID)
+	}
+	if jumpServer.ConnectionType != models.ServerConnectionSSH {
+		return nil, fmt.Errorf("%w: jump host %s is not connected over SSH", ErrInvalidJumpHost, jumpServer.ID)
+	}
+
+	privateKey, err := repository.GetPrivateKeyByID(ctx, db, jumpServer.PrivateKeyID, jumpServer.TeamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get private key of jump host: %w", err)
+	}
+	if privateKey == nil {
+		return nil, fmt.Errorf("private key of jump host %s not found", jumpServer.ID)
+	}
+
+	credentials := privatekeysvc.Credentials(*privateKey)
+	if credentials.JumpHost, err = resolveJumpHost(ctx, db, *jumpServer, visited); err != nil {
+		return nil, err
+	}
+
+	return &connection.JumpHost{
+		ConnectionID: ServerConnectionID(jumpServer.TeamID, jumpServer.ID),
+		Host:         ServerHost(*jumpServer),
+		Credentials:  credentials,
+	}, nil
+}
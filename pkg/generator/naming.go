@@ -13,6 +13,13 @@ const (
 	MaxNameLength = 50
 )
 
+// Labels Starker puts on every resource it creates for a service
+const (
+	ServiceIDLabel = "starker.service.id"
+	TeamIDLabel    = "starker.team.id"
+	ServerIDLabel  = "starker.server.id"
+)
+
 type NamingGenerator struct {
 	serviceID string
 	teamID    string
@@ -80,14 +87,24 @@ func (ng *NamingGenerator) VolumeName(volumeName string) string {
 }
 
 func (ng *NamingGenerator) ConnectionID() string {
-	return fmt.Sprintf("%s-%s", ng.teamID, ng.serverID)
+	return ServerConnectionID(ng.teamID, ng.serverID)
+}
+
+// ServerConnectionID returns the connection pool ID of a server, shared by all services on it
+func ServerConnectionID(teamID, serverID string) string {
+	return fmt.Sprintf("%s-%s", teamID, serverID)
+}
+
+// ParseServerConnectionID splits a connection pool ID created by ServerConnectionID
+func ParseServerConnectionID(connectionID string) (teamID, serverID string, ok bool) {
+	return strings.Cut(connectionID, "-")
 }
 
 func (ng *NamingGenerator) GetLabels() map[string]string {
 	return map[string]string{
-		"starker.service.id": ng.serviceID,
-		"starker.team.id":    ng.teamID,
-		"starker.server.id":  ng.serverID,
+		ServiceIDLabel: ng.serviceID,
+		TeamIDLabel:    ng.teamID,
+		ServerIDLabel:  ng.serverID,
 	}
 }
 
@@ -148,14 +165,14 @@ func (fb *FilterBuilder) ServiceFilters(projectName, serviceName string) filters
 	args := filters.NewArgs()
 	args.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
 	args.Add("label", fmt.Sprintf("com.docker.compose.service=%s", serviceName))
-	args.Add("label", fmt.Sprintf("starker.service.id=%s", fb.generator.serviceID))
+	args.Add("label", fmt.Sprintf("%s=%s", ServiceIDLabel, fb.generator.serviceID))
 	return args
 }
 
 func (fb *FilterBuilder) ProjectFilters(projectName string) filters.Args {
 	args := filters.NewArgs()
 	args.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
-	args.Add("label", fmt.Sprintf("starker.service.id=%s", fb.generator.serviceID))
+	args.Add("label", fmt.Sprintf("%s=%s", ServiceIDLabel, fb.generator.serviceID))
 	return args
 }
 
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yorukot/starker/internal/config"
+	"github.com/yorukot/starker/internal/models"
+	"github.com/yorukot/starker/internal/repository"
+	"github.com/yorukot/starker/internal/service/privatekeysvc"
+	"github.com/yorukot/starker/pkg/connection"
+)
+
+// ErrPrivateKeyNotFound is returned when the private key of a server does not exist in its team
+var ErrPrivateKeyNotFound = errors.New("private key not found")
+
+// ServerHost returns the host the connection pool reaches the Docker daemon of a server with
+func ServerHost(server models.Server) string {
+	switch server.ConnectionType {
+	case models.ServerConnectionLocal:
+		return "unix://" + config.Env().LocalDockerSocket
+	case models.ServerConnectionTCP:
+		return "tcp://" + net.JoinHostPort(server.IP, server.Port)
+	case models.ServerConnectionAgent:
+		return connection.AgentHost(server.ID)
+	default:
+		return fmt.Sprintf("%s@%s:%s", server.User, server.IP, server.Port)
+	}
+}
+
+// ServerCredentials returns the credentials to connect to a server.
+// SSH servers get their key and the jump hosts in front of them, TCP servers their TLS client certificates,
+// local and agent servers need none.
+func ServerCredentials(ctx context.Context, db pgx.Tx, server models.Server) (connection.Credentials, error) {
+	if server.ConnectionType == models.ServerConnectionLocal || server.ConnectionType == models.ServerConnectionAgent {
+		return connection.Credentials{}, nil
+	}
+
+	privateKey, err := repository.GetPrivateKeyByID(ctx, db, server.PrivateKeyID, server.TeamID)
+	if err != nil {
+		return connection.Credentials{}, fmt.Errorf("failed to get private key: %w", err)
+	}
+	if privateKey == nil {
+		return connection.Credentials{}, ErrPrivateKeyNotFound
+	}
+
+	// The private key of a TCP server is the key of its TLS client certificate
+	if server.ConnectionType == models.ServerConnectionTCP {
+		tlsCredentials := &connection.TLSCredentials{PrivateKey: []byte(privateKey.PrivateKey)}
+		if server.TLSCACertificate != nil {
+			tlsCredentials.CACertificate = []byte(*server.TLSCACertificate)
+		}
+		if server.TLSCertificate != nil {
+			tlsCredentials.Certificate = []byte(*server.TLSCertificate)
+		}
+		return connection.Credentials{TLS: tlsCredentials}, nil
+	}
+
+	credentials := privatekeysvc.Credentials(*privateKey)
+	if credentials.JumpHost, err = ServerJumpHost(ctx, db, server); err != nil {
+		return connection.Credentials{}, err
+	}
+
+	return credentials, nil
+}
@@ -28,17 +28,17 @@ func PrepareDockerConfigForService(ctx context.Context, serviceID, teamID, proje
 		return nil, fmt.Errorf("failed to get server: %w", err)
 	}
 
-	privateKey, err := repository.GetPrivateKeyByID(ctx, db, server.PrivateKeyID, teamID)
+	credentials, err := ServerCredentials(ctx, db, *server)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get private key: %w", err)
+		return nil, fmt.Errorf("failed to resolve server credentials: %w", err)
 	}
 
 	generator := NewNamingGenerator(serviceID, teamID, server.ID)
 
-	host := fmt.Sprintf("ssh://%s@%s:%s", server.User, server.IP, server.Port)
+	host := ServerHost(*server)
 
 	connectionID := generator.ConnectionID()
-	dockerClient, err := dockerPool.GetDockerConnection(connectionID, host, []byte(privateKey.PrivateKey))
+	dockerClient, err := dockerPool.GetDockerConnection(connectionID, host, credentials)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Docker connection: %w", err)
 	}
@@ -0,0 +1,96 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// asciicastHeader is the first line of an asciicast v2 recording
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     uint              `json:"width"`
+	Height    uint              `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// AsciicastRecorder writes a terminal session in the asciicast v2 format.
+// See https://docs.asciinema.org/manual/asciicast/v2/
+type AsciicastRecorder struct {
+	w       io.Writer
+	start   time.Time
+	pending []byte // Trailing bytes of a UTF-8 character split between two reads
+	mutex   sync.Mutex
+}
+
+// NewAsciicastRecorder writes the asciicast header and returns a recorder for the events
+func NewAsciicastRecorder(w io.Writer, cols, rows uint, title, term string) (*AsciicastRecorder, error) {
+	start := time.Now()
+
+	header, err := json.Marshal(asciicastHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": term},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", header); err != nil {
+		return nil, err
+	}
+
+	return &AsciicastRecorder{w: w, start: start}, nil
+}
+
+// Output records a chunk of terminal output
+func (r *AsciicastRecorder) Output(data []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	buf := append(r.pending, data...)
+	cut := completeUTF8(buf)
+	r.pending = append([]byte(nil), buf[cut:]...)
+	if cut > 0 {
+		r.writeEvent("o", string(buf[:cut]))
+	}
+}
+
+// Resize records a change of the terminal size
+func (r *AsciicastRecorder) Resize(cols, rows uint) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.writeEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// completeUTF8 returns the length of the prefix of b that does not end in a partial UTF-8 character
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// writeEvent appends a single [time, code, data] event line, write errors are ignored
+// so a full disk never breaks the live session. The caller must hold the mutex.
+func (r *AsciicastRecorder) writeEvent(code, data string) {
+	elapsed := time.Since(r.start).Seconds()
+	event, err := json.Marshal([]any{elapsed, code, data})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(r.w, "%s\n", event)
+}
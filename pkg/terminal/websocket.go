@@ -0,0 +1,140 @@
+package terminal
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// MessageType is the type of a control message sent by the client
+type MessageType string
+
+const (
+	MessageTypeInput  MessageType = "input"
+	MessageTypeResize MessageType = "resize"
+)
+
+// Message is a JSON message sent by the client over the WebSocket.
+// Input messages carry keystrokes in Data, resize messages carry the new terminal size.
+type Message struct {
+	Type MessageType `json:"type"`
+	Data string      `json:"data,omitempty"`
+	Cols uint        `json:"cols,omitempty"`
+	Rows uint        `json:"rows,omitempty"`
+}
+
+// Session connects a TTY to a WebSocket
+type Session struct {
+	Stdin    io.Writer                   // Receives the input typed by the client
+	Stdout   io.Reader                   // Output of the TTY, sent to the client as binary frames
+	Resize   func(cols, rows uint) error // Called when the client resizes the terminal
+	OnOutput func(data []byte)           // Optional hook called with every chunk of output
+	OnInput  func(data []byte)           // Optional hook called with every chunk of input
+
+	// IdleTimeout ends the session when the client sends no input for this long, zero disables it
+	IdleTimeout time.Duration
+}
+
+// ErrIdleTimeout is returned by Bridge when the session was closed because the client was idle
+var ErrIdleTimeout = errors.New("terminal session idle timeout")
+
+// Stats counts the traffic of a finished session
+type Stats struct {
+	BytesIn  int64
+	BytesOut int64
+}
+
+// Bridge copies data between the WebSocket and the session until either side closes.
+// The caller is responsible for closing the WebSocket and the TTY afterwards.
+func Bridge(ws *websocket.Conn, session Session) (Stats, error) {
+	var bytesIn, bytesOut atomic.Int64
+	done := make(chan error, 3)
+
+	// Close the session when no input arrives within the idle timeout
+	var idleTimer *time.Timer
+	if session.IdleTimeout > 0 {
+		idleTimer = time.AfterFunc(session.IdleTimeout, func() {
+			select {
+			case done <- ErrIdleTimeout:
+			default:
+			}
+		})
+		defer idleTimer.Stop()
+	}
+
+	// TTY -> WebSocket
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := session.Stdout.Read(buf)
+			if n > 0 {
+				if session.OnOutput != nil {
+					session.OnOutput(buf[:n])
+				}
+				if sendErr := websocket.Message.Send(ws, buf[:n]); sendErr != nil {
+					done <- sendErr
+					return
+				}
+				bytesOut.Add(int64(n))
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				done <- err
+				return
+			}
+		}
+	}()
+
+	// WebSocket -> TTY
+	go func() {
+		for {
+			var raw []byte
+			if err := websocket.Message.Receive(ws, &raw); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				done <- err
+				return
+			}
+
+			var message Message
+			if err := json.Unmarshal(raw, &message); err != nil {
+				// Ignore malformed messages instead of killing the session
+				continue
+			}
+
+			if idleTimer != nil {
+				idleTimer.Reset(session.IdleTimeout)
+			}
+
+			switch message.Type {
+			case MessageTypeInput:
+				data := []byte(message.Data)
+				if session.OnInput != nil {
+					session.OnInput(data)
+				}
+				if _, err := session.Stdin.Write(data); err != nil {
+					done <- err
+					return
+				}
+				bytesIn.Add(int64(len(data)))
+			case MessageTypeResize:
+				if session.Resize != nil && message.Cols > 0 && message.Rows > 0 {
+					if err := session.Resize(message.Cols, message.Rows); err != nil {
+						done <- err
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	err := <-done
+	return Stats{BytesIn: bytesIn.Load(), BytesOut: bytesOut.Load()}, err
+}